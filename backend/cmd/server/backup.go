@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"rss-feed-manager/backend/internal/backup"
+	"rss-feed-manager/backend/internal/db"
+)
+
+const backupUsage = `usage: server backup [list]
+       server restore <name|file>
+
+commands:
+  backup          take a backup now and prune old ones
+  backup list     list stored backups
+  restore NAME    replace the database with a stored backup, or with a
+                  backup file on disk; stop the server first`
+
+// runBackup implements the backup subcommand.
+func runBackup(sqlDB *db.DB, args []string) error {
+	store, err := backupStoreFromEnv()
+	if err != nil {
+		return err
+	}
+	b := backup.New(sqlDB, store, backupConfigFromEnv())
+	switch {
+	case len(args) == 0:
+		name, err := b.Run(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Println(name)
+		return nil
+	case len(args) == 1 && args[0] == "list":
+		backups, err := b.List(context.Background())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSIZE\tMODIFIED")
+		for _, o := range backups {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", o.Name, o.Size, o.ModTime.Local().Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("%s", backupUsage)
+}
+
+// runRestore implements the restore subcommand. It runs before the database
+// is opened, since the file is swapped out from under it.
+func runRestore(databaseURL, dbPath string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s", backupUsage)
+	}
+	if databaseURL != "" {
+		return backup.ErrPostgres
+	}
+	var store backup.Store
+	name := args[0]
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		store, name = backup.DirStore{Dir: filepath.Dir(name)}, filepath.Base(name)
+	} else if store, err = backupStoreFromEnv(); err != nil {
+		return err
+	}
+	if err := backup.Restore(context.Background(), store, name, dbPath); err != nil {
+		return err
+	}
+	fmt.Printf("restored %s; the previous database is at %s.before-restore\n", args[0], dbPath)
+	return nil
+}
+
+// backupStoreFromEnv returns the S3 store when BACKUP_S3_BUCKET is set and
+// the BACKUP_DIR directory otherwise.
+func backupStoreFromEnv() (backup.Store, error) {
+	if bucket := os.Getenv("BACKUP_S3_BUCKET"); bucket != "" {
+		return backup.NewS3Store(backup.S3Config{
+			Endpoint:  getEnv("BACKUP_S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:    os.Getenv("BACKUP_S3_REGION"),
+			Bucket:    bucket,
+			Prefix:    os.Getenv("BACKUP_S3_PREFIX"),
+			AccessKey: os.Getenv("BACKUP_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("BACKUP_S3_SECRET_KEY"),
+		})
+	}
+	return backup.DirStore{Dir: getEnv("BACKUP_DIR", "./backups")}, nil
+}
+
+func backupConfigFromEnv() backup.Config {
+	return backup.Config{
+		Keep: parseInt(getEnv("BACKUP_KEEP", "7"), 7),
+		Gzip: getEnv("BACKUP_GZIP", "true") == "true",
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/joho/godotenv"
 
+	"rss-feed-manager/backend/internal/backup"
 	"rss-feed-manager/backend/internal/db"
 	"rss-feed-manager/backend/internal/feeds"
 	"rss-feed-manager/backend/internal/handlers"
@@ -28,15 +30,42 @@ func main() {
 	port := getEnv("PORT", "8080")
 	dbPath := getEnv("DB_PATH", "./data/rss.db")
 	pollInterval := parseDuration(getEnv("POLL_INTERVAL", "1h"), time.Hour)
+	pollMinInterval := parseDuration(getEnv("POLL_MIN_INTERVAL", "15m"), 15*time.Minute)
+	pollMaxInterval := parseDuration(getEnv("POLL_MAX_INTERVAL", "24h"), 24*time.Hour)
+	pollCheckInterval := parseDuration(getEnv("POLL_CHECK_INTERVAL", "5m"), 5*time.Minute)
+	refreshConcurrency := parseInt(getEnv("REFRESH_CONCURRENCY", "8"), 8)
+	fetchHostMaxConns := parseInt(getEnv("FETCH_HOST_MAX_CONNS", "2"), 2)
+	fetchHostMinGap := parseDuration(getEnv("FETCH_HOST_MIN_GAP", "1s"), time.Second)
 	digestInterval := parseDuration(getEnv("DIGEST_INTERVAL", "6h"), 6*time.Hour)
 	digestEnabled := os.Getenv("DIGEST_ENABLED") == "true"
+	backupInterval := parseDuration(getEnv("BACKUP_INTERVAL", "0"), 0)
 
-	sqlDB, err := db.Connect(dbPath)
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		if err := runRestore(os.Getenv("DATABASE_URL"), dbPath, os.Args[2:]); err != nil {
+			log.Fatalf("restore: %v", err)
+		}
+		return
+	}
+
+	sqlDB, err := connectDB(os.Getenv("DATABASE_URL"), dbPath)
 	if err != nil {
 		log.Fatalf("db connect: %v", err)
 	}
 	defer sqlDB.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(sqlDB, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		if err := runBackup(sqlDB, os.Args[2:]); err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		return
+	}
+
 	if err := db.Migrate(sqlDB); err != nil {
 		log.Fatalf("db migrate: %v", err)
 	}
@@ -48,22 +77,54 @@ func main() {
 	}
 
 	feedFetcher := feeds.NewFetcher(getEnv("READER_USER_AGENT", "RSSFeedManager/0.1"))
+	feedFetcher.SetHostLimits(fetchHostMaxConns, fetchHostMinGap)
 	appMailer := mailer.FromEnv()
 	readerClient := reader.NewClient(getEnv("READER_USER_AGENT", "RSSFeedManager/0.1"))
 
 	feedService := services.NewFeedService(sqlDB, feedFetcher)
-	digestService := services.NewDigestService(sqlDB, appMailer)
+	feedService.SetPollPolicy(feeds.PollPolicy{
+		Default: pollInterval,
+		Min:     pollMinInterval,
+		Max:     pollMaxInterval,
+	})
+	feedService.SetRefreshConcurrency(refreshConcurrency)
+	eventBus := services.NewEventBus()
+	feedService.SetEventBus(eventBus)
+	go func() {
+		// Index items stored before search existed; new items are indexed on save.
+		if n, err := feedService.IndexMissingItems(context.Background()); err != nil {
+			log.Printf("search index backfill: %v", err)
+		} else if n > 0 {
+			log.Printf("search index backfill: indexed %d items", n)
+		}
+	}()
+	digestService := services.NewDigestService(sqlDB, appMailer, feedService)
 	topNewsService := services.NewTopNewsService(sqlDB)
 	summaryService := services.NewSummaryService()
 	authService := services.NewAuthService(sqlDB, appMailer)
 	opmlService := services.NewOPMLService(feedService)
+	accountService := services.NewAccountService(sqlDB, feedService)
+	publishService := services.NewPublishService(sqlDB, feedService, topNewsService)
+	feverService := services.NewFeverService(sqlDB, feedService)
+	greaderService := services.NewGReaderService(sqlDB, feedService)
 
 	sched := scheduler.NewScheduler(feedService, digestService, scheduler.Config{
 		UserID:         demoUserID,
-		PollInterval:   pollInterval,
+		CheckInterval:  pollCheckInterval,
 		DigestEnabled:  digestEnabled,
 		DigestInterval: digestInterval,
 	})
+	if backupInterval > 0 {
+		store, err := backupStoreFromEnv()
+		if err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		if sqlDB.Dialect() == db.SQLite {
+			sched.SetBackups(backup.New(sqlDB, store, backupConfigFromEnv()), backupInterval)
+		} else {
+			log.Printf("BACKUP_INTERVAL ignored: %v", backup.ErrPostgres)
+		}
+	}
 	sched.Start()
 	defer sched.Stop()
 
@@ -75,6 +136,11 @@ func main() {
 		SummaryService:      summaryService,
 		AuthService:         authService,
 		OPMLService:         opmlService,
+		AccountService:      accountService,
+		PublishService:      publishService,
+		FeverService:        feverService,
+		GReaderService:      greaderService,
+		Events:              eventBus,
 		Reader:              readerClient,
 		FrontendOrigin:      getEnv("FRONTEND_ORIGIN", "http://localhost:5173"),
 		ReaderRatePerMinute: parseInt(getEnv("READER_RATE_PER_MINUTE", "20"), 20),
@@ -102,6 +168,18 @@ func main() {
 	_ = server.Shutdown(ctx)
 }
 
+// connectDB opens Postgres when DATABASE_URL is a postgres:// URL and the
+// SQLite file at dbPath otherwise.
+func connectDB(databaseURL, dbPath string) (*db.DB, error) {
+	if databaseURL == "" {
+		return db.Connect(dbPath)
+	}
+	if !db.IsPostgresURL(databaseURL) {
+		return nil, fmt.Errorf("DATABASE_URL must be a postgres:// URL; use DB_PATH for SQLite")
+	}
+	return db.ConnectPostgres(databaseURL, parseInt(getEnv("DB_MAX_CONNS", "10"), 10))
+}
+
 func getEnv(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"rss-feed-manager/backend/internal/db"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  status    list migrations and whether each is applied
+  up        apply every pending migration
+  down      revert the most recent migration
+  to N      migrate up or down to version N`
+
+// runMigrate implements the migrate subcommand, which manages the schema
+// without starting the server.
+func runMigrate(sqlDB *db.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+	switch args[0] {
+	case "status":
+		return printMigrationStatus(sqlDB)
+	case "up":
+		return db.MigrateTo(sqlDB, db.LatestVersion())
+	case "down":
+		current, err := db.CurrentVersion(sqlDB)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return nil
+		}
+		return db.MigrateTo(sqlDB, previousVersion(sqlDB, current))
+	case "to":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("bad version %q", args[1])
+		}
+		return db.MigrateTo(sqlDB, target)
+	}
+	return fmt.Errorf("%s", migrateUsage)
+}
+
+// previousVersion is the highest known version below current, so down
+// reverts exactly one migration even if versions are not contiguous.
+func previousVersion(sqlDB *db.DB, current int) int {
+	states, err := db.MigrationStatus(sqlDB)
+	if err != nil {
+		return current - 1
+	}
+	prev := 0
+	for _, s := range states {
+		if s.Version < current {
+			prev = s.Version
+		}
+	}
+	return prev
+}
+
+func printMigrationStatus(sqlDB *db.DB) error {
+	states, err := db.MigrationStatus(sqlDB)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range states {
+		status, appliedAt := "pending", ""
+		if s.AppliedAt != nil {
+			status, appliedAt = "applied", s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if s.Modified {
+			status = "modified"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,270 @@
+// Package backup takes consistent copies of a running SQLite database,
+// keeps a rotating set of them in a local directory or an S3-compatible
+// bucket, and restores one after checking it is sound.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+)
+
+// ErrPostgres is returned when asked to back up a Postgres database; use
+// pg_dump there.
+var ErrPostgres = errors.New("built-in backups need SQLite; use pg_dump for Postgres")
+
+const (
+	namePrefix = "rss-backup-"
+	nameLayout = "20060102T150405Z"
+)
+
+// Config controls how backups are written and rotated.
+type Config struct {
+	Keep int  // newest backups kept; 0 keeps all
+	Gzip bool // compress backups
+}
+
+// Backuper writes backups of one database to a store.
+type Backuper struct {
+	db    *db.DB
+	store Store
+	cfg   Config
+	now   func() time.Time
+}
+
+func New(d *db.DB, store Store, cfg Config) *Backuper {
+	return &Backuper{db: d, store: store, cfg: cfg, now: time.Now}
+}
+
+// Run takes a backup, stores it and prunes old ones, returning its name.
+func (b *Backuper) Run(ctx context.Context) (string, error) {
+	if b.db.Dialect() != db.SQLite {
+		return "", ErrPostgres
+	}
+	dir, err := os.MkdirTemp("", "rss-backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	name := namePrefix + b.now().UTC().Format(nameLayout) + ".db"
+	path := filepath.Join(dir, name)
+	if err := Snapshot(ctx, b.db, path); err != nil {
+		return "", err
+	}
+	if b.cfg.Gzip {
+		if err := gzipFile(path, path+".gz"); err != nil {
+			return "", fmt.Errorf("compress backup: %w", err)
+		}
+		name, path = name+".gz", path+".gz"
+	}
+	if err := b.store.Put(ctx, name, path); err != nil {
+		return "", fmt.Errorf("store backup: %w", err)
+	}
+	if err := b.rotate(ctx); err != nil {
+		return name, fmt.Errorf("rotate backups: %w", err)
+	}
+	return name, nil
+}
+
+// List returns the stored backups, oldest first.
+func (b *Backuper) List(ctx context.Context) ([]Object, error) {
+	return listBackups(ctx, b.store)
+}
+
+// rotate deletes all but the newest Keep backups. Other files in the store
+// are left alone.
+func (b *Backuper) rotate(ctx context.Context) error {
+	if b.cfg.Keep <= 0 {
+		return nil
+	}
+	backups, err := listBackups(ctx, b.store)
+	if err != nil {
+		return err
+	}
+	for len(backups) > b.cfg.Keep {
+		if err := b.store.Delete(ctx, backups[0].Name); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// listBackups returns the store's backups in name order, which is also
+// the order they were taken in.
+func listBackups(ctx context.Context, store Store) ([]Object, error) {
+	objects, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backups := objects[:0]
+	for _, o := range objects {
+		if strings.HasPrefix(o.Name, namePrefix) && (strings.HasSuffix(o.Name, ".db") || strings.HasSuffix(o.Name, ".db.gz")) {
+			backups = append(backups, o)
+		}
+	}
+	return backups, nil
+}
+
+// Snapshot writes a consistent copy of the live database to path with
+// VACUUM INTO; the copy is also compacted. The server's pool has a single
+// connection, so the copy is taken on a connection of its own: in WAL mode
+// it reads a snapshot of the database while requests keep reading and
+// writing through the pool.
+func Snapshot(ctx context.Context, d *db.DB, path string) error {
+	if d.Dialect() != db.SQLite {
+		return ErrPostgres
+	}
+	conn, err := sql.Open("sqlite3", "file:"+d.Path()+"?_busy_timeout=5000")
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the SQLite database at dbPath with the named backup. The
+// backup is downloaded, decompressed and validated next to dbPath first, so
+// a bad backup leaves the database untouched. The replaced database is kept
+// as dbPath + ".before-restore". The server must not be running.
+func Restore(ctx context.Context, store Store, name, dbPath string) error {
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	download := filepath.Join(dir, ".restore-download")
+	staged := filepath.Join(dir, ".restore.db")
+	defer os.Remove(download)
+	defer os.Remove(staged)
+
+	if err := store.Get(ctx, name, download); err != nil {
+		return err
+	}
+	if err := decompressTo(download, staged); err != nil {
+		return fmt.Errorf("read backup: %w", err)
+	}
+	if err := Validate(staged); err != nil {
+		return err
+	}
+
+	// The database, its WAL and shared memory are only consistent together,
+	// so if any step fails the files already moved are put back.
+	previous := dbPath + ".before-restore"
+	var moved []string
+	rollback := func(err error) error {
+		for i := len(moved) - 1; i >= 0; i-- {
+			if rerr := os.Rename(previous+moved[i], dbPath+moved[i]); rerr != nil {
+				return fmt.Errorf("%w; putting back %s also failed: %v", err, dbPath+moved[i], rerr)
+			}
+		}
+		return err
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(dbPath+suffix, previous+suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			// A database with no WAL, or none at all yet.
+			os.Remove(previous + suffix)
+			continue
+		}
+		if err != nil {
+			return rollback(fmt.Errorf("move aside %s: %w", dbPath+suffix, err))
+		}
+		moved = append(moved, suffix)
+	}
+	if err := os.Rename(staged, dbPath); err != nil {
+		return rollback(fmt.Errorf("move restored database into place: %w", err))
+	}
+	return nil
+}
+
+// Validate checks that the file is an intact database of this application
+// at a schema version this build can run.
+func Validate(path string) error {
+	// immutable: nothing else has the staged file open, and read-only WAL
+	// databases otherwise need a shared-memory file beside them.
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&immutable=1")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var result string
+	if err := conn.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed integrity check: %s", result)
+	}
+	var version int
+	if err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("backup has no schema version: %w", err)
+	}
+	if version > db.LatestVersion() {
+		return fmt.Errorf("backup is at schema version %d, newer than this build (%d)", version, db.LatestVersion())
+	}
+	return nil
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// decompressTo copies src to dst, gunzipping it if it is gzip data.
+func decompressTo(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	br := bufio.NewReader(in)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
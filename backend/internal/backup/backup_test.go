@@ -0,0 +1,220 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+)
+
+func openDB(t *testing.T, path string) *db.DB {
+	t.Helper()
+	d, err := db.Connect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return d
+}
+
+func countUsers(t *testing.T, path string) int {
+	t.Helper()
+	d, err := db.Connect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	var n int
+	if err := d.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestBackupRotateRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "rss.db")
+	store := DirStore{Dir: filepath.Join(dir, "backups")}
+
+	d := openDB(t, dbPath)
+	b := New(d, store, Config{Keep: 2, Gzip: true})
+	clock := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return clock }
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		if _, err := d.Exec(`INSERT INTO users (email) VALUES (?)`, string(rune('a'+i))+"@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		name, err := b.Run(ctx)
+		if err != nil {
+			t.Fatalf("backup %d: %v", i, err)
+		}
+		names = append(names, name)
+		clock = clock.Add(time.Hour)
+	}
+	if names[0] != "rss-backup-20240501T120000Z.db.gz" {
+		t.Errorf("name = %q", names[0])
+	}
+	// An unrelated file in the directory survives rotation.
+	if err := os.WriteFile(filepath.Join(store.Dir, "notes.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := b.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Name != names[1] || backups[1].Name != names[2] {
+		t.Fatalf("backups after rotation = %+v, want %v", backups, names[1:])
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, "notes.txt")); err != nil {
+		t.Errorf("unrelated file removed: %v", err)
+	}
+
+	if _, err := d.Exec(`INSERT INTO users (email) VALUES ('late@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	if err := Restore(ctx, store, names[1], dbPath); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if n := countUsers(t, dbPath); n != 2 {
+		t.Errorf("restored users = %d, want 2", n)
+	}
+	if n := countUsers(t, dbPath+".before-restore"); n != 4 {
+		t.Errorf("previous database users = %d, want 4", n)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".restore*"))
+	if len(leftovers) != 0 {
+		t.Errorf("restore left %v behind", leftovers)
+	}
+}
+
+func TestRestoreRejectsBadBackups(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "rss.db")
+	store := DirStore{Dir: filepath.Join(dir, "backups")}
+
+	d := openDB(t, dbPath)
+	if _, err := d.Exec(`INSERT INTO users (email) VALUES ('a@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(d, store, Config{}).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A backup from a newer build than this one.
+	if _, err := d.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES (?, 'future', '')`, db.LatestVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Snapshot(ctx, d, filepath.Join(store.Dir, "future.db")); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if err := os.WriteFile(filepath.Join(store.Dir, "garbage.db"), []byte("not a database at all, just some bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"garbage.db", "future.db"} {
+		if err := Restore(ctx, store, name, dbPath); err == nil {
+			t.Errorf("restore %s: want error", name)
+		}
+	}
+	if err := Restore(ctx, store, "missing.db", dbPath); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore missing: err = %v, want ErrNotFound", err)
+	}
+	if n := countUsers(t, dbPath); n != 1 {
+		t.Errorf("users = %d after rejected restores, want 1", n)
+	}
+	if _, err := os.Stat(dbPath + ".before-restore"); !os.IsNotExist(err) {
+		t.Errorf("database moved aside by a rejected restore")
+	}
+}
+
+// TestSnapshotDoesNotBlockPool takes a snapshot while a transaction holds
+// the pool's only connection; the snapshot must not wait for it, and sees
+// only what was committed.
+func TestSnapshotDoesNotBlockPool(t *testing.T) {
+	dir := t.TempDir()
+	d := openDB(t, filepath.Join(dir, "rss.db"))
+	defer d.Close()
+	if _, err := d.Exec(`INSERT INTO users (email) VALUES ('a@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO users (email) VALUES ('b@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "snap.db")
+	done := make(chan error, 1)
+	go func() { done <- Snapshot(ctx, d, path) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("snapshot waited for the open transaction")
+	}
+	conn.ExecContext(ctx, `ROLLBACK`)
+	conn.Close()
+	if n := countUsers(t, path); n != 1 {
+		t.Errorf("snapshot users = %d, want the 1 committed", n)
+	}
+}
+
+// TestRestoreRollsBack makes moving the old database aside fail halfway and
+// checks the files already moved are put back.
+func TestRestoreRollsBack(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "rss.db")
+	store := DirStore{Dir: filepath.Join(dir, "backups")}
+
+	d := openDB(t, dbPath)
+	name, err := New(d, store, Config{}).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Exec(`INSERT INTO users (email) VALUES ('a@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	// A non-empty directory cannot be renamed over another one, so moving
+	// aside -shm fails after the database itself has been moved.
+	for _, p := range []string{dbPath + "-shm", dbPath + ".before-restore-shm"} {
+		if err := os.MkdirAll(filepath.Join(p, "x"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Restore(ctx, store, name, dbPath); err == nil {
+		t.Fatal("restore: want error")
+	}
+	os.RemoveAll(dbPath + "-shm")
+	if n := countUsers(t, dbPath); n != 1 {
+		t.Errorf("users = %d after failed restore, want the original 1", n)
+	}
+	if _, err := os.Stat(dbPath + ".before-restore"); !os.IsNotExist(err) {
+		t.Errorf("database left moved aside by a failed restore")
+	}
+}
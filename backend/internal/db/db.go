@@ -1,15 +1,48 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func Connect(path string) (*sql.DB, error) {
+// Dialect is the SQL flavour of the database behind a DB.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// postgresDriver is the database/sql driver used for Postgres. It is
+// registered by github.com/jackc/pgx/v5/stdlib, which is only linked into
+// builds with -tags postgres.
+const postgresDriver = "pgx"
+
+// DB is the storage handle services use. Queries are written once with ?
+// placeholders and portable SQL; DB rewrites the placeholders for the
+// dialect, and Dialect provides the fragments that differ between backends.
+type DB struct {
+	*sql.DB
+	dialect Dialect
+	path    string
+}
+
+// Tx is a transaction on a DB, with the same placeholder rewriting.
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+// Connect opens the SQLite database at path, creating its directory.
+func Connect(path string) (*DB, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, fmt.Errorf("mkdir: %w", err)
 	}
@@ -23,5 +56,157 @@ func Connect(path string) (*sql.DB, error) {
 	if err := database.Ping(); err != nil {
 		return nil, fmt.Errorf("ping: %w", err)
 	}
-	return database, nil
+	return &DB{DB: database, dialect: SQLite, path: path}, nil
+}
+
+// ConnectPostgres opens a Postgres database from a postgres:// URL. Unlike
+// SQLite it keeps a pool of connections, so requests run concurrently and
+// several server instances can share the database.
+func ConnectPostgres(url string, maxConns int) (*DB, error) {
+	if !slices.Contains(sql.Drivers(), postgresDriver) {
+		return nil, fmt.Errorf("postgres support is not built in (build with -tags postgres)")
+	}
+	database, err := sql.Open(postgresDriver, url)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	if maxConns > 0 {
+		database.SetMaxOpenConns(maxConns)
+		database.SetMaxIdleConns(maxConns)
+	}
+	if err := database.Ping(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+	return &DB{DB: database, dialect: Postgres}, nil
+}
+
+// IsPostgresURL reports whether a DATABASE_URL names a Postgres database.
+func IsPostgresURL(url string) bool {
+	return strings.HasPrefix(url, "postgres://") || strings.HasPrefix(url, "postgresql://")
+}
+
+func (d *DB) Dialect() Dialect { return d.dialect }
+
+// Path is the SQLite database file, or "" for Postgres.
+func (d *DB) Path() string { return d.path }
+
+func (d *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.Exec(d.dialect.Rebind(query), args...)
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.ExecContext(ctx, d.dialect.Rebind(query), args...)
+}
+
+func (d *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.Query(d.dialect.Rebind(query), args...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.QueryContext(ctx, d.dialect.Rebind(query), args...)
+}
+
+func (d *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRow(d.dialect.Rebind(query), args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRowContext(ctx, d.dialect.Rebind(query), args...)
+}
+
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.DB.PrepareContext(ctx, d.dialect.Rebind(query))
+}
+
+func (d *DB) Begin() (*Tx, error) {
+	return d.BeginTx(context.Background(), nil)
+}
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: d.dialect}, nil
+}
+
+func (t *Tx) Dialect() Dialect { return t.dialect }
+
+func (t *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, t.dialect.Rebind(query), args...)
+}
+
+func (t *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.Tx.PrepareContext(ctx, t.dialect.Rebind(query))
+}
+
+// Rebind rewrites ? placeholders as $1, $2, ... for Postgres. Question marks
+// inside quoted strings and identifiers are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		b     strings.Builder
+		n     int
+		quote byte
+	)
+	b.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// UnixTime converts a timestamp expression to Unix seconds as a 64-bit
+// integer, or NULL for NULL.
+func (d Dialect) UnixTime(expr string) string {
+	if d == Postgres {
+		return fmt.Sprintf("CAST(EXTRACT(EPOCH FROM %s) AS BIGINT)", expr)
+	}
+	return fmt.Sprintf("CAST(strftime('%%s', %s) AS INTEGER)", expr)
+}
+
+// TableExistsQuery returns a query counting tables named by its one
+// argument.
+func (d Dialect) TableExistsQuery() string {
+	if d == Postgres {
+		return `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?`
 }
@@ -0,0 +1,86 @@
+// Package dbtest runs storage tests against every database backend. SQLite
+// always runs; Postgres runs when TEST_DATABASE_URL names a server the tests
+// may create schemas on and the tests are built with -tags postgres.
+package dbtest
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"rss-feed-manager/backend/internal/db"
+)
+
+var schemaSeq atomic.Int64
+
+// Each calls fn once per backend with a fresh, empty database.
+func Each(t *testing.T, fn func(t *testing.T, d *db.DB)) {
+	t.Helper()
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, openSQLite(t))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		fn(t, openPostgres(t))
+	})
+}
+
+// EachMigrated is Each with the schema migrated to the latest version.
+func EachMigrated(t *testing.T, fn func(t *testing.T, d *db.DB)) {
+	t.Helper()
+	Each(t, func(t *testing.T, d *db.DB) {
+		if err := db.Migrate(d); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		fn(t, d)
+	})
+}
+
+func openSQLite(t *testing.T) *db.DB {
+	t.Helper()
+	d, err := db.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// openPostgres connects to TEST_DATABASE_URL with a schema of its own, which
+// is dropped when the test ends.
+func openPostgres(t *testing.T) *db.DB {
+	t.Helper()
+	rawURL := os.Getenv("TEST_DATABASE_URL")
+	if rawURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	admin, err := db.ConnectPostgres(rawURL, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("rfm_test_%d_%d", os.Getpid(), schemaSeq.Add(1))
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	d, err := db.ConnectPostgres(u.String(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
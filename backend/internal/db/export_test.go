@@ -0,0 +1,15 @@
+package db
+
+import "testing"
+
+// SwapMigrations replaces the migration list for the rest of the test.
+func SwapMigrations(t *testing.T, ms []Migration) {
+	saved := migrations
+	migrations = ms
+	t.Cleanup(func() { migrations = saved })
+}
+
+// Migrations returns the migration list.
+func Migrations() []Migration {
+	return migrations
+}
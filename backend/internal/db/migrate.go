@@ -0,0 +1,294 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrUnknownMigration = errors.New("database has a migration this build does not know")
+	ErrIrreversible     = errors.New("migration cannot be reverted")
+)
+
+// Migration is one numbered schema change. Up and Down each run inside a
+// single transaction together with the schema_migrations bookkeeping, so a
+// failed migration leaves the database at the previous version. Up and Down
+// are SQLite; PostgresUp and PostgresDown are the same change for Postgres.
+//
+// SQLite can only change most of a table's definition by building a new
+// table and dropping the old one, which would cascade to referencing rows.
+// RebuildsTables runs such a migration with foreign keys off and checks them
+// before committing.
+type Migration struct {
+	Version        int
+	Name           string
+	Up             string
+	Down           string
+	PostgresUp     string
+	PostgresDown   string
+	RebuildsTables bool
+}
+
+func (m Migration) script(dialect Dialect, up bool) string {
+	switch {
+	case dialect == Postgres && up:
+		return m.PostgresUp
+	case dialect == Postgres:
+		return m.PostgresDown
+	case up:
+		return m.Up
+	}
+	return m.Down
+}
+
+// Checksum identifies the Up script for the dialect. Applied migrations are
+// checked against it so that editing a migration after release is caught
+// instead of silently leaving databases with different schemas.
+func (m Migration) Checksum(dialect Dialect) string {
+	sum := sha256.Sum256([]byte(m.script(dialect, true)))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationState is a migration together with whether and when it was
+// applied. Modified is set when the applied checksum differs from the code.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Modified  bool
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// LatestVersion is the version Migrate brings the database to.
+func LatestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationStatus lists every known migration and its state.
+func MigrationStatus(db *DB) ([]MigrationState, error) {
+	if err := prepareMigrations(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.appliedAt
+			state.AppliedAt = &appliedAt
+			state.Modified = a.checksum != m.Checksum(db.Dialect())
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// CurrentVersion returns the highest applied migration, or 0.
+func CurrentVersion(db *DB) (int, error) {
+	if err := prepareMigrations(db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// MigrateTo applies or reverts migrations until the database is at target.
+// It refuses to run if an applied migration was changed or is unknown to
+// this build.
+func MigrateTo(db *DB, target int) error {
+	if target < 0 || target > LatestVersion() {
+		return fmt.Errorf("migrate: no version %d (latest is %d)", target, LatestVersion())
+	}
+	if err := prepareMigrations(db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	if err := verifyMigrations(db.Dialect(), applied); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok || m.Version > target {
+			continue
+		}
+		if err := runMigration(db, m, true); err != nil {
+			return err
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok || m.Version <= target {
+			continue
+		}
+		if err := runMigration(db, m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareMigrations creates schema_migrations. SQLite databases created
+// before migrations were versioned have tables but no schema_migrations;
+// their late-added columns are filled in first so the baseline applies
+// cleanly.
+func prepareMigrations(db *DB) error {
+	var tracked, legacy int
+	if err := db.QueryRow(db.Dialect().TableExistsQuery(), "schema_migrations").Scan(&tracked); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if tracked > 0 {
+		return nil
+	}
+	if db.Dialect() == SQLite {
+		if err := db.QueryRow(db.Dialect().TableExistsQuery(), "users").Scan(&legacy); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+		if legacy > 0 {
+			if err := upgradeLegacySchema(db); err != nil {
+				return err
+			}
+		}
+	}
+	appliedAtType := "DATETIME"
+	if db.Dialect() == Postgres {
+		appliedAtType = "TIMESTAMPTZ"
+	}
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at ` + appliedAtType + ` DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(db *DB) (map[int]appliedMigration, error) {
+	rows, err := db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	defer rows.Close()
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var (
+			version int
+			a       appliedMigration
+		)
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+func verifyMigrations(dialect Dialect, applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if a.checksum != m.Checksum(dialect) {
+			return fmt.Errorf("%w: version %d (%s)", ErrChecksumMismatch, version, m.Name)
+		}
+	}
+	return nil
+}
+
+func runMigration(db *DB, m Migration, up bool) error {
+	script, direction := m.script(db.Dialect(), up), "up"
+	if !up {
+		direction = "down"
+		if script == "" {
+			return fmt.Errorf("%w: version %d (%s)", ErrIrreversible, m.Version, m.Name)
+		}
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	relaxForeignKeys := m.RebuildsTables && db.Dialect() == SQLite
+	if relaxForeignKeys {
+		// The pragma is a no-op inside a transaction, so it is set on the
+		// connection first.
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys=OFF`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys=ON`)
+	}
+	sqlTx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := &Tx{Tx: sqlTx, dialect: db.Dialect()}
+	defer tx.Rollback()
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("migrate %s %d (%s): %w", direction, m.Version, m.Name, err)
+	}
+	if relaxForeignKeys {
+		if err := checkForeignKeys(tx); err != nil {
+			return fmt.Errorf("migrate %s %d (%s): %w", direction, m.Version, m.Name, err)
+		}
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES(?, ?, ?, ?)`,
+			m.Version, m.Name, m.Checksum(db.Dialect()), time.Now().UTC())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version=?`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s %d (%s): %w", direction, m.Version, m.Name, err)
+	}
+	return tx.Commit()
+}
+
+// checkForeignKeys reports the first row a table rebuild left without its
+// parent.
+func checkForeignKeys(tx *Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var (
+			table, parent string
+			rowID         sql.NullInt64
+			fkID          int
+		)
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation: %s row %d references missing %s", table, rowID.Int64, parent)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,256 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+)
+
+func tableExists(t *testing.T, d *db.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := d.QueryRow(d.Dialect().TableExistsQuery(), name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, d *db.DB) {
+		if err := db.MigrateTo(d, db.LatestVersion()); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := db.CurrentVersion(d); v != db.LatestVersion() {
+			t.Fatalf("version %d, expected %d", v, db.LatestVersion())
+		}
+		if !tableExists(t, d, "entries") {
+			t.Fatal("entries not created")
+		}
+		// Applying again is a no-op.
+		if err := db.MigrateTo(d, db.LatestVersion()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.MigrateTo(d, 0); err != nil {
+			t.Fatal(err)
+		}
+		if tableExists(t, d, "feeds") {
+			t.Error("feeds kept after migrating to 0")
+		}
+		if v, _ := db.CurrentVersion(d); v != 0 {
+			t.Errorf("version %d after migrating to 0", v)
+		}
+	})
+}
+
+func TestMigrateChecksumMismatch(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, d *db.DB) {
+		if err := db.MigrateTo(d, db.LatestVersion()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Exec(`UPDATE schema_migrations SET checksum='edited' WHERE version=1`); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.MigrateTo(d, db.LatestVersion()); !errors.Is(err, db.ErrChecksumMismatch) {
+			t.Errorf("got %v, expected ErrChecksumMismatch", err)
+		}
+		states, err := db.MigrationStatus(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !states[0].Modified {
+			t.Error("status does not flag the edited migration")
+		}
+	})
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, d *db.DB) {
+		if err := db.MigrateTo(d, db.LatestVersion()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Exec(`INSERT INTO schema_migrations(version, name, checksum) VALUES(9999, 'future', 'x')`); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.MigrateTo(d, db.LatestVersion()); !errors.Is(err, db.ErrUnknownMigration) {
+			t.Errorf("got %v, expected ErrUnknownMigration", err)
+		}
+	})
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	saved := db.Migrations()
+	broken := `CREATE TABLE half_done (id INTEGER); INSERT INTO no_such_table VALUES (1);`
+	db.SwapMigrations(t, append(append([]db.Migration{}, saved...), db.Migration{
+		Version:    db.LatestVersion() + 1,
+		Name:       "broken",
+		Up:         broken,
+		PostgresUp: broken,
+	}))
+
+	dbtest.Each(t, func(t *testing.T, d *db.DB) {
+		if err := db.MigrateTo(d, db.LatestVersion()); err == nil {
+			t.Fatal("broken migration applied")
+		}
+		if tableExists(t, d, "half_done") {
+			t.Error("failed migration was not rolled back")
+		}
+		if v, _ := db.CurrentVersion(d); v != saved[len(saved)-1].Version {
+			t.Errorf("version %d after failed migration", v)
+		}
+	})
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, d *db.DB) {
+		if d.Dialect() != db.SQLite {
+			t.Skip("only SQLite databases predate versioned migrations")
+		}
+		// A database from before versioned migrations, missing later columns.
+		for _, stmt := range []string{
+			`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, digest_last_sent_at DATETIME, created_at DATETIME)`,
+			`CREATE TABLE folders (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, name TEXT NOT NULL, created_at DATETIME)`,
+			`CREATE TABLE feeds (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, folder_id INTEGER NOT NULL, url TEXT NOT NULL, title TEXT, site_url TEXT, etag TEXT, last_modified TEXT, last_checked_at DATETIME, created_at DATETIME, UNIQUE(user_id, url))`,
+			`INSERT INTO users(id, email) VALUES(1, 'a@example.com')`,
+		} {
+			if _, err := d.Exec(stmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.MigrateTo(d, db.LatestVersion()); err != nil {
+			t.Fatal(err)
+		}
+		var paused, users int
+		if err := d.QueryRow(`SELECT COUNT(paused) FROM feeds`).Scan(&paused); err != nil {
+			t.Errorf("feeds.paused not carried over: %v", err)
+		}
+		if err := d.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil || users != 1 {
+			t.Errorf("users lost: %d, %v", users, err)
+		}
+	})
+}
+
+func TestMigrateSharedFeeds(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, d *db.DB) {
+		if err := db.MigrateTo(d, 1); err != nil {
+			t.Fatal(err)
+		}
+		// Two users subscribed to the same URL, each with their own copy of
+		// the same two items, and a third user with a feed of their own.
+		for _, stmt := range []string{
+			`INSERT INTO users(id, email) VALUES(1, 'a@example.com'), (2, 'b@example.com'), (3, 'c@example.com')`,
+			`INSERT INTO folders(id, user_id, name) VALUES(1, 1, 'News'), (2, 2, 'Reading'), (3, 3, 'Misc')`,
+			`INSERT INTO feeds(id, user_id, folder_id, url, title, etag) VALUES
+				(1, 1, 1, 'https://example.com/feed', 'Example', 'v1'),
+				(2, 2, 2, 'https://example.com/feed', 'My Example', 'v1'),
+				(3, 3, 3, 'https://other.example/feed', 'Other', NULL)`,
+			`INSERT INTO items(id, user_id, feed_id, guid, title) VALUES
+				(1, 1, 1, 'a', 'A'), (2, 1, 1, 'b', 'B'),
+				(3, 2, 2, 'a', 'A'), (4, 2, 2, 'b', 'B'),
+				(5, 3, 3, 'x', 'X')`,
+			`INSERT INTO item_state(item_id, user_id, is_read, is_bookmarked) VALUES(1, 1, 1, 0), (4, 2, 0, 1)`,
+			`INSERT INTO item_tags(item_id, user_id, tag) VALUES(3, 2, 'later')`,
+			`INSERT INTO feed_errors(feed_id, message) VALUES(2, 'timeout'), (3, 'gone')`,
+			`INSERT INTO saved_searches(user_id, name, query, feed_id) VALUES(2, 'Go', 'golang', 2)`,
+		} {
+			if _, err := d.Exec(stmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if d.Dialect() == db.Postgres {
+			for _, table := range []string{"users", "folders", "feeds", "items"} {
+				if _, err := d.Exec(`SELECT setval(pg_get_serial_sequence('` + table + `', 'id'), 10)`); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		if err := db.MigrateTo(d, 2); err != nil {
+			t.Fatal(err)
+		}
+		count := func(query string, args ...any) int {
+			t.Helper()
+			var n int
+			if err := d.QueryRow(query, args...).Scan(&n); err != nil {
+				t.Fatalf("%s: %v", query, err)
+			}
+			return n
+		}
+		if n := count(`SELECT COUNT(*) FROM sources`); n != 2 {
+			t.Errorf("%d sources, expected 2", n)
+		}
+		if n := count(`SELECT COUNT(*) FROM entries`); n != 3 {
+			t.Errorf("%d entries, expected 3", n)
+		}
+		if n := count(`SELECT COUNT(*) FROM items WHERE user_id=2`); n != 2 {
+			t.Errorf("user 2 sees %d items, expected 2", n)
+		}
+		var title *string
+		if err := d.QueryRow(`SELECT title FROM feeds WHERE id=1`).Scan(&title); err != nil || title != nil {
+			t.Errorf("feed 1 kept a title override: %v, %v", title, err)
+		}
+		if err := d.QueryRow(`SELECT title FROM feeds WHERE id=2`).Scan(&title); err != nil || title == nil || *title != "My Example" {
+			t.Errorf("feed 2 lost its title override: %v, %v", title, err)
+		}
+		// User 2's state and tags now point at the shared entries.
+		if n := count(`SELECT COUNT(*) FROM item_state WHERE user_id=2 AND item_id=2 AND is_bookmarked=1`); n != 1 {
+			t.Error("user 2's bookmark not moved to the shared entry")
+		}
+		if n := count(`SELECT COUNT(*) FROM item_tags WHERE user_id=2 AND item_id=1 AND tag='later'`); n != 1 {
+			t.Error("user 2's tag not moved to the shared entry")
+		}
+		if n := count(`SELECT COUNT(*) FROM feed_errors`); n != 1 {
+			t.Errorf("%d feed errors, expected only the source's own", n)
+		}
+		// Rebuilding feeds must not cascade to rows that reference it.
+		if n := count(`SELECT COUNT(*) FROM saved_searches WHERE feed_id=2`); n != 1 {
+			t.Error("saved search on feed 2 lost")
+		}
+
+		if err := db.MigrateTo(d, 1); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(`SELECT COUNT(*) FROM items`); n != 5 {
+			t.Errorf("%d items after reverting, expected 5", n)
+		}
+		if n := count(`SELECT COUNT(*) FROM feeds WHERE url='https://example.com/feed' AND etag='v1'`); n != 2 {
+			t.Errorf("%d feeds got their fetch state back, expected 2", n)
+		}
+		if n := count(`SELECT COUNT(*) FROM item_state JOIN items ON items.id = item_state.item_id
+			WHERE items.user_id=2 AND items.guid='b' AND item_state.is_bookmarked=1`); n != 1 {
+			t.Error("user 2's bookmark lost when reverting")
+		}
+		if n := count(`SELECT COUNT(*) FROM item_tags JOIN items ON items.id = item_tags.item_id
+			WHERE items.user_id=2 AND items.guid='a'`); n != 1 {
+			t.Error("user 2's tag lost when reverting")
+		}
+		if n := count(`SELECT COUNT(*) FROM feed_errors WHERE feed_id=3`); n != 1 {
+			t.Error("feed error not restored")
+		}
+		if n := count(`SELECT COUNT(*) FROM saved_searches WHERE feed_id=2`); n != 1 {
+			t.Error("saved search on feed 2 lost when reverting")
+		}
+	})
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`SELECT 1`, `SELECT 1`},
+		{`SELECT * FROM t WHERE a=? AND b=?`, `SELECT * FROM t WHERE a=$1 AND b=$2`},
+		{`SELECT '?', "x?" FROM t WHERE a=?`, `SELECT '?', "x?" FROM t WHERE a=$1`},
+		{`SELECT 'it''s?' WHERE a IN (?,?)`, `SELECT 'it''s?' WHERE a IN ($1,$2)`},
+	}
+	for _, tc := range tests {
+		if got := db.Postgres.Rebind(tc.query); got != tc.expected {
+			t.Errorf("Rebind(%q) = %q, expected %q", tc.query, got, tc.expected)
+		}
+		if got := db.SQLite.Rebind(tc.query); got != tc.query {
+			t.Errorf("SQLite rebind changed %q to %q", tc.query, got)
+		}
+	}
+}
@@ -3,38 +3,73 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"log"
 )
 
-func Migrate(db *sql.DB) error {
-	stmts := []string{
-		`PRAGMA foreign_keys = ON;`,
-		`CREATE TABLE IF NOT EXISTS users (
+// Migrate brings the schema to the latest version and sets up the search
+// index.
+func Migrate(db *DB) error {
+	if err := MigrateTo(db, LatestVersion()); err != nil {
+		return err
+	}
+	if db.Dialect() == Postgres {
+		// The Postgres search index is part of the schema.
+		return nil
+	}
+	return migrateSearchIndex(db)
+}
+
+// migrations are applied in order and must never be edited once released;
+// change the schema by appending a new version with both SQLite and Postgres
+// scripts.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up: `
+		CREATE TABLE IF NOT EXISTS users (
 			id INTEGER PRIMARY KEY,
 			email TEXT NOT NULL UNIQUE,
 			digest_last_sent_at DATETIME,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);`,
-		`CREATE TABLE IF NOT EXISTS sessions (
+		);
+		CREATE TABLE IF NOT EXISTS sessions (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			token TEXT UNIQUE NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			expires_at DATETIME NOT NULL,
+			last_seen_at DATETIME,
+			ip TEXT,
+			user_agent TEXT,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);`,
-		`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);`,
-		`CREATE TABLE IF NOT EXISTS magic_links (
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
+		CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
+		-- Personal API tokens, stored as SHA-256 hashes
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			prefix TEXT NOT NULL,
+			scope TEXT NOT NULL DEFAULT 'read',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_used_at DATETIME,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+		CREATE TABLE IF NOT EXISTS magic_links (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			email TEXT NOT NULL,
 			token TEXT UNIQUE NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			expires_at DATETIME NOT NULL,
 			used INTEGER DEFAULT 0
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_magic_links_token ON magic_links(token);`,
-		// OTP codes table for passwordless auth
-		`CREATE TABLE IF NOT EXISTS otp_codes (
+		);
+		CREATE INDEX IF NOT EXISTS idx_magic_links_token ON magic_links(token);
+		-- OTP codes table for passwordless auth
+		CREATE TABLE IF NOT EXISTS otp_codes (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			email TEXT NOT NULL,
 			code TEXT NOT NULL,
@@ -42,11 +77,11 @@ func Migrate(db *sql.DB) error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			expires_at DATETIME NOT NULL,
 			used INTEGER DEFAULT 0
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_otp_codes_email ON otp_codes(email);`,
-		`CREATE INDEX IF NOT EXISTS idx_otp_codes_expires ON otp_codes(expires_at);`,
-		// Rate limiting table
-		`CREATE TABLE IF NOT EXISTS auth_rate_limits (
+		);
+		CREATE INDEX IF NOT EXISTS idx_otp_codes_email ON otp_codes(email);
+		CREATE INDEX IF NOT EXISTS idx_otp_codes_expires ON otp_codes(expires_at);
+		-- Rate limiting table
+		CREATE TABLE IF NOT EXISTS auth_rate_limits (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			email TEXT NOT NULL,
 			action TEXT NOT NULL,
@@ -54,16 +89,16 @@ func Migrate(db *sql.DB) error {
 			first_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			last_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			locked_until DATETIME
-		);`,
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_limits_email_action ON auth_rate_limits(email, action);`,
-		`CREATE TABLE IF NOT EXISTS folders (
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_limits_email_action ON auth_rate_limits(email, action);
+		CREATE TABLE IF NOT EXISTS folders (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			name TEXT NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS feeds (
+		);
+		CREATE TABLE IF NOT EXISTS feeds (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			folder_id INTEGER NOT NULL,
@@ -73,12 +108,19 @@ func Migrate(db *sql.DB) error {
 			etag TEXT,
 			last_modified TEXT,
 			last_checked_at DATETIME,
+			next_check_at DATETIME,
+			last_success_at DATETIME,
+			last_error TEXT,
+			last_error_at DATETIME,
+			last_http_status INTEGER,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			paused INTEGER NOT NULL DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			UNIQUE(user_id, url),
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
 			FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS items (
+		);
+		CREATE TABLE IF NOT EXISTS items (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			feed_id INTEGER NOT NULL,
@@ -94,35 +136,1148 @@ func Migrate(db *sql.DB) error {
 			UNIQUE(user_id, feed_id, guid),
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
 			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS item_state (
+		);
+		CREATE TABLE IF NOT EXISTS item_state (
 			item_id INTEGER PRIMARY KEY,
 			user_id INTEGER NOT NULL,
 			is_read INTEGER DEFAULT 0,
 			is_bookmarked INTEGER DEFAULT 0,
 			bookmarked_at DATETIME,
+			is_hidden INTEGER NOT NULL DEFAULT 0,
+			is_highlighted INTEGER NOT NULL DEFAULT 0,
 			FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_items_feed_published ON items(feed_id, published_at DESC);`,
-		`CREATE INDEX IF NOT EXISTS idx_items_created ON items(created_at DESC);`,
-		`CREATE TABLE IF NOT EXISTS user_settings (
+		);
+		-- Recent fetch failures per feed, trimmed on insert
+		CREATE TABLE IF NOT EXISTS feed_errors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feed_id INTEGER NOT NULL,
+			http_status INTEGER,
+			message TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_feed_errors_feed ON feed_errors(feed_id, occurred_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_items_feed_published ON items(feed_id, published_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_items_created ON items(created_at DESC);
+		-- Named full-text queries shown as virtual folders
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,
+			folder_id INTEGER,
+			feed_id INTEGER,
+			unread_only INTEGER NOT NULL DEFAULT 0,
+			within_days INTEGER NOT NULL DEFAULT 0,
+			digest INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id);
+		-- Per-user rules applied to items as they are stored
+		CREATE TABLE IF NOT EXISTS filter_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			field TEXT NOT NULL,
+			match_type TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			action TEXT NOT NULL,
+			tag TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_filter_rules_user ON filter_rules(user_id);
+		CREATE TABLE IF NOT EXISTS item_tags (
+			item_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			tag TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(item_id, tag),
+			FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_item_tags_user_tag ON item_tags(user_id, tag);
+		-- One token per user for published (JSON Feed / Atom) URLs
+		CREATE TABLE IF NOT EXISTS publish_tokens (
+			user_id INTEGER PRIMARY KEY,
+			token TEXT UNIQUE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		-- Fever API key: md5 of "email:password" for a password set in settings
+		CREATE TABLE IF NOT EXISTS fever_credentials (
+			user_id INTEGER PRIMARY KEY,
+			api_key TEXT UNIQUE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS user_settings (
 			user_id INTEGER PRIMARY KEY,
 			retention_days INTEGER DEFAULT 30,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
+		);
+		CREATE INDEX IF NOT EXISTS idx_feeds_next_check ON feeds(next_check_at);
+`,
+		Down: `
+		DROP TABLE IF EXISTS items_fts;
+		DROP TABLE IF EXISTS user_settings;
+		DROP TABLE IF EXISTS fever_credentials;
+		DROP TABLE IF EXISTS publish_tokens;
+		DROP TABLE IF EXISTS item_tags;
+		DROP TABLE IF EXISTS filter_rules;
+		DROP TABLE IF EXISTS saved_searches;
+		DROP TABLE IF EXISTS feed_errors;
+		DROP TABLE IF EXISTS item_state;
+		DROP TABLE IF EXISTS items;
+		DROP TABLE IF EXISTS feeds;
+		DROP TABLE IF EXISTS folders;
+		DROP TABLE IF EXISTS auth_rate_limits;
+		DROP TABLE IF EXISTS otp_codes;
+		DROP TABLE IF EXISTS magic_links;
+		DROP TABLE IF EXISTS api_tokens;
+		DROP TABLE IF EXISTS sessions;
+		DROP TABLE IF EXISTS users;
+`,
+		PostgresUp: `
+		CREATE TABLE IF NOT EXISTS users (
+			id BIGSERIAL PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			digest_last_sent_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS sessions (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			token TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMPTZ NOT NULL,
+			last_seen_at TIMESTAMPTZ,
+			ip TEXT,
+			user_agent TEXT,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
+		CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
+		-- Personal API tokens, stored as SHA-256 hashes
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			prefix TEXT NOT NULL,
+			scope TEXT NOT NULL DEFAULT 'read',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			last_used_at TIMESTAMPTZ,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+		CREATE TABLE IF NOT EXISTS magic_links (
+			id BIGSERIAL PRIMARY KEY,
+			email TEXT NOT NULL,
+			token TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMPTZ NOT NULL,
+			used INTEGER DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_magic_links_token ON magic_links(token);
+		-- OTP codes table for passwordless auth
+		CREATE TABLE IF NOT EXISTS otp_codes (
+			id BIGSERIAL PRIMARY KEY,
+			email TEXT NOT NULL,
+			code TEXT NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMPTZ NOT NULL,
+			used INTEGER DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_otp_codes_email ON otp_codes(email);
+		CREATE INDEX IF NOT EXISTS idx_otp_codes_expires ON otp_codes(expires_at);
+		-- Rate limiting table
+		CREATE TABLE IF NOT EXISTS auth_rate_limits (
+			id BIGSERIAL PRIMARY KEY,
+			email TEXT NOT NULL,
+			action TEXT NOT NULL,
+			attempts INTEGER DEFAULT 1,
+			first_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			last_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			locked_until TIMESTAMPTZ
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_limits_email_action ON auth_rate_limits(email, action);
+		CREATE TABLE IF NOT EXISTS folders (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			name TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS feeds (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			folder_id BIGINT NOT NULL,
+			url TEXT NOT NULL,
+			title TEXT,
+			site_url TEXT,
+			etag TEXT,
+			last_modified TEXT,
+			last_checked_at TIMESTAMPTZ,
+			next_check_at TIMESTAMPTZ,
+			last_success_at TIMESTAMPTZ,
+			last_error TEXT,
+			last_error_at TIMESTAMPTZ,
+			last_http_status INTEGER,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			paused INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, url),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS items (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			feed_id BIGINT NOT NULL,
+			guid TEXT NOT NULL,
+			link TEXT,
+			title TEXT,
+			author TEXT,
+			published_at TIMESTAMPTZ,
+			summary_text TEXT,
+			content_html TEXT,
+			media_json TEXT,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, feed_id, guid),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS item_state (
+			item_id BIGINT PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			is_read INTEGER DEFAULT 0,
+			is_bookmarked INTEGER DEFAULT 0,
+			bookmarked_at TIMESTAMPTZ,
+			is_hidden INTEGER NOT NULL DEFAULT 0,
+			is_highlighted INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		-- Recent fetch failures per feed, trimmed on insert
+		CREATE TABLE IF NOT EXISTS feed_errors (
+			id BIGSERIAL PRIMARY KEY,
+			feed_id BIGINT NOT NULL,
+			http_status INTEGER,
+			message TEXT NOT NULL,
+			occurred_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_feed_errors_feed ON feed_errors(feed_id, occurred_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_items_feed_published ON items(feed_id, published_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_items_created ON items(created_at DESC);
+		-- Named full-text queries shown as virtual folders
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,
+			folder_id BIGINT,
+			feed_id BIGINT,
+			unread_only INTEGER NOT NULL DEFAULT 0,
+			within_days INTEGER NOT NULL DEFAULT 0,
+			digest INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id);
+		-- Per-user rules applied to items as they are stored
+		CREATE TABLE IF NOT EXISTS filter_rules (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			name TEXT NOT NULL,
+			field TEXT NOT NULL,
+			match_type TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			action TEXT NOT NULL,
+			tag TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_filter_rules_user ON filter_rules(user_id);
+		CREATE TABLE IF NOT EXISTS item_tags (
+			item_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			tag TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(item_id, tag),
+			FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_item_tags_user_tag ON item_tags(user_id, tag);
+		-- One token per user for published (JSON Feed / Atom) URLs
+		CREATE TABLE IF NOT EXISTS publish_tokens (
+			user_id BIGINT PRIMARY KEY,
+			token TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		-- Fever API key: md5 of "email:password" for a password set in settings
+		CREATE TABLE IF NOT EXISTS fever_credentials (
+			user_id BIGINT PRIMARY KEY,
+			api_key TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS user_settings (
+			user_id BIGINT PRIMARY KEY,
+			retention_days INTEGER DEFAULT 30,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_feeds_next_check ON feeds(next_check_at);
+		-- Search index: the feed service writes the plain text, Postgres keeps
+		-- the weighted document in sync.
+		CREATE TABLE IF NOT EXISTS items_fts (
+			rowid BIGINT PRIMARY KEY,
+			title TEXT,
+			author TEXT,
+			summary TEXT,
+			content TEXT,
+			document tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+				setweight(to_tsvector('english', COALESCE(author, '')), 'B') ||
+				setweight(to_tsvector('english', COALESCE(summary, '')), 'C') ||
+				setweight(to_tsvector('english', COALESCE(content, '')), 'D')
+			) STORED,
+			FOREIGN KEY(rowid) REFERENCES items(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_items_fts_document ON items_fts USING GIN(document);
+`,
+		PostgresDown: `
+		DROP TABLE IF EXISTS items_fts;
+		DROP TABLE IF EXISTS user_settings;
+		DROP TABLE IF EXISTS fever_credentials;
+		DROP TABLE IF EXISTS publish_tokens;
+		DROP TABLE IF EXISTS item_tags;
+		DROP TABLE IF EXISTS filter_rules;
+		DROP TABLE IF EXISTS saved_searches;
+		DROP TABLE IF EXISTS feed_errors;
+		DROP TABLE IF EXISTS item_state;
+		DROP TABLE IF EXISTS items;
+		DROP TABLE IF EXISTS feeds;
+		DROP TABLE IF EXISTS folders;
+		DROP TABLE IF EXISTS auth_rate_limits;
+		DROP TABLE IF EXISTS otp_codes;
+		DROP TABLE IF EXISTS magic_links;
+		DROP TABLE IF EXISTS api_tokens;
+		DROP TABLE IF EXISTS sessions;
+		DROP TABLE IF EXISTS users;
+`,
+	},
+	{
+		// Feeds are fetched once per URL. sources holds each URL with its
+		// fetch state and entries the items it carries; feeds become users'
+		// subscriptions to a source, and items a view of the entries each
+		// user is subscribed to, so per-user queries read as before.
+		// Per-user copies of an item collapse into the oldest, whose id the
+		// entry keeps; item_state and item_tags follow it.
+		Version:        2,
+		Name:           "shared_feeds",
+		RebuildsTables: true,
+		Up: `
+		CREATE TABLE sources (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL UNIQUE,
+			title TEXT,
+			site_url TEXT,
+			etag TEXT,
+			last_modified TEXT,
+			last_checked_at DATETIME,
+			next_check_at DATETIME,
+			last_success_at DATETIME,
+			last_error TEXT,
+			last_error_at DATETIME,
+			last_http_status INTEGER,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			paused INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		-- The oldest subscription to a URL gives the source its id and state.
+		INSERT INTO sources(id, url, title, site_url, etag, last_modified, last_checked_at, next_check_at,
+			last_success_at, last_error, last_error_at, last_http_status, consecutive_failures, paused, created_at)
+		SELECT id, url, title, site_url, etag, last_modified, last_checked_at, next_check_at,
+			last_success_at, last_error, last_error_at, last_http_status, consecutive_failures, paused, created_at
+		FROM feeds WHERE id IN (SELECT MIN(id) FROM feeds GROUP BY url);
+
+		CREATE TABLE entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source_id INTEGER NOT NULL,
+			guid TEXT NOT NULL,
+			link TEXT,
+			title TEXT,
+			author TEXT,
+			published_at DATETIME,
+			summary_text TEXT,
+			content_html TEXT,
+			media_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(source_id, guid),
+			FOREIGN KEY(source_id) REFERENCES sources(id) ON DELETE CASCADE
+		);
+		CREATE TABLE entry_map (
+			item_id INTEGER PRIMARY KEY,
+			entry_id INTEGER NOT NULL
+		);
+		INSERT INTO entry_map(item_id, entry_id)
+		SELECT items.id, canonical.id
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN (
+			SELECT feeds.url, items.guid, MIN(items.id) AS id
+			FROM items JOIN feeds ON feeds.id = items.feed_id
+			GROUP BY feeds.url, items.guid
+		) canonical ON canonical.url = feeds.url AND canonical.guid = items.guid;
+		INSERT INTO entries(id, source_id, guid, link, title, author, published_at, summary_text, content_html, media_json, created_at)
+		SELECT items.id, sources.id, items.guid, items.link, items.title, items.author, items.published_at,
+			items.summary_text, items.content_html, items.media_json, items.created_at
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.url = feeds.url
+		WHERE items.id = (SELECT entry_id FROM entry_map WHERE entry_map.item_id = items.id);
+
+		CREATE TABLE item_state_new (
+			item_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			is_read INTEGER DEFAULT 0,
+			is_bookmarked INTEGER DEFAULT 0,
+			bookmarked_at DATETIME,
+			is_hidden INTEGER NOT NULL DEFAULT 0,
+			is_highlighted INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY(user_id, item_id),
+			FOREIGN KEY(item_id) REFERENCES entries(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		INSERT INTO item_state_new(item_id, user_id, is_read, is_bookmarked, bookmarked_at, is_hidden, is_highlighted)
+		SELECT entry_map.entry_id, item_state.user_id, item_state.is_read, item_state.is_bookmarked,
+			item_state.bookmarked_at, item_state.is_hidden, item_state.is_highlighted
+		FROM item_state JOIN entry_map ON entry_map.item_id = item_state.item_id;
+
+		CREATE TABLE item_tags_new (
+			item_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			tag TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(user_id, item_id, tag),
+			FOREIGN KEY(item_id) REFERENCES entries(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		INSERT INTO item_tags_new(item_id, user_id, tag, created_at)
+		SELECT entry_map.entry_id, item_tags.user_id, item_tags.tag, item_tags.created_at
+		FROM item_tags JOIN entry_map ON entry_map.item_id = item_tags.item_id;
+
+		CREATE TABLE feed_errors_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source_id INTEGER NOT NULL,
+			http_status INTEGER,
+			message TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(source_id) REFERENCES sources(id) ON DELETE CASCADE
+		);
+		INSERT INTO feed_errors_new(id, source_id, http_status, message, occurred_at)
+		SELECT id, feed_id, http_status, message, occurred_at FROM feed_errors
+		WHERE feed_id IN (SELECT id FROM sources);
+
+		-- title is the user's name for the feed; NULL shows the source's.
+		CREATE TABLE feeds_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			folder_id INTEGER NOT NULL,
+			source_id INTEGER NOT NULL,
+			title TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, source_id),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+			FOREIGN KEY(source_id) REFERENCES sources(id)
+		);
+		INSERT INTO feeds_new(id, user_id, folder_id, source_id, title, created_at)
+		SELECT feeds.id, feeds.user_id, feeds.folder_id, sources.id, NULLIF(feeds.title, sources.title), feeds.created_at
+		FROM feeds JOIN sources ON sources.url = feeds.url;
+
+		-- The search index is rebuilt over entries on the next start.
+		DROP TABLE IF EXISTS items_fts;
+		DROP TABLE item_state;
+		DROP TABLE item_tags;
+		DROP TABLE feed_errors;
+		DROP TABLE items;
+		DROP TABLE feeds;
+		DROP TABLE entry_map;
+		ALTER TABLE item_state_new RENAME TO item_state;
+		ALTER TABLE item_tags_new RENAME TO item_tags;
+		ALTER TABLE feed_errors_new RENAME TO feed_errors;
+		ALTER TABLE feeds_new RENAME TO feeds;
+
+		CREATE INDEX idx_sources_next_check ON sources(next_check_at);
+		CREATE INDEX idx_entries_source_published ON entries(source_id, published_at DESC);
+		CREATE INDEX idx_entries_created ON entries(created_at DESC);
+		CREATE INDEX idx_feeds_source ON feeds(source_id);
+		CREATE INDEX idx_item_tags_user_tag ON item_tags(user_id, tag);
+		CREATE INDEX idx_feed_errors_source ON feed_errors(source_id, occurred_at DESC);
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id;
+`,
+		// Down gives every subscriber a copy of each entry again; the oldest
+		// subscription keeps the entry's id.
+		Down: `
+		DROP VIEW items;
+		CREATE TABLE feeds_old (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			folder_id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			title TEXT,
+			site_url TEXT,
+			etag TEXT,
+			last_modified TEXT,
+			last_checked_at DATETIME,
+			next_check_at DATETIME,
+			last_success_at DATETIME,
+			last_error TEXT,
+			last_error_at DATETIME,
+			last_http_status INTEGER,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			paused INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, url),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(folder_id) REFERENCES folders(id) ON DELETE CASCADE
+		);
+		INSERT INTO feeds_old(id, user_id, folder_id, url, title, site_url, etag, last_modified, last_checked_at,
+			next_check_at, last_success_at, last_error, last_error_at, last_http_status, consecutive_failures, paused, created_at)
+		SELECT feeds.id, feeds.user_id, feeds.folder_id, sources.url, COALESCE(feeds.title, sources.title), sources.site_url,
+			sources.etag, sources.last_modified, sources.last_checked_at, sources.next_check_at, sources.last_success_at,
+			sources.last_error, sources.last_error_at, sources.last_http_status, sources.consecutive_failures, sources.paused,
+			feeds.created_at
+		FROM feeds JOIN sources ON sources.id = feeds.source_id;
+
+		CREATE TABLE items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			feed_id INTEGER NOT NULL,
+			guid TEXT NOT NULL,
+			link TEXT,
+			title TEXT,
+			author TEXT,
+			published_at DATETIME,
+			summary_text TEXT,
+			content_html TEXT,
+			media_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, feed_id, guid),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		INSERT INTO items(id, user_id, feed_id, guid, link, title, author, published_at, summary_text, content_html, media_json, created_at)
+		SELECT entries.id, feeds.user_id, feeds.id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE feeds.id = (SELECT MIN(id) FROM feeds f WHERE f.source_id = entries.source_id);
+		INSERT INTO items(user_id, feed_id, guid, link, title, author, published_at, summary_text, content_html, media_json, created_at)
+		SELECT feeds.user_id, feeds.id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE feeds.id <> (SELECT MIN(id) FROM feeds f WHERE f.source_id = entries.source_id)
+		ORDER BY entries.id, feeds.id;
+		CREATE TABLE item_map (
+			user_id INTEGER NOT NULL,
+			entry_id INTEGER NOT NULL,
+			item_id INTEGER NOT NULL,
+			PRIMARY KEY(user_id, entry_id)
+		);
+		INSERT INTO item_map(user_id, entry_id, item_id)
+		SELECT feeds.user_id, entries.id, items.id
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		JOIN items ON items.feed_id = feeds.id AND items.guid = entries.guid;
+
+		CREATE TABLE item_state_old (
+			item_id INTEGER PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			is_read INTEGER DEFAULT 0,
+			is_bookmarked INTEGER DEFAULT 0,
+			bookmarked_at DATETIME,
+			is_hidden INTEGER NOT NULL DEFAULT 0,
+			is_highlighted INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		INSERT INTO item_state_old(item_id, user_id, is_read, is_bookmarked, bookmarked_at, is_hidden, is_highlighted)
+		SELECT item_map.item_id, item_state.user_id, item_state.is_read, item_state.is_bookmarked,
+			item_state.bookmarked_at, item_state.is_hidden, item_state.is_highlighted
+		FROM item_state JOIN item_map ON item_map.user_id = item_state.user_id AND item_map.entry_id = item_state.item_id;
+
+		CREATE TABLE item_tags_old (
+			item_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			tag TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(item_id, tag),
+			FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		INSERT INTO item_tags_old(item_id, user_id, tag, created_at)
+		SELECT item_map.item_id, item_tags.user_id, item_tags.tag, item_tags.created_at
+		FROM item_tags JOIN item_map ON item_map.user_id = item_tags.user_id AND item_map.entry_id = item_tags.item_id;
+
+		CREATE TABLE feed_errors_old (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feed_id INTEGER NOT NULL,
+			http_status INTEGER,
+			message TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		INSERT INTO feed_errors_old(id, feed_id, http_status, message, occurred_at)
+		SELECT feed_errors.id, MIN(feeds.id), feed_errors.http_status, feed_errors.message, feed_errors.occurred_at
+		FROM feed_errors JOIN feeds ON feeds.source_id = feed_errors.source_id
+		GROUP BY feed_errors.id;
+
+		DROP TABLE IF EXISTS items_fts;
+		DROP TABLE item_state;
+		DROP TABLE item_tags;
+		DROP TABLE feed_errors;
+		DROP TABLE item_map;
+		DROP TABLE entries;
+		DROP TABLE feeds;
+		DROP TABLE sources;
+		ALTER TABLE feeds_old RENAME TO feeds;
+		ALTER TABLE item_state_old RENAME TO item_state;
+		ALTER TABLE item_tags_old RENAME TO item_tags;
+		ALTER TABLE feed_errors_old RENAME TO feed_errors;
+
+		CREATE INDEX idx_feeds_next_check ON feeds(next_check_at);
+		CREATE INDEX idx_items_feed_published ON items(feed_id, published_at DESC);
+		CREATE INDEX idx_items_created ON items(created_at DESC);
+		CREATE INDEX idx_item_tags_user_tag ON item_tags(user_id, tag);
+		CREATE INDEX idx_feed_errors_feed ON feed_errors(feed_id, occurred_at DESC);
+`,
+		PostgresUp: `
+		CREATE TABLE sources (
+			id BIGSERIAL PRIMARY KEY,
+			url TEXT NOT NULL UNIQUE,
+			title TEXT,
+			site_url TEXT,
+			etag TEXT,
+			last_modified TEXT,
+			last_checked_at TIMESTAMPTZ,
+			next_check_at TIMESTAMPTZ,
+			last_success_at TIMESTAMPTZ,
+			last_error TEXT,
+			last_error_at TIMESTAMPTZ,
+			last_http_status INTEGER,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			paused INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+		-- The oldest subscription to a URL gives the source its id and state.
+		INSERT INTO sources(id, url, title, site_url, etag, last_modified, last_checked_at, next_check_at,
+			last_success_at, last_error, last_error_at, last_http_status, consecutive_failures, paused, created_at)
+		SELECT id, url, title, site_url, etag, last_modified, last_checked_at, next_check_at,
+			last_success_at, last_error, last_error_at, last_http_status, consecutive_failures, paused, created_at
+		FROM feeds WHERE id IN (SELECT MIN(id) FROM feeds GROUP BY url);
+		SELECT setval(pg_get_serial_sequence('sources', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM sources;
+
+		CREATE TABLE entries (
+			id BIGSERIAL PRIMARY KEY,
+			source_id BIGINT NOT NULL,
+			guid TEXT NOT NULL,
+			link TEXT,
+			title TEXT,
+			author TEXT,
+			published_at TIMESTAMPTZ,
+			summary_text TEXT,
+			content_html TEXT,
+			media_json TEXT,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(source_id, guid),
+			FOREIGN KEY(source_id) REFERENCES sources(id) ON DELETE CASCADE
+		);
+		CREATE TABLE entry_map (
+			item_id BIGINT PRIMARY KEY,
+			entry_id BIGINT NOT NULL
+		);
+		INSERT INTO entry_map(item_id, entry_id)
+		SELECT items.id, canonical.id
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN (
+			SELECT feeds.url, items.guid, MIN(items.id) AS id
+			FROM items JOIN feeds ON feeds.id = items.feed_id
+			GROUP BY feeds.url, items.guid
+		) canonical ON canonical.url = feeds.url AND canonical.guid = items.guid;
+		INSERT INTO entries(id, source_id, guid, link, title, author, published_at, summary_text, content_html, media_json, created_at)
+		SELECT items.id, sources.id, items.guid, items.link, items.title, items.author, items.published_at,
+			items.summary_text, items.content_html, items.media_json, items.created_at
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.url = feeds.url
+		WHERE items.id IN (SELECT entry_id FROM entry_map);
+		SELECT setval(pg_get_serial_sequence('entries', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM entries;
+
+		ALTER TABLE item_state DROP CONSTRAINT item_state_pkey, DROP CONSTRAINT item_state_item_id_fkey;
+		UPDATE item_state SET item_id = entry_map.entry_id FROM entry_map WHERE entry_map.item_id = item_state.item_id;
+		ALTER TABLE item_state
+			ADD CONSTRAINT item_state_pkey PRIMARY KEY(user_id, item_id),
+			ADD CONSTRAINT item_state_item_id_fkey FOREIGN KEY(item_id) REFERENCES entries(id) ON DELETE CASCADE;
+
+		ALTER TABLE item_tags DROP CONSTRAINT item_tags_pkey, DROP CONSTRAINT item_tags_item_id_fkey;
+		UPDATE item_tags SET item_id = entry_map.entry_id FROM entry_map WHERE entry_map.item_id = item_tags.item_id;
+		ALTER TABLE item_tags
+			ADD CONSTRAINT item_tags_pkey PRIMARY KEY(user_id, item_id, tag),
+			ADD CONSTRAINT item_tags_item_id_fkey FOREIGN KEY(item_id) REFERENCES entries(id) ON DELETE CASCADE;
+
+		ALTER TABLE items_fts DROP CONSTRAINT items_fts_rowid_fkey;
+		DELETE FROM items_fts WHERE rowid NOT IN (SELECT id FROM entries);
+		ALTER TABLE items_fts
+			ADD CONSTRAINT items_fts_rowid_fkey FOREIGN KEY(rowid) REFERENCES entries(id) ON DELETE CASCADE;
+
+		ALTER TABLE feed_errors DROP CONSTRAINT feed_errors_feed_id_fkey;
+		DELETE FROM feed_errors WHERE feed_id NOT IN (SELECT id FROM sources);
+		ALTER TABLE feed_errors RENAME COLUMN feed_id TO source_id;
+		ALTER TABLE feed_errors
+			ADD CONSTRAINT feed_errors_source_id_fkey FOREIGN KEY(source_id) REFERENCES sources(id) ON DELETE CASCADE;
+		ALTER INDEX idx_feed_errors_feed RENAME TO idx_feed_errors_source;
+
+		-- title is the user's name for the feed; NULL shows the source's.
+		ALTER TABLE feeds ADD COLUMN source_id BIGINT;
+		UPDATE feeds SET source_id = sources.id, title = NULLIF(feeds.title, sources.title)
+		FROM sources WHERE sources.url = feeds.url;
+		DROP TABLE items;
+		DROP TABLE entry_map;
+		ALTER TABLE feeds
+			ALTER COLUMN source_id SET NOT NULL,
+			ADD CONSTRAINT feeds_source_id_fkey FOREIGN KEY(source_id) REFERENCES sources(id),
+			ADD CONSTRAINT feeds_user_id_source_id_key UNIQUE(user_id, source_id),
+			DROP COLUMN url,
+			DROP COLUMN site_url,
+			DROP COLUMN etag,
+			DROP COLUMN last_modified,
+			DROP COLUMN last_checked_at,
+			DROP COLUMN next_check_at,
+			DROP COLUMN last_success_at,
+			DROP COLUMN last_error,
+			DROP COLUMN last_error_at,
+			DROP COLUMN last_http_status,
+			DROP COLUMN consecutive_failures,
+			DROP COLUMN paused;
+
+		CREATE INDEX idx_sources_next_check ON sources(next_check_at);
+		CREATE INDEX idx_entries_source_published ON entries(source_id, published_at DESC);
+		CREATE INDEX idx_entries_created ON entries(created_at DESC);
+		CREATE INDEX idx_feeds_source ON feeds(source_id);
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id;
+`,
+		PostgresDown: `
+		DROP VIEW items;
+		CREATE TABLE items (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			feed_id BIGINT NOT NULL,
+			guid TEXT NOT NULL,
+			link TEXT,
+			title TEXT,
+			author TEXT,
+			published_at TIMESTAMPTZ,
+			summary_text TEXT,
+			content_html TEXT,
+			media_json TEXT,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, feed_id, guid),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+		);
+		INSERT INTO items(id, user_id, feed_id, guid, link, title, author, published_at, summary_text, content_html, media_json, created_at)
+		SELECT entries.id, feeds.user_id, feeds.id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE feeds.id = (SELECT MIN(id) FROM feeds f WHERE f.source_id = entries.source_id);
+		SELECT setval(pg_get_serial_sequence('items', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM items;
+		INSERT INTO items(user_id, feed_id, guid, link, title, author, published_at, summary_text, content_html, media_json, created_at)
+		SELECT feeds.user_id, feeds.id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE feeds.id <> (SELECT MIN(id) FROM feeds f WHERE f.source_id = entries.source_id)
+		ORDER BY entries.id, feeds.id;
+		CREATE TABLE item_map (
+			user_id BIGINT NOT NULL,
+			entry_id BIGINT NOT NULL,
+			item_id BIGINT NOT NULL,
+			PRIMARY KEY(user_id, entry_id)
+		);
+		INSERT INTO item_map(user_id, entry_id, item_id)
+		SELECT feeds.user_id, entries.id, items.id
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		JOIN items ON items.feed_id = feeds.id AND items.guid = entries.guid;
+
+		ALTER TABLE item_state DROP CONSTRAINT item_state_pkey, DROP CONSTRAINT item_state_item_id_fkey;
+		DELETE FROM item_state WHERE NOT EXISTS (
+			SELECT 1 FROM item_map WHERE item_map.user_id = item_state.user_id AND item_map.entry_id = item_state.item_id);
+		UPDATE item_state SET item_id = item_map.item_id
+		FROM item_map WHERE item_map.user_id = item_state.user_id AND item_map.entry_id = item_state.item_id;
+		ALTER TABLE item_state
+			ADD CONSTRAINT item_state_pkey PRIMARY KEY(item_id),
+			ADD CONSTRAINT item_state_item_id_fkey FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE;
+
+		ALTER TABLE item_tags DROP CONSTRAINT item_tags_pkey, DROP CONSTRAINT item_tags_item_id_fkey;
+		DELETE FROM item_tags WHERE NOT EXISTS (
+			SELECT 1 FROM item_map WHERE item_map.user_id = item_tags.user_id AND item_map.entry_id = item_tags.item_id);
+		UPDATE item_tags SET item_id = item_map.item_id
+		FROM item_map WHERE item_map.user_id = item_tags.user_id AND item_map.entry_id = item_tags.item_id;
+		ALTER TABLE item_tags
+			ADD CONSTRAINT item_tags_pkey PRIMARY KEY(item_id, tag),
+			ADD CONSTRAINT item_tags_item_id_fkey FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE;
+
+		ALTER TABLE items_fts DROP CONSTRAINT items_fts_rowid_fkey;
+		DELETE FROM items_fts WHERE rowid NOT IN (SELECT id FROM items);
+		ALTER TABLE items_fts
+			ADD CONSTRAINT items_fts_rowid_fkey FOREIGN KEY(rowid) REFERENCES items(id) ON DELETE CASCADE;
+
+		ALTER TABLE feed_errors DROP CONSTRAINT feed_errors_source_id_fkey;
+		UPDATE feed_errors SET source_id = (SELECT MIN(id) FROM feeds WHERE feeds.source_id = feed_errors.source_id);
+		DELETE FROM feed_errors WHERE source_id IS NULL;
+		ALTER TABLE feed_errors RENAME COLUMN source_id TO feed_id;
+		ALTER TABLE feed_errors
+			ADD CONSTRAINT feed_errors_feed_id_fkey FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE;
+		ALTER INDEX idx_feed_errors_source RENAME TO idx_feed_errors_feed;
+
+		ALTER TABLE feeds
+			ADD COLUMN url TEXT,
+			ADD COLUMN site_url TEXT,
+			ADD COLUMN etag TEXT,
+			ADD COLUMN last_modified TEXT,
+			ADD COLUMN last_checked_at TIMESTAMPTZ,
+			ADD COLUMN next_check_at TIMESTAMPTZ,
+			ADD COLUMN last_success_at TIMESTAMPTZ,
+			ADD COLUMN last_error TEXT,
+			ADD COLUMN last_error_at TIMESTAMPTZ,
+			ADD COLUMN last_http_status INTEGER,
+			ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
+		UPDATE feeds SET url = sources.url, title = COALESCE(feeds.title, sources.title), site_url = sources.site_url,
+			etag = sources.etag, last_modified = sources.last_modified, last_checked_at = sources.last_checked_at,
+			next_check_at = sources.next_check_at, last_success_at = sources.last_success_at,
+			last_error = sources.last_error, last_error_at = sources.last_error_at,
+			last_http_status = sources.last_http_status, consecutive_failures = sources.consecutive_failures,
+			paused = sources.paused
+		FROM sources WHERE sources.id = feeds.source_id;
+		ALTER TABLE feeds
+			ALTER COLUMN url SET NOT NULL,
+			ADD CONSTRAINT feeds_user_id_url_key UNIQUE(user_id, url),
+			DROP COLUMN source_id;
+		DROP TABLE item_map;
+		DROP TABLE entries;
+		DROP TABLE sources;
+
+		CREATE INDEX idx_feeds_next_check ON feeds(next_check_at);
+		CREATE INDEX idx_items_feed_published ON items(feed_id, published_at DESC);
+		CREATE INDEX idx_items_created ON items(created_at DESC);
+`,
+	},
+	{
+		// Pausing is per subscription: one user resuming or pausing a feed
+		// no longer does so for everyone, and a source is only polled while
+		// someone still follows it unpaused. items hides entries older than
+		// each user's retention, keeping what they bookmarked or tagged, as
+		// entries themselves are kept for the longest retention among the
+		// source's subscribers.
+		Version: 3,
+		Name:    "per_user_feed_state",
+		Up: `
+		DROP VIEW items;
+		ALTER TABLE feeds ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
+		UPDATE feeds SET paused = (SELECT paused FROM sources WHERE sources.id = feeds.source_id);
+		ALTER TABLE sources DROP COLUMN paused;
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		LEFT JOIN user_settings ON user_settings.user_id = feeds.user_id
+		WHERE CAST(strftime('%s', COALESCE(entries.published_at, entries.created_at)) AS INTEGER)
+			>= CAST(strftime('%s', 'now') AS INTEGER)
+			 - CASE WHEN user_settings.retention_days > 0 THEN user_settings.retention_days ELSE 30 END * 86400
+		   OR EXISTS (SELECT 1 FROM item_state WHERE item_state.user_id = feeds.user_id
+				AND item_state.item_id = entries.id AND item_state.is_bookmarked = 1)
+		   OR EXISTS (SELECT 1 FROM item_tags WHERE item_tags.user_id = feeds.user_id AND item_tags.item_id = entries.id);
+`,
+		Down: `
+		DROP VIEW items;
+		ALTER TABLE sources ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
+		UPDATE sources SET paused = 1 WHERE NOT EXISTS (
+			SELECT 1 FROM feeds WHERE feeds.source_id = sources.id AND feeds.paused = 0);
+		ALTER TABLE feeds DROP COLUMN paused;
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id;
+`,
+		PostgresUp: `
+		DROP VIEW items;
+		ALTER TABLE feeds ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
+		UPDATE feeds SET paused = sources.paused FROM sources WHERE sources.id = feeds.source_id;
+		ALTER TABLE sources DROP COLUMN paused;
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		LEFT JOIN user_settings ON user_settings.user_id = feeds.user_id
+		WHERE COALESCE(entries.published_at, entries.created_at)
+			>= CURRENT_TIMESTAMP
+			 - CASE WHEN user_settings.retention_days > 0 THEN user_settings.retention_days ELSE 30 END * INTERVAL '1 day'
+		   OR EXISTS (SELECT 1 FROM item_state WHERE item_state.user_id = feeds.user_id
+				AND item_state.item_id = entries.id AND item_state.is_bookmarked = 1)
+		   OR EXISTS (SELECT 1 FROM item_tags WHERE item_tags.user_id = feeds.user_id AND item_tags.item_id = entries.id);
+`,
+		PostgresDown: `
+		DROP VIEW items;
+		ALTER TABLE sources ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
+		UPDATE sources SET paused = 1 WHERE NOT EXISTS (
+			SELECT 1 FROM feeds WHERE feeds.source_id = sources.id AND feeds.paused = 0);
+		ALTER TABLE feeds DROP COLUMN paused;
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries JOIN feeds ON feeds.source_id = entries.source_id;
+`,
+	},
+	{
+		// Sessions from Google Reader ClientLogin are signed in with the
+		// app password and may only use the Google Reader API, so they are
+		// kept apart from web sign-ins.
+		Version: 4,
+		Name:    "session_kinds",
+		Up: `
+		ALTER TABLE sessions ADD COLUMN kind TEXT NOT NULL DEFAULT 'web';
+`,
+		Down: `
+		ALTER TABLE sessions DROP COLUMN kind;
+`,
+		PostgresUp: `
+		ALTER TABLE sessions ADD COLUMN kind TEXT NOT NULL DEFAULT 'web';
+`,
+		PostgresDown: `
+		ALTER TABLE sessions DROP COLUMN kind;
+`,
+	},
+	{
+		// The retention filter in items compared a per-row expression and
+		// OR'd in bookmark and tag lookups, so no index could serve it. Each
+		// entry's time is now indexed per source, and items is split into
+		// the entries within retention, an indexed range, and the older
+		// ones the user bookmarked or tagged.
+		Version: 5,
+		Name:    "indexed_retention",
+		Up: `
+		DROP VIEW items;
+		CREATE INDEX idx_entries_source_time ON entries(source_id, CAST(strftime('%s', COALESCE(published_at, created_at)) AS INTEGER));
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE CAST(strftime('%s', COALESCE(entries.published_at, entries.created_at)) AS INTEGER)
+			>= CAST(strftime('%s', 'now') AS INTEGER)
+			 - COALESCE((SELECT retention_days FROM user_settings
+				WHERE user_settings.user_id = feeds.user_id AND retention_days > 0), 30) * 86400
+		UNION ALL
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE CAST(strftime('%s', COALESCE(entries.published_at, entries.created_at)) AS INTEGER)
+			< CAST(strftime('%s', 'now') AS INTEGER)
+			 - COALESCE((SELECT retention_days FROM user_settings
+				WHERE user_settings.user_id = feeds.user_id AND retention_days > 0), 30) * 86400
+		  AND (EXISTS (SELECT 1 FROM item_state WHERE item_state.user_id = feeds.user_id
+				AND item_state.item_id = entries.id AND item_state.is_bookmarked = 1)
+		   OR EXISTS (SELECT 1 FROM item_tags WHERE item_tags.user_id = feeds.user_id AND item_tags.item_id = entries.id));
+`,
+		Down: `
+		DROP VIEW items;
+		DROP INDEX idx_entries_source_time;
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		LEFT JOIN user_settings ON user_settings.user_id = feeds.user_id
+		WHERE CAST(strftime('%s', COALESCE(entries.published_at, entries.created_at)) AS INTEGER)
+			>= CAST(strftime('%s', 'now') AS INTEGER)
+			 - CASE WHEN user_settings.retention_days > 0 THEN user_settings.retention_days ELSE 30 END * 86400
+		   OR EXISTS (SELECT 1 FROM item_state WHERE item_state.user_id = feeds.user_id
+				AND item_state.item_id = entries.id AND item_state.is_bookmarked = 1)
+		   OR EXISTS (SELECT 1 FROM item_tags WHERE item_tags.user_id = feeds.user_id AND item_tags.item_id = entries.id);
+`,
+		PostgresUp: `
+		DROP VIEW items;
+		CREATE INDEX idx_entries_source_time ON entries(source_id, (COALESCE(published_at, created_at)));
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE COALESCE(entries.published_at, entries.created_at)
+			>= CURRENT_TIMESTAMP
+			 - COALESCE((SELECT retention_days FROM user_settings
+				WHERE user_settings.user_id = feeds.user_id AND retention_days > 0), 30) * INTERVAL '1 day'
+		UNION ALL
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		WHERE COALESCE(entries.published_at, entries.created_at)
+			< CURRENT_TIMESTAMP
+			 - COALESCE((SELECT retention_days FROM user_settings
+				WHERE user_settings.user_id = feeds.user_id AND retention_days > 0), 30) * INTERVAL '1 day'
+		  AND (EXISTS (SELECT 1 FROM item_state WHERE item_state.user_id = feeds.user_id
+				AND item_state.item_id = entries.id AND item_state.is_bookmarked = 1)
+		   OR EXISTS (SELECT 1 FROM item_tags WHERE item_tags.user_id = feeds.user_id AND item_tags.item_id = entries.id));
+`,
+		PostgresDown: `
+		DROP VIEW items;
+		DROP INDEX idx_entries_source_time;
+		CREATE VIEW items AS
+		SELECT entries.id, feeds.user_id, feeds.id AS feed_id, entries.guid, entries.link, entries.title, entries.author,
+			entries.published_at, entries.summary_text, entries.content_html, entries.media_json, entries.created_at
+		FROM entries
+		JOIN feeds ON feeds.source_id = entries.source_id
+		LEFT JOIN user_settings ON user_settings.user_id = feeds.user_id
+		WHERE COALESCE(entries.published_at, entries.created_at)
+			>= CURRENT_TIMESTAMP
+			 - CASE WHEN user_settings.retention_days > 0 THEN user_settings.retention_days ELSE 30 END * INTERVAL '1 day'
+		   OR EXISTS (SELECT 1 FROM item_state WHERE item_state.user_id = feeds.user_id
+				AND item_state.item_id = entries.id AND item_state.is_bookmarked = 1)
+		   OR EXISTS (SELECT 1 FROM item_tags WHERE item_tags.user_id = feeds.user_id AND item_tags.item_id = entries.id);
+`,
+	},
+}
+
+// upgradeLegacySchema adds the columns that unversioned databases gained
+// over time, which CREATE TABLE IF NOT EXISTS in the baseline would skip.
+func upgradeLegacySchema(db *DB) error {
+	columns := []struct {
+		table, name, definition string
+	}{
+		{"sessions", "last_seen_at", "DATETIME"},
+		{"sessions", "ip", "TEXT"},
+		{"sessions", "user_agent", "TEXT"},
+		{"feeds", "next_check_at", "DATETIME"},
+		{"feeds", "last_success_at", "DATETIME"},
+		{"feeds", "last_error", "TEXT"},
+		{"feeds", "last_error_at", "DATETIME"},
+		{"feeds", "last_http_status", "INTEGER"},
+		{"feeds", "consecutive_failures", "INTEGER NOT NULL DEFAULT 0"},
+		{"feeds", "paused", "INTEGER NOT NULL DEFAULT 0"},
+		{"item_state", "is_hidden", "INTEGER NOT NULL DEFAULT 0"},
+		{"item_state", "is_highlighted", "INTEGER NOT NULL DEFAULT 0"},
+		{"filter_rules", "tag", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, col := range columns {
+		if err := addColumnIfMissing(db, col.table, col.name, col.definition); err != nil {
+			return fmt.Errorf("migrate column %s.%s: %w", col.table, col.name, err)
+		}
 	}
+	return nil
+}
 
+// migrateSearchIndex creates the FTS5 index over entries. FTS5 is only compiled
+// into go-sqlite3 with -tags sqlite_fts5; without it search is disabled and
+// everything else keeps working.
+func migrateSearchIndex(db *DB) error {
+	var fts5 int
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return fmt.Errorf("migrate search: %w", err)
+	}
+	if fts5 == 0 {
+		log.Printf("sqlite built without FTS5 (build with -tags sqlite_fts5); search disabled")
+		return nil
+	}
+	stmts := []string{
+		// Rows are keyed by entries.id and written by the feed service,
+		// which strips HTML before indexing.
+		`CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(
+			title, author, summary, content,
+			tokenize = 'porter unicode61 remove_diacritics 2'
+		);`,
+		`CREATE TRIGGER IF NOT EXISTS entries_fts_delete AFTER DELETE ON entries BEGIN
+			DELETE FROM items_fts WHERE rowid = old.id;
+		END;`,
+	}
 	for _, stmt := range stmts {
 		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("migrate step: %w", err)
+			return fmt.Errorf("migrate search: %w", err)
 		}
 	}
 	return nil
 }
 
-func SeedDemoUser(db *sql.DB, id int64, email string) error {
-	_, err := db.Exec(`INSERT INTO users(id, email) VALUES(?, ?) ON CONFLICT(id) DO NOTHING`, id, email)
+// addColumnIfMissing adds a column to an existing table. Missing tables are
+// left for the baseline migration to create.
+func addColumnIfMissing(db *DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	tableExists, exists := false, false
+	for rows.Next() {
+		tableExists = true
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultVal, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if !tableExists || exists {
+		return nil
+	}
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
 	return err
 }
+
+func SeedDemoUser(db *DB, id int64, email string) error {
+	if _, err := db.Exec(`INSERT INTO users(id, email) VALUES(?, ?) ON CONFLICT(id) DO NOTHING`, id, email); err != nil {
+		return err
+	}
+	if db.Dialect() == Postgres {
+		// An explicit id does not advance the sequence; move it past the
+		// demo user so sign-ups do not collide with it.
+		_, err := db.Exec(`SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX(id) FROM users))`)
+		return err
+	}
+	return nil
+}
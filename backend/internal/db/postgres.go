@@ -0,0 +1,6 @@
+//go:build postgres
+
+package db
+
+// Postgres support links in the pgx driver; build with -tags postgres.
+import _ "github.com/jackc/pgx/v5/stdlib"
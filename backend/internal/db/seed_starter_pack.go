@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 )
 
@@ -153,7 +152,7 @@ var newUserStarterPack = []starterCategory{
 
 // SeedNewUser seeds a new user with a small starter pack of 3 folders with 2 feeds each.
 // This is called when a user first creates their account.
-func SeedNewUser(db *sql.DB, userID int64) error {
+func SeedNewUser(db *DB, userID int64) error {
 	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return fmt.Errorf("begin: %w", err)
@@ -161,23 +160,12 @@ func SeedNewUser(db *sql.DB, userID int64) error {
 	defer tx.Rollback()
 
 	for _, category := range newUserStarterPack {
-		res, err := tx.Exec(`INSERT INTO folders(user_id, name) VALUES(?, ?)`, userID, category.Name)
-		if err != nil {
+		var folderID int64
+		if err := tx.QueryRow(`INSERT INTO folders(user_id, name) VALUES(?, ?) RETURNING id`, userID, category.Name).Scan(&folderID); err != nil {
 			return fmt.Errorf("insert folder %q: %w", category.Name, err)
 		}
-		folderID, err := res.LastInsertId()
-		if err != nil {
-			return fmt.Errorf("folder id %q: %w", category.Name, err)
-		}
 		for _, feed := range category.Feeds {
-			if _, err := tx.Exec(
-				`INSERT INTO feeds(user_id, folder_id, url, title, site_url) VALUES(?, ?, ?, ?, ?)`,
-				userID,
-				folderID,
-				feed.URL,
-				feed.Title,
-				feed.SiteURL,
-			); err != nil {
+			if err := subscribeStarterFeed(tx, userID, folderID, feed); err != nil {
 				return fmt.Errorf("insert feed %q: %w", feed.Title, err)
 			}
 		}
@@ -189,7 +177,7 @@ func SeedNewUser(db *sql.DB, userID int64) error {
 	return nil
 }
 
-func SeedStarterPack(db *sql.DB, userID int64) error {
+func SeedStarterPack(db *DB, userID int64) error {
 	tx, err := db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return fmt.Errorf("begin: %w", err)
@@ -199,8 +187,8 @@ func SeedStarterPack(db *sql.DB, userID int64) error {
 	if _, err := tx.Exec(`DELETE FROM item_state WHERE user_id=?`, userID); err != nil {
 		return fmt.Errorf("clear item_state: %w", err)
 	}
-	if _, err := tx.Exec(`DELETE FROM items WHERE user_id=?`, userID); err != nil {
-		return fmt.Errorf("clear items: %w", err)
+	if _, err := tx.Exec(`DELETE FROM item_tags WHERE user_id=?`, userID); err != nil {
+		return fmt.Errorf("clear item_tags: %w", err)
 	}
 	if _, err := tx.Exec(`DELETE FROM feeds WHERE user_id=?`, userID); err != nil {
 		return fmt.Errorf("clear feeds: %w", err)
@@ -208,25 +196,17 @@ func SeedStarterPack(db *sql.DB, userID int64) error {
 	if _, err := tx.Exec(`DELETE FROM folders WHERE user_id=?`, userID); err != nil {
 		return fmt.Errorf("clear folders: %w", err)
 	}
+	if _, err := tx.Exec(`DELETE FROM sources WHERE id NOT IN (SELECT source_id FROM feeds)`); err != nil {
+		return fmt.Errorf("clear sources: %w", err)
+	}
 
 	for _, category := range starterPack {
-		res, err := tx.Exec(`INSERT INTO folders(user_id, name) VALUES(?, ?)`, userID, category.Name)
-		if err != nil {
+		var folderID int64
+		if err := tx.QueryRow(`INSERT INTO folders(user_id, name) VALUES(?, ?) RETURNING id`, userID, category.Name).Scan(&folderID); err != nil {
 			return fmt.Errorf("insert folder %q: %w", category.Name, err)
 		}
-		folderID, err := res.LastInsertId()
-		if err != nil {
-			return fmt.Errorf("folder id %q: %w", category.Name, err)
-		}
 		for _, feed := range category.Feeds {
-			if _, err := tx.Exec(
-				`INSERT INTO feeds(user_id, folder_id, url, title, site_url) VALUES(?, ?, ?, ?, ?)`,
-				userID,
-				folderID,
-				feed.URL,
-				feed.Title,
-				feed.SiteURL,
-			); err != nil {
+			if err := subscribeStarterFeed(tx, userID, folderID, feed); err != nil {
 				return fmt.Errorf("insert feed %q: %w", feed.Title, err)
 			}
 		}
@@ -237,3 +217,15 @@ func SeedStarterPack(db *sql.DB, userID int64) error {
 	}
 	return nil
 }
+
+// subscribeStarterFeed subscribes the user to a starter feed, sharing its
+// source with anyone already subscribed to the URL.
+func subscribeStarterFeed(tx *Tx, userID, folderID int64, feed starterFeed) error {
+	if _, err := tx.Exec(`INSERT INTO sources(url, title, site_url) VALUES(?, ?, ?) ON CONFLICT(url) DO NOTHING`,
+		feed.URL, feed.Title, feed.SiteURL); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO feeds(user_id, folder_id, source_id) SELECT ?, ?, id FROM sources WHERE url=?`,
+		userID, folderID, feed.URL)
+	return err
+}
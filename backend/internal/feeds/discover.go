@@ -0,0 +1,255 @@
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Candidate is a validated feed found while resolving a page URL.
+type Candidate struct {
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Format      string     `json:"format"` // rss, atom or json, with version when known
+	ItemCount   int        `json:"itemCount"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+const (
+	maxDiscoverCandidates = 12
+	discoverConcurrency   = 4
+)
+
+// commonFeedPaths are tried against the site root when a page advertises no feeds.
+var commonFeedPaths = []string{"/feed", "/feed/", "/rss", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml"}
+
+var youtubeChannelPath = regexp.MustCompile(`^/channel/(UC[\w-]+)`)
+
+// Discover resolves a user-supplied URL into the feeds it points at. The URL
+// may be a feed itself, an HTML page advertising feeds, or a YouTube, Reddit
+// or GitHub page with a well-known feed location. Every candidate is fetched
+// and parsed; only those that parse are returned, in discovery order.
+func (f *Fetcher) Discover(ctx context.Context, rawURL string) ([]Candidate, error) {
+	pageURL, err := normalizePageURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := siteFeedURLs(pageURL)
+	page, pageErr := f.get(ctx, pageURL.String())
+	if pageErr == nil {
+		if _, err := gofeed.NewParser().Parse(bytes.NewReader(page.body)); err == nil {
+			candidates = append([]string{page.finalURL}, candidates...)
+		} else {
+			linked := DiscoverFeedLinks(page.body, page.finalURL)
+			for _, l := range linked {
+				candidates = append(candidates, l.URL)
+			}
+			if len(linked) == 0 {
+				candidates = append(candidates, commonPathURLs(page.finalURL)...)
+			}
+		}
+	} else if len(candidates) == 0 {
+		candidates = commonPathURLs(pageURL.String())
+	}
+
+	found := f.validateCandidates(ctx, dedupeStrings(candidates))
+	if len(found) == 0 {
+		if pageErr != nil {
+			return nil, fmt.Errorf("fetch page: %w", pageErr)
+		}
+		return nil, errors.New("no feeds found")
+	}
+	return found, nil
+}
+
+func normalizePageURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("url required")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", raw)
+	}
+	return u, nil
+}
+
+// siteFeedURLs derives feed URLs from well-known URL shapes without fetching.
+func siteFeedURLs(u *url.URL) []string {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	parts := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	switch host {
+	case "youtube.com":
+		if m := youtubeChannelPath.FindStringSubmatch(u.Path); m != nil {
+			return []string{"https://www.youtube.com/feeds/videos.xml?channel_id=" + m[1]}
+		}
+		if list := u.Query().Get("list"); list != "" {
+			return []string{"https://www.youtube.com/feeds/videos.xml?playlist_id=" + url.QueryEscape(list)}
+		}
+		if len(parts) >= 2 && parts[0] == "user" {
+			return []string{"https://www.youtube.com/feeds/videos.xml?user=" + url.QueryEscape(parts[1])}
+		}
+		// @handle and /c/ pages advertise their feed via <link rel=alternate>.
+	case "reddit.com", "old.reddit.com":
+		if len(parts) >= 2 && (parts[0] == "r" || parts[0] == "user" || parts[0] == "u") {
+			return []string{fmt.Sprintf("https://www.reddit.com/%s/%s/.rss", parts[0], parts[1])}
+		}
+		if len(parts) == 0 {
+			return []string{"https://www.reddit.com/.rss"}
+		}
+	case "github.com":
+		switch len(parts) {
+		case 0:
+		case 1:
+			return []string{fmt.Sprintf("https://github.com/%s.atom", parts[0])}
+		default:
+			repo := fmt.Sprintf("https://github.com/%s/%s", parts[0], parts[1])
+			return []string{repo + "/releases.atom", repo + "/tags.atom", repo + "/commits.atom"}
+		}
+	}
+	return nil
+}
+
+func commonPathURLs(pageURL string) []string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	root := &url.URL{Scheme: u.Scheme, Host: u.Host}
+	out := make([]string, 0, len(commonFeedPaths))
+	for _, p := range commonFeedPaths {
+		out = append(out, root.String()+p)
+	}
+	return out
+}
+
+// validateCandidates fetches and parses each URL, keeping the ones that are
+// real feeds. Order follows the input; duplicates that resolve to the same
+// final URL are dropped.
+func (f *Fetcher) validateCandidates(ctx context.Context, urls []string) []Candidate {
+	if len(urls) > maxDiscoverCandidates {
+		urls = urls[:maxDiscoverCandidates]
+	}
+	results := make([]*Candidate, len(urls))
+	sem := make(chan struct{}, discoverConcurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			if c, err := f.validateFeed(ctx, u); err == nil {
+				results[i] = c
+			}
+		}(i, u)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	var out []Candidate
+	for _, c := range results {
+		if c == nil || seen[c.URL] {
+			continue
+		}
+		seen[c.URL] = true
+		out = append(out, *c)
+	}
+	return out
+}
+
+func (f *Fetcher) validateFeed(ctx context.Context, feedURL string) (*Candidate, error) {
+	page, err := f.get(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(page.body))
+	if err != nil {
+		return nil, err
+	}
+	format := feed.FeedType
+	if feed.FeedVersion != "" {
+		format += " " + feed.FeedVersion
+	}
+	return &Candidate{
+		URL:         page.finalURL,
+		Title:       strings.TrimSpace(feed.Title),
+		Format:      format,
+		ItemCount:   len(feed.Items),
+		LastUpdated: feedLastUpdated(feed),
+	}, nil
+}
+
+func feedLastUpdated(feed *gofeed.Feed) *time.Time {
+	var latest *time.Time
+	consider := func(t *time.Time) {
+		if t != nil && (latest == nil || t.After(*latest)) {
+			latest = t
+		}
+	}
+	consider(feed.UpdatedParsed)
+	consider(feed.PublishedParsed)
+	for _, item := range feed.Items {
+		consider(item.UpdatedParsed)
+		consider(item.PublishedParsed)
+	}
+	return latest
+}
+
+type fetchedPage struct {
+	finalURL string
+	body     []byte
+}
+
+// get fetches rawURL and fails on any non-2xx status.
+func (f *Fetcher) get(ctx context.Context, rawURL string) (*fetchedPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.ua)
+	resp, body, err := f.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &HTTPError{StatusCode: resp.StatusCode}
+	}
+	finalURL := rawURL
+	if resp.Request != nil && resp.Request.URL != nil {
+		finalURL = resp.Request.URL.String()
+	}
+	return &fetchedPage{finalURL: finalURL, body: body}, nil
+}
+
+func dedupeStrings(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSiteFeedURLs(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected []string
+	}{
+		{"https://www.youtube.com/channel/UCabc-123", []string{"https://www.youtube.com/feeds/videos.xml?channel_id=UCabc-123"}},
+		{"https://www.youtube.com/playlist?list=PL123", []string{"https://www.youtube.com/feeds/videos.xml?playlist_id=PL123"}},
+		{"https://www.reddit.com/r/golang/", []string{"https://www.reddit.com/r/golang/.rss"}},
+		{"https://github.com/golang", []string{"https://github.com/golang.atom"}},
+		{"https://github.com/golang/go", []string{
+			"https://github.com/golang/go/releases.atom",
+			"https://github.com/golang/go/tags.atom",
+			"https://github.com/golang/go/commits.atom",
+		}},
+		{"https://example.com/blog", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.raw, func(t *testing.T) {
+			u, _ := url.Parse(tc.raw)
+			if result := siteFeedURLs(u); !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("siteFeedURLs(%q) = %v, expected %v", tc.raw, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<link rel="alternate" type="application/rss+xml" href="/feed">
+			<link rel="alternate" type="application/rss+xml" href="/broken">
+		</head></html>`))
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("not a feed"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := NewFetcher("test")
+	fetcher.SetHostLimits(0, 0)
+	candidates, err := fetcher.Discover(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("Expected 1 valid candidate, got %d: %v", len(candidates), candidates)
+	}
+	c := candidates[0]
+	if c.URL != srv.URL+"/feed" || c.Title != "Test" || c.ItemCount != 1 || c.Format != "rss 2.0" {
+		t.Errorf("Unexpected candidate %+v", c)
+	}
+
+	// A direct feed URL resolves to itself.
+	candidates, err = fetcher.Discover(context.Background(), srv.URL+"/feed")
+	if err != nil || len(candidates) != 1 || candidates[0].URL != srv.URL+"/feed" {
+		t.Errorf("Expected direct feed to resolve to itself, got %v, %v", candidates, err)
+	}
+}
@@ -0,0 +1,158 @@
+package feeds
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FeedLink is a feed advertised by an HTML page.
+type FeedLink struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	Type       string `json:"type,omitempty"` // rss, atom, json, or empty if unknown
+	Comments   bool   `json:"comments,omitempty"`
+	SameOrigin bool   `json:"sameOrigin"`
+	fromAnchor bool   // found in an <a> rather than a <link>
+	position   int
+}
+
+// DiscoverFeedLinks returns the feeds a page points at, best first:
+// <link rel="alternate"> entries before plain <a> links, main feeds before
+// comment feeds, same-origin before third-party, then document order. When a
+// site advertises the same feed as both RSS and Atom only the first is kept.
+func DiscoverFeedLinks(body []byte, baseURL string) []FeedLink {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	base, _ := url.Parse(baseURL)
+
+	var links []FeedLink
+	add := func(href, title, typ string, fromAnchor bool) {
+		resolved := ResolveRelative(baseURL, strings.TrimSpace(href))
+		u, err := url.Parse(resolved)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		lower := strings.ToLower(resolved + " " + title)
+		links = append(links, FeedLink{
+			URL:        resolved,
+			Title:      strings.TrimSpace(title),
+			Type:       typ,
+			Comments:   strings.Contains(lower, "comment"),
+			SameOrigin: base != nil && strings.EqualFold(u.Hostname(), base.Hostname()),
+			fromAnchor: fromAnchor,
+			position:   len(links),
+		})
+	}
+
+	doc.Find("link[href]").Each(func(_ int, s *goquery.Selection) {
+		rel := strings.ToLower(s.AttrOr("rel", ""))
+		typ := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+		if !strings.Contains(rel, "alternate") && !strings.Contains(rel, "feed") {
+			return
+		}
+		if isFeedMediaType(typ) || (typ == "" && strings.Contains(rel, "feed")) {
+			add(s.AttrOr("href", ""), s.AttrOr("title", ""), feedKind(typ), false)
+		}
+	})
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href := s.AttrOr("href", "")
+		lower := strings.ToLower(href)
+		if strings.Contains(lower, "rss") || strings.Contains(lower, "atom") || strings.Contains(lower, "feed") {
+			add(href, s.Text(), "", true)
+		}
+	})
+
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.fromAnchor != b.fromAnchor {
+			return !a.fromAnchor
+		}
+		if a.Comments != b.Comments {
+			return !a.Comments
+		}
+		if a.SameOrigin != b.SameOrigin {
+			return a.SameOrigin
+		}
+		return a.position < b.position
+	})
+	return dedupeFeedLinks(links)
+}
+
+// AmbiguousFeedLinks reports whether more than one main (non-comment) feed is
+// advertised via <link>, so picking the first would be a guess.
+func AmbiguousFeedLinks(links []FeedLink) bool {
+	main := 0
+	for _, l := range links {
+		if !l.fromAnchor && !l.Comments {
+			main++
+		}
+	}
+	return main > 1
+}
+
+func dedupeFeedLinks(links []FeedLink) []FeedLink {
+	seenURL := make(map[string]bool, len(links))
+	seenTitle := make(map[string]string, len(links)) // normalized title -> type
+	out := make([]FeedLink, 0, len(links))
+	for _, l := range links {
+		if seenURL[l.URL] {
+			continue
+		}
+		seenURL[l.URL] = true
+		if key := feedTitleKey(l.Title); key != "" && !l.fromAnchor {
+			if typ, ok := seenTitle[key]; ok && typ != l.Type {
+				continue // same feed in another format
+			}
+			seenTitle[key] = l.Type
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// feedTitleKey normalizes a feed title so "Blog RSS" and "Blog (Atom)" match.
+func feedTitleKey(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		switch w {
+		case "rss", "atom", "feed", "xml", "json", "2", "0", "1":
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return strings.Join(kept, " ")
+}
+
+func feedKind(typ string) string {
+	switch {
+	case strings.Contains(typ, "atom"):
+		return "atom"
+	case strings.Contains(typ, "rss"):
+		return "rss"
+	case strings.Contains(typ, "json"):
+		return "json"
+	}
+	return ""
+}
+
+func isFeedMediaType(typ string) bool {
+	switch {
+	case strings.Contains(typ, "rss"), strings.Contains(typ, "atom"):
+		return true
+	case typ == "application/feed+json", typ == "application/json":
+		return true
+	case strings.HasSuffix(typ, "/xml"):
+		return true
+	}
+	return false
+}
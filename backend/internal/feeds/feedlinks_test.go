@@ -0,0 +1,67 @@
+package feeds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func linkURLs(links []FeedLink) []string {
+	var out []string
+	for _, l := range links {
+		out = append(out, l.URL)
+	}
+	return out
+}
+
+func TestDiscoverFeedLinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		expected  []string
+		ambiguous bool
+	}{
+		{
+			name: "ignores non-feed links",
+			html: `<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+				<link rel="alternate" hreflang="fr" href="/fr/">
+				<link rel="stylesheet" href="/style.css">`,
+			expected: []string{"https://example.com/feed.xml"},
+		},
+		{
+			name: "main feed before comment feed",
+			html: `<link rel="alternate" type="application/rss+xml" title="Comments Feed" href="/comments/feed/">
+				<link rel="alternate" type="application/rss+xml" title="Blog" href="/feed/">`,
+			expected: []string{"https://example.com/feed/", "https://example.com/comments/feed/"},
+		},
+		{
+			name: "same origin preferred",
+			html: `<link rel="alternate" type="application/atom+xml" href="https://cdn.example.net/atom.xml">
+				<link rel="alternate" type="application/rss+xml" href="/rss.xml">`,
+			expected:  []string{"https://example.com/rss.xml", "https://cdn.example.net/atom.xml"},
+			ambiguous: true,
+		},
+		{
+			name: "rss and atom of the same feed collapse",
+			html: `<link rel="alternate" type="application/rss+xml" title="Blog RSS" href="/rss.xml">
+				<link rel="alternate" type="application/atom+xml" title="Blog (Atom)" href="/atom.xml">`,
+			expected: []string{"https://example.com/rss.xml"},
+		},
+		{
+			name: "link tags before anchors",
+			html: `<a href="/rss-policy">RSS policy</a>
+				<link rel="alternate" type="application/rss+xml" href="/feed.xml">`,
+			expected: []string{"https://example.com/feed.xml", "https://example.com/rss-policy"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			links := DiscoverFeedLinks([]byte("<html><head>"+tc.html+"</head></html>"), "https://example.com/post")
+			if result := linkURLs(links); !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("DiscoverFeedLinks() = %v, expected %v", result, tc.expected)
+			}
+			if result := AmbiguousFeedLinks(links); result != tc.ambiguous {
+				t.Errorf("AmbiguousFeedLinks() = %v, expected %v", result, tc.ambiguous)
+			}
+		})
+	}
+}
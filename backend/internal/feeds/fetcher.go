@@ -5,14 +5,15 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/mmcdole/gofeed"
 )
 
@@ -22,25 +23,153 @@ type FetchResult struct {
 	Items        []*gofeed.Item
 	Etag         string
 	LastModified string
+	Hints        PollHints
+	StatusCode   int
+	// PermanentURL is set when the feed was reached only through permanent
+	// redirects (301/308); callers should store it in place of the old URL.
+	PermanentURL string
+	// FeedURL is the URL the feed was parsed from. It differs from the
+	// requested URL when that pointed at an HTML page advertising the feed.
+	FeedURL string
+	// Candidates lists every feed the HTML page advertised, best first; it is
+	// nil when the requested URL was a feed.
+	Candidates []FeedLink
+}
+
+// CanonicalURL is the URL the feed should be stored under: the permanent
+// redirect target if there was one, else the URL it was parsed from.
+func (r *FetchResult) CanonicalURL() string {
+	if r.PermanentURL != "" {
+		return r.PermanentURL
+	}
+	return r.FeedURL
+}
+
+// HTTPError is returned when the feed server answers with an error status.
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration // from Retry-After on 429/503, zero if absent
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("status %d", e.StatusCode)
+}
+
+// Gone reports whether the server said the feed is permanently gone.
+func (e *HTTPError) Gone() bool {
+	return e.StatusCode == http.StatusGone
+}
+
+// Throttled reports whether the server asked us to slow down.
+func (e *HTTPError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// AsHTTPError returns the HTTPError wrapped in err, if any.
+func AsHTTPError(err error) (*HTTPError, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr, true
+	}
+	return nil, false
+}
+
+// StatusCode returns the HTTP status carried by err, or 0 if err is not an
+// HTTPError.
+func StatusCode(err error) int {
+	if httpErr, ok := AsHTTPError(err); ok {
+		return httpErr.StatusCode
+	}
+	return 0
 }
 
 type Fetcher struct {
 	client *http.Client
-	parser *gofeed.Parser
 	ua     string
+	hosts  *HostLimiter
 }
 
 func NewFetcher(userAgent string) *Fetcher {
-	client := &http.Client{Timeout: 20 * time.Second}
-	parser := gofeed.NewParser()
-	parser.Client = client
 	return &Fetcher{
-		client: client,
-		parser: parser,
+		client: &http.Client{Timeout: 20 * time.Second, CheckRedirect: checkRedirect},
 		ua:     userAgent,
+		hosts:  NewHostLimiter(2, time.Second),
 	}
 }
 
+// maxRedirects matches the net/http default.
+const maxRedirects = 10
+
+// hostHop holds the host slot of the request currently in flight; redirects
+// swap it for a slot on the next hop's host.
+type hostHop struct {
+	hosts   *HostLimiter
+	release func()
+}
+
+type hostHopKey struct{}
+
+func (h *hostHop) acquire(req *http.Request) error {
+	release, err := h.hosts.Acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return err
+	}
+	h.release = release
+	return nil
+}
+
+func (h *hostHop) done() {
+	if h.release != nil {
+		h.release()
+		h.release = nil
+	}
+}
+
+// checkRedirect moves the request's host slot to the redirect target, so a
+// redirect to another host waits for that host's limit like any request.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if hop, ok := req.Context().Value(hostHopKey{}).(*hostHop); ok {
+		hop.done()
+		return hop.acquire(req)
+	}
+	return nil
+}
+
+// SetHostLimits sets how many requests may be in flight to one host at a
+// time and the minimum spacing between request starts to that host.
+func (f *Fetcher) SetHostLimits(maxConns int, minGap time.Duration) {
+	f.hosts = NewHostLimiter(maxConns, minGap)
+}
+
+// do performs req while holding a slot for the host of each hop and returns
+// the response with its body already read and closed, so no slot is held
+// across parsing or follow-up requests; those take their own.
+func (f *Fetcher) do(req *http.Request) (*http.Response, []byte, error) {
+	hop := &hostHop{hosts: f.hosts}
+	req = req.WithContext(context.WithValue(req.Context(), hostHopKey{}, hop))
+	if err := hop.acquire(req); err != nil {
+		return nil, nil, err
+	}
+	defer hop.done()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified || resp.StatusCode >= 400 {
+		return resp, nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read: %w", err)
+	}
+	return resp, body, nil
+}
+
 func (f *Fetcher) Fetch(ctx context.Context, feedURL string, etag string, lastModified string) (*FetchResult, bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
 	if err != nil {
@@ -54,24 +183,31 @@ func (f *Fetcher) Fetch(ctx context.Context, feedURL string, etag string, lastMo
 		req.Header.Set("If-Modified-Since", lastModified)
 	}
 
-	resp, err := f.client.Do(req)
+	resp, body, err := f.do(req)
 	if err != nil {
 		return nil, false, fmt.Errorf("fetch: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotModified {
-		return nil, true, nil
+		// Only the polling hints are meaningful on a 304.
+		return &FetchResult{
+			Hints:        ParsePollHints(resp.Header, nil, nil, time.Now()),
+			StatusCode:   resp.StatusCode,
+			PermanentURL: permanentRedirectTarget(resp, feedURL),
+			FeedURL:      feedURL,
+		}, true, nil
 	}
 	if resp.StatusCode >= 400 {
-		return nil, false, fmt.Errorf("status %d", resp.StatusCode)
+		httpErr := &HTTPError{StatusCode: resp.StatusCode}
+		if httpErr.Throttled() {
+			httpErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		return nil, false, httpErr
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, false, fmt.Errorf("read: %w", err)
-	}
-	feed, err := f.parser.Parse(bytes.NewReader(body))
+	// A gofeed.Parser keeps state while parsing, so concurrent fetches each
+	// need their own.
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
 	if err != nil {
 		contentType := resp.Header.Get("Content-Type")
 		baseURL := feedURL
@@ -79,8 +215,13 @@ func (f *Fetcher) Fetch(ctx context.Context, feedURL string, etag string, lastMo
 			baseURL = resp.Request.URL.String()
 		}
 		if strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml+xml") || len(body) > 0 {
-			if discovered := discoverFeedURL(body, baseURL); discovered != "" && discovered != feedURL {
-				return f.Fetch(ctx, discovered, "", "")
+			links := DiscoverFeedLinks(body, baseURL)
+			if len(links) > 0 && links[0].URL != feedURL {
+				result, notModified, fetchErr := f.Fetch(ctx, links[0].URL, "", "")
+				if result != nil && result.Candidates == nil {
+					result.Candidates = links
+				}
+				return result, notModified, fetchErr
 			}
 		}
 		return nil, false, fmt.Errorf("parse: %w", err)
@@ -92,47 +233,56 @@ func (f *Fetcher) Fetch(ctx context.Context, feedURL string, etag string, lastMo
 		Items:        feed.Items,
 		Etag:         resp.Header.Get("ETag"),
 		LastModified: resp.Header.Get("Last-Modified"),
+		Hints:        ParsePollHints(resp.Header, feed, body, time.Now()),
+		StatusCode:   resp.StatusCode,
+		PermanentURL: permanentRedirectTarget(resp, feedURL),
+		FeedURL:      feedURL,
 	}
 	return result, false, nil
 }
 
-func discoverFeedURL(body []byte, baseURL string) string {
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
-	if err != nil {
+// permanentRedirectTarget returns the final URL of resp if every redirect hop
+// from feedURL was permanent, or "" if there were no redirects or any hop was
+// temporary.
+func permanentRedirectTarget(resp *http.Response, feedURL string) string {
+	if resp.Request == nil || resp.Request.URL == nil {
 		return ""
 	}
-	var candidate string
-	doc.Find("link").EachWithBreak(func(_ int, s *goquery.Selection) bool {
-		rel := strings.ToLower(strings.TrimSpace(s.AttrOr("rel", "")))
-		typ := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
-		href := strings.TrimSpace(s.AttrOr("href", ""))
-		if href == "" {
-			return true
-		}
-		if strings.Contains(rel, "alternate") || strings.Contains(rel, "feed") {
-			if strings.Contains(typ, "rss") || strings.Contains(typ, "atom") || strings.Contains(typ, "xml") || typ == "" {
-				candidate = ResolveRelative(baseURL, href)
-				return false
-			}
-		}
-		return true
-	})
-	if candidate != "" {
-		return candidate
-	}
-	doc.Find("a").EachWithBreak(func(_ int, s *goquery.Selection) bool {
-		href := strings.TrimSpace(s.AttrOr("href", ""))
-		if href == "" {
-			return true
+	final := resp.Request.URL.String()
+	if final == feedURL {
+		return ""
+	}
+	hops := 0
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			hops++
+		default:
+			return ""
 		}
-		lower := strings.ToLower(href)
-		if strings.Contains(lower, "rss") || strings.Contains(lower, "atom") || strings.Contains(lower, "feed") {
-			candidate = ResolveRelative(baseURL, href)
-			return false
+	}
+	if hops == 0 {
+		return ""
+	}
+	return final
+}
+
+// parseRetryAfter accepts either delay-seconds or an HTTP-date.
+func parseRetryAfter(raw string, now time.Time) time.Duration {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(raw); err == nil {
+		if secs <= 0 {
+			return 0
 		}
-		return true
-	})
-	return candidate
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(raw); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
 }
 
 func NormalizeGUID(item *gofeed.Item) string {
@@ -1,11 +1,21 @@
 package feeds
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 )
 
+const testRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title><link>https://example.com</link>
+<item><guid>1</guid><title>Hello</title></item></channel></rss>`
+
 func TestNormalizeGUID_WithGUID(t *testing.T) {
 	item := &gofeed.Item{GUID: "test-guid-123"}
 	result := NormalizeGUID(item)
@@ -64,3 +74,178 @@ func TestResolveRelative_EmptyRaw(t *testing.T) {
 		t.Errorf("Expected empty string, got '%s'", result)
 	}
 }
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved-twice", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/throttled", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<link rel="alternate" type="application/rss+xml" title="Comments" href="/comments">
+			<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed">
+		</head></html>`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetch_DiscoversFromPage(t *testing.T) {
+	srv := newTestServer(t)
+	fetcher := NewFetcher("test")
+	fetcher.SetHostLimits(0, 0)
+
+	result, _, err := fetcher.Fetch(context.Background(), srv.URL+"/page", "", "")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if result.FeedURL != srv.URL+"/feed" {
+		t.Errorf("FeedURL = %q, expected the main feed over comments", result.FeedURL)
+	}
+	if len(result.Candidates) != 2 {
+		t.Errorf("Candidates = %v, expected 2", result.Candidates)
+	}
+}
+
+func TestFetch_PermanentRedirect(t *testing.T) {
+	srv := newTestServer(t)
+	fetcher := NewFetcher("test")
+	fetcher.SetHostLimits(0, 0)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/feed", ""},
+		{"/moved", srv.URL + "/feed"},
+		{"/moved-twice", srv.URL + "/feed"},
+		{"/temporary", ""}, // a temporary hop anywhere keeps the old URL
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			result, _, err := fetcher.Fetch(context.Background(), srv.URL+tc.path, "", "")
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if result.PermanentURL != tc.expected {
+				t.Errorf("PermanentURL = %q, expected %q", result.PermanentURL, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFetch_Gone(t *testing.T) {
+	srv := newTestServer(t)
+	fetcher := NewFetcher("test")
+	fetcher.SetHostLimits(0, 0)
+
+	_, _, err := fetcher.Fetch(context.Background(), srv.URL+"/gone", "", "")
+	httpErr, ok := AsHTTPError(err)
+	if !ok || !httpErr.Gone() {
+		t.Errorf("Expected Gone HTTPError, got %v", err)
+	}
+}
+
+func TestFetch_RetryAfter(t *testing.T) {
+	srv := newTestServer(t)
+	fetcher := NewFetcher("test")
+	fetcher.SetHostLimits(0, 0)
+
+	_, _, err := fetcher.Fetch(context.Background(), srv.URL+"/throttled", "", "")
+	httpErr, ok := AsHTTPError(err)
+	if !ok || !httpErr.Throttled() {
+		t.Fatalf("Expected throttled HTTPError, got %v", err)
+	}
+	if httpErr.RetryAfter != 2*time.Minute {
+		t.Errorf("RetryAfter = %v, expected 2m", httpErr.RetryAfter)
+	}
+}
+
+func TestFetch_HostLimitPerHop(t *testing.T) {
+	var otherHits int32
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	// The same server under another host name stands in for a second host.
+	other := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "localhost") {
+			atomic.AddInt32(&otherHits, 1)
+		}
+		w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other+"/feed", http.StatusFound)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><link rel="alternate" type="application/rss+xml" href="%s/feed"></head></html>`, other)
+	})
+
+	fetcher := NewFetcher("test")
+	fetcher.SetHostLimits(1, 0)
+	for _, path := range []string{"/redirect", "/page"} {
+		t.Run(path, func(t *testing.T) {
+			release, err := fetcher.hosts.Acquire(context.Background(), "localhost")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, _, err := fetcher.Fetch(ctx, srv.URL+path, "", ""); err == nil {
+				t.Error("Expected the fetch to wait for the busy host it was sent to")
+			}
+			if n := atomic.LoadInt32(&otherHits); n != 0 {
+				t.Errorf("Busy host was requested %d times", n)
+			}
+
+			release()
+			result, _, err := fetcher.Fetch(context.Background(), srv.URL+path, "", "")
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if len(result.Items) == 0 {
+				t.Error("Expected the feed once the host was free")
+			}
+			atomic.StoreInt32(&otherHits, 0)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		raw      string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{now.Add(time.Hour).Format(http.TimeFormat), time.Hour},
+		{"garbage", 0},
+	}
+	for _, tc := range tests {
+		if result := parseRetryAfter(tc.raw, now); result != tc.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tc.raw, result, tc.expected)
+		}
+	}
+}
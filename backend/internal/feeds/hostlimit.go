@@ -0,0 +1,117 @@
+package feeds
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostSweepInterval is how often Acquire drops hosts nobody is using.
+const hostSweepInterval = time.Minute
+
+// HostLimiter caps concurrent requests to each origin host and spaces out
+// the start of consecutive requests to the same host.
+type HostLimiter struct {
+	maxConns   int
+	minGap     time.Duration
+	sweepEvery time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+	swept time.Time
+}
+
+type hostSlot struct {
+	sem   chan struct{}
+	next  time.Time // earliest start time for the next request
+	users int       // requests holding or waiting for the slot
+}
+
+// NewHostLimiter returns a limiter allowing maxConns in-flight requests per
+// host, started at least minGap apart. maxConns <= 0 disables the cap.
+func NewHostLimiter(maxConns int, minGap time.Duration) *HostLimiter {
+	return &HostLimiter{
+		maxConns:   maxConns,
+		minGap:     minGap,
+		sweepEvery: hostSweepInterval,
+		hosts:      make(map[string]*hostSlot),
+	}
+}
+
+// Acquire blocks until a request to host may start. The returned release
+// func must be called once the request (including reading the body) is done.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	l.sweep(time.Now())
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{}
+		if l.maxConns > 0 {
+			slot.sem = make(chan struct{}, l.maxConns)
+		}
+		l.hosts[host] = slot
+	}
+	slot.users++
+	l.mu.Unlock()
+
+	done := func() {
+		l.mu.Lock()
+		slot.users--
+		l.mu.Unlock()
+	}
+	if slot.sem != nil {
+		select {
+		case slot.sem <- struct{}{}:
+		case <-ctx.Done():
+			done()
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if slot.sem != nil {
+			<-slot.sem
+		}
+		done()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := slot.next
+	if start.Before(now) {
+		start = now
+	}
+	slot.next = start.Add(l.minGap)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// sweep drops hosts with no request holding or waiting for a slot whose
+// spacing has run out, at most once per sweepEvery. l.mu must be held.
+func (l *HostLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.sweepEvery {
+		return
+	}
+	l.swept = now
+	for host, slot := range l.hosts {
+		if slot.users == 0 && !slot.next.After(now) {
+			delete(l.hosts, host)
+		}
+	}
+}
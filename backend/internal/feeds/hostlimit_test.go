@@ -0,0 +1,123 @@
+package feeds
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter_CapsConcurrentRequests(t *testing.T) {
+	limiter := NewHostLimiter(2, 0)
+	var inFlight, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(context.Background(), "example.com")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestHostLimiter_SpacesRequests(t *testing.T) {
+	limiter := NewHostLimiter(0, 20*time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected requests spaced at least 40ms overall, got %v", elapsed)
+	}
+
+	// A different host is not delayed by the first one.
+	start = time.Now()
+	release, err := limiter.Acquire(context.Background(), "other.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("Expected no delay for a new host, got %v", elapsed)
+	}
+}
+
+func TestHostLimiter_Cancelled(t *testing.T) {
+	limiter := NewHostLimiter(1, 0)
+	release, err := limiter.Acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "example.com"); err == nil {
+		t.Error("Expected error when context expires while waiting")
+	}
+}
+
+func TestHostLimiter_EvictsIdleHosts(t *testing.T) {
+	limiter := NewHostLimiter(1, 0)
+	limiter.sweepEvery = 0
+	acquire := func(host string) func() {
+		t.Helper()
+		release, err := limiter.Acquire(context.Background(), host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return release
+	}
+	acquire("a.example.com")()
+	acquire("b.example.com")()
+	held := acquire("c.example.com")
+	defer held()
+	acquire("d.example.com")()
+
+	limiter.mu.Lock()
+	_, keptHeld := limiter.hosts["c.example.com"]
+	n := len(limiter.hosts)
+	limiter.mu.Unlock()
+	if n != 2 || !keptHeld {
+		t.Errorf("Expected only the held host and the latest one to remain, got %d hosts", n)
+	}
+
+	// A host still inside its spacing window is kept so the gap holds.
+	spaced := NewHostLimiter(0, time.Hour)
+	spaced.sweepEvery = 0
+	release, err := spaced.Acquire(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if release, err = spaced.Acquire(context.Background(), "b.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, ok := spaced.hosts["a.example.com"]; !ok {
+		t.Error("Expected a host inside its spacing window to be kept")
+	}
+}
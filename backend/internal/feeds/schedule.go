@@ -0,0 +1,184 @@
+package feeds
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// PollPolicy bounds the adaptive polling interval computed for each feed.
+type PollPolicy struct {
+	Default time.Duration // used when a feed gives no usable signals
+	Min     time.Duration
+	Max     time.Duration
+}
+
+func DefaultPollPolicy() PollPolicy {
+	return PollPolicy{
+		Default: time.Hour,
+		Min:     15 * time.Minute,
+		Max:     24 * time.Hour,
+	}
+}
+
+// PollHints are publisher-provided hints about how often a feed may be polled.
+// A zero value means the hint was absent.
+type PollHints struct {
+	TTL          time.Duration // RSS <ttl>
+	UpdatePeriod time.Duration // sy:updatePeriod / sy:updateFrequency
+	CacheMaxAge  time.Duration // Cache-Control max-age or Expires
+}
+
+// MaxObservedItems limits how many recent items feed into the publish-rate
+// estimate; callers need not load more publish dates than this.
+const MaxObservedItems = 20
+
+// NextPollInterval picks how long to wait before checking a feed again. The
+// observed gap between recent items is halved so a feed is checked roughly
+// twice per expected post; a feed that has gone quiet is checked less often
+// the longer it stays quiet. Publisher hints act as lower bounds, and the
+// result is clamped to the policy.
+func NextPollInterval(policy PollPolicy, hints PollHints, published []time.Time, now time.Time) time.Duration {
+	interval := policy.Default
+	if observed, ok := observedInterval(published, now); ok {
+		interval = observed
+	}
+	for _, hint := range []time.Duration{hints.TTL, hints.UpdatePeriod, hints.CacheMaxAge} {
+		if hint > interval {
+			interval = hint
+		}
+	}
+	if policy.Min > 0 && interval < policy.Min {
+		interval = policy.Min
+	}
+	if policy.Max > 0 && interval > policy.Max {
+		interval = policy.Max
+	}
+	return interval
+}
+
+// FailureBackoff returns how long to wait after the given number of
+// consecutive failures: the policy minimum, doubled per further failure and
+// capped at the policy maximum.
+func FailureBackoff(policy PollPolicy, failures int) time.Duration {
+	backoff := policy.Min
+	if backoff <= 0 {
+		backoff = policy.Default
+	}
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if policy.Max > 0 && backoff >= policy.Max {
+			return policy.Max
+		}
+	}
+	if policy.Max > 0 && backoff > policy.Max {
+		return policy.Max
+	}
+	return backoff
+}
+
+func observedInterval(published []time.Time, now time.Time) (time.Duration, bool) {
+	var times []time.Time
+	for _, t := range published {
+		if t.IsZero() || t.After(now) {
+			continue
+		}
+		times = append(times, t)
+	}
+	if len(times) == 0 {
+		return 0, false
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+	if len(times) > MaxObservedItems {
+		times = times[:MaxObservedItems]
+	}
+	sinceLatest := now.Sub(times[0])
+	if len(times) < 2 {
+		return sinceLatest / 2, true
+	}
+	avgGap := times[0].Sub(times[len(times)-1]) / time.Duration(len(times)-1)
+	interval := avgGap / 2
+	// A feed that has been silent for longer than its usual cadence is
+	// probably dormant; back off in proportion to the silence.
+	if sinceLatest > avgGap && sinceLatest/2 > interval {
+		interval = sinceLatest / 2
+	}
+	return interval, true
+}
+
+// ParsePollHints extracts polling hints from the response headers, the parsed
+// feed and, for RSS, the raw body (gofeed does not surface <ttl>).
+func ParsePollHints(header http.Header, feed *gofeed.Feed, body []byte, now time.Time) PollHints {
+	hints := PollHints{CacheMaxAge: cacheMaxAge(header, now)}
+	if feed == nil {
+		return hints
+	}
+	if feed.FeedType == "rss" && len(body) > 0 {
+		parser := &rss.Parser{}
+		if raw, err := parser.Parse(bytes.NewReader(body)); err == nil {
+			if minutes, err := strconv.Atoi(strings.TrimSpace(raw.TTL)); err == nil && minutes > 0 {
+				hints.TTL = time.Duration(minutes) * time.Minute
+			}
+		}
+	}
+	hints.UpdatePeriod = syndicationPeriod(feed)
+	return hints
+}
+
+func cacheMaxAge(header http.Header, now time.Time) time.Duration {
+	if header == nil {
+		return 0
+	}
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		if secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil && t.After(now) {
+			return t.Sub(now)
+		}
+	}
+	return 0
+}
+
+func syndicationPeriod(feed *gofeed.Feed) time.Duration {
+	sy, ok := feed.Extensions["sy"]
+	if !ok {
+		return 0
+	}
+	var period time.Duration
+	if list := sy["updatePeriod"]; len(list) > 0 {
+		switch strings.ToLower(strings.TrimSpace(list[0].Value)) {
+		case "hourly":
+			period = time.Hour
+		case "daily":
+			period = 24 * time.Hour
+		case "weekly":
+			period = 7 * 24 * time.Hour
+		case "monthly":
+			period = 30 * 24 * time.Hour
+		case "yearly":
+			period = 365 * 24 * time.Hour
+		}
+	}
+	if period == 0 {
+		return 0
+	}
+	if list := sy["updateFrequency"]; len(list) > 0 {
+		if freq, err := strconv.Atoi(strings.TrimSpace(list[0].Value)); err == nil && freq > 1 {
+			period /= time.Duration(freq)
+		}
+	}
+	return period
+}
@@ -0,0 +1,96 @@
+package feeds
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestNextPollInterval(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := PollPolicy{Default: time.Hour, Min: 15 * time.Minute, Max: 24 * time.Hour}
+	hourly := []time.Time{now.Add(-30 * time.Minute), now.Add(-90 * time.Minute), now.Add(-150 * time.Minute)}
+
+	tests := []struct {
+		name      string
+		hints     PollHints
+		published []time.Time
+		expected  time.Duration
+	}{
+		{"no signals uses default", PollHints{}, nil, time.Hour},
+		{"hourly publisher checked twice an hour", PollHints{}, hourly, 30 * time.Minute},
+		{"ttl is a lower bound", PollHints{TTL: 2 * time.Hour}, hourly, 2 * time.Hour},
+		{"cache max-age is a lower bound", PollHints{CacheMaxAge: 45 * time.Minute}, hourly, 45 * time.Minute},
+		{"clamped to min", PollHints{}, []time.Time{now.Add(-time.Minute), now.Add(-2 * time.Minute)}, 15 * time.Minute},
+		{"dormant feed clamped to max", PollHints{}, []time.Time{now.AddDate(0, -6, 0), now.AddDate(0, -7, 0)}, 24 * time.Hour},
+		{"future dates ignored", PollHints{}, []time.Time{now.Add(time.Hour)}, time.Hour},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := NextPollInterval(policy, tc.hints, tc.published, now)
+			if result != tc.expected {
+				t.Errorf("NextPollInterval() = %v, expected %v", result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	header := http.Header{}
+	header.Set("Cache-Control", "public, max-age=600")
+	if result := cacheMaxAge(header, now); result != 10*time.Minute {
+		t.Errorf("Expected 10m from max-age, got %v", result)
+	}
+
+	header = http.Header{}
+	header.Set("Expires", now.Add(time.Hour).Format(http.TimeFormat))
+	if result := cacheMaxAge(header, now); result != time.Hour {
+		t.Errorf("Expected 1h from Expires, got %v", result)
+	}
+
+	header = http.Header{}
+	header.Set("Expires", now.Add(-time.Hour).Format(http.TimeFormat))
+	if result := cacheMaxAge(header, now); result != 0 {
+		t.Errorf("Expected 0 for past Expires, got %v", result)
+	}
+}
+
+func TestSyndicationPeriod(t *testing.T) {
+	feed := &gofeed.Feed{Extensions: ext.Extensions{
+		"sy": {
+			"updatePeriod":    {{Value: "daily"}},
+			"updateFrequency": {{Value: "4"}},
+		},
+	}}
+	if result := syndicationPeriod(feed); result != 6*time.Hour {
+		t.Errorf("Expected 6h, got %v", result)
+	}
+	if result := syndicationPeriod(&gofeed.Feed{}); result != 0 {
+		t.Errorf("Expected 0 without sy extension, got %v", result)
+	}
+}
+
+func TestFailureBackoff(t *testing.T) {
+	policy := PollPolicy{Default: time.Hour, Min: 15 * time.Minute, Max: 24 * time.Hour}
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{1, 15 * time.Minute},
+		{2, 30 * time.Minute},
+		{4, 2 * time.Hour},
+		{8, 24 * time.Hour},
+		{100, 24 * time.Hour},
+	}
+	for _, tc := range tests {
+		if result := FailureBackoff(policy, tc.failures); result != tc.expected {
+			t.Errorf("FailureBackoff(%d) = %v, expected %v", tc.failures, result, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"rss-feed-manager/backend/internal/services"
+)
+
+// Account archives carry article content, so they may be far larger than
+// an OPML file.
+const maxArchiveUploadSize = 200 << 20
+
+// exportAccount downloads the user's account archive as JSON, or as a zip
+// bundle with ?format=zip.
+func (h *Handler) exportAccount(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be json or zip"))
+		return
+	}
+	archive, err := h.cfg.AccountService.Export(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("rss-account-%s", time.Now().Format("2006-01-02"))
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", filename))
+		w.WriteHeader(http.StatusOK)
+		if err := services.WriteAccountArchiveZip(w, archive); err != nil {
+			log.Printf("account export: user=%d err=%v", h.getUserID(r), err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.json\"", filename))
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(archive); err != nil {
+		log.Printf("account export: user=%d err=%v", h.getUserID(r), err)
+	}
+}
+
+// importAccount merges an uploaded archive, JSON or zip, into the account.
+func (h *Handler) importAccount(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	// Large uploads are spooled to disk by ParseMultipartForm and read from
+	// there, so the archive is never held in memory whole.
+	archive, err := services.ReadAccountArchive(file, header.Size)
+	if errors.Is(err, services.ErrArchiveTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.cfg.AccountService.Import(r.Context(), h.getUserID(r), archive)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"message": fmt.Sprintf("Imported %d folders, %d feeds and %d items", result.Folders, result.Feeds, result.Items),
+		"result":  result,
+	})
+}
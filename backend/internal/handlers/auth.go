@@ -2,17 +2,26 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
+
 	"rss-feed-manager/backend/internal/models"
 	"rss-feed-manager/backend/internal/services"
 )
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey     contextKey = "user"
+	apiTokenContextKey contextKey = "apiToken"
+)
 
 type AuthHandler struct {
 	authService *services.AuthService
@@ -140,7 +149,8 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, user)
 }
 
-// AuthMiddleware validates session and adds user to context
+// AuthMiddleware validates the session or personal API token and adds the
+// user to context. Read-only API tokens may only make GET and HEAD requests.
 func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
@@ -149,23 +159,43 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := h.authService.ValidateSession(r.Context(), token)
+		user, apiToken, err := h.authenticate(r.Context(), token)
 		if err != nil {
 			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or expired session"})
 			return
 		}
+		if apiToken != nil && apiToken.Scope == services.APITokenScopeRead &&
+			r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "api token is read-only"})
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), userContextKey, user)
+		if apiToken != nil {
+			ctx = context.WithValue(ctx, apiTokenContextKey, apiToken)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireSession rejects requests authenticated with an API token, for
+// account management that scripts should not reach.
+func (h *AuthHandler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(apiTokenContextKey) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "sign in to manage this"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // OptionalAuthMiddleware adds user to context if valid token, but doesn't require it
 func (h *AuthHandler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
 		if token != "" {
-			user, err := h.authService.ValidateSession(r.Context(), token)
+			user, _, err := h.authenticate(r.Context(), token)
 			if err == nil && user != nil {
 				ctx := context.WithValue(r.Context(), userContextKey, user)
 				r = r.WithContext(ctx)
@@ -175,6 +205,69 @@ func (h *AuthHandler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// authenticate accepts either a session token or a personal API token; the
+// API token is returned only for the latter.
+func (h *AuthHandler) authenticate(ctx context.Context, token string) (*models.User, *models.APIToken, error) {
+	if strings.HasPrefix(token, services.APITokenPrefix) {
+		user, apiToken, err := h.authService.ValidateAPIToken(ctx, token)
+		if err != nil {
+			return nil, nil, err
+		}
+		return user, &apiToken, nil
+	}
+	user, err := h.authService.ValidateSession(ctx, token)
+	return user, nil, err
+}
+
+// ListSessions handles GET /api/auth/sessions
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.authService.ListSessions(r.Context(), UserFromContext(r.Context()).ID, extractToken(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /api/auth/sessions/{id}
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	err := h.authService.RevokeSession(r.Context(), UserFromContext(r.Context()).ID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, errors.New("session not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions handles DELETE /api/auth/sessions, signing out
+// everywhere but the current session.
+func (h *AuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	n, err := h.authService.RevokeOtherSessions(r.Context(), UserFromContext(r.Context()).ID, extractToken(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"revoked": n})
+}
+
+// clientInfo records the request's IP and user agent for sessions created or
+// used while handling it. It relies on middleware.RealIP for RemoteAddr.
+func clientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		ctx := services.WithClientInfo(r.Context(), services.ClientInfo{IP: ip, UserAgent: r.UserAgent()})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // UserFromContext extracts user from context
 func UserFromContext(ctx context.Context) *models.User {
 	user, ok := ctx.Value(userContextKey).(*models.User)
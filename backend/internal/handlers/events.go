@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+// eventHeartbeat keeps idle event streams from being closed by proxies.
+const eventHeartbeat = 25 * time.Second
+
+// events streams the user's events as Server-Sent Events until the client
+// disconnects.
+func (h *Handler) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || h.cfg.Events == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("event stream not supported"))
+		return
+	}
+	events, unsubscribe := h.cfg.Events.Subscribe(h.getUserID(r))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// eventTicket issues a single-use ticket for opening the event stream, so
+// EventSource never has to put a session or API token in its URL, where
+// access logs and proxies would record it.
+func (h *Handler) eventTicket(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.Events == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("event stream not supported"))
+		return
+	}
+	ticket, err := h.cfg.Events.IssueTicket(h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"ticket": ticket})
+}
+
+// streamTicketAuth authenticates the event stream by its ?ticket=, which
+// EventSource can send; requests without one go through auth, so scripts
+// can still use the Authorization header.
+func (h *Handler) streamTicketAuth(auth func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authed := auth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ticket := r.URL.Query().Get("ticket")
+			if ticket == "" {
+				authed.ServeHTTP(w, r)
+				return
+			}
+			userID, ok := h.cfg.Events.RedeemTicket(ticket)
+			if !ok {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or expired ticket"})
+				return
+			}
+			ctx := context.WithValue(r.Context(), userContextKey, &models.User{ID: userID})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
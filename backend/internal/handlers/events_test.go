@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestEventStreamTicket checks that the event stream opens with a ticket,
+// once, and no longer takes a token in the URL.
+func TestEventStreamTicket(t *testing.T) {
+	s := newTestServer(t)
+	rec := s.do(http.MethodPost, "/api/events/ticket", s.session, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ticket: status %d", rec.Code)
+	}
+	var body struct{ Ticket string }
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream runs until the client goes away; a cancelled request
+	// returns as soon as the stream has opened.
+	open := func(query string) int {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodGet, "/api/events?"+query, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := open("access_token=" + s.session); code != http.StatusUnauthorized {
+		t.Errorf("stream with access_token: status %d, want 401", code)
+	}
+	if code := open("ticket=" + body.Ticket); code != http.StatusOK {
+		t.Errorf("stream with ticket: status %d, want 200", code)
+	}
+	if code := open("ticket=" + body.Ticket); code != http.StatusUnauthorized {
+		t.Errorf("stream with a used ticket: status %d, want 401", code)
+	}
+	if rec := s.do(http.MethodPost, "/api/events/ticket", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ticket without auth: status %d, want 401", rec.Code)
+	}
+}
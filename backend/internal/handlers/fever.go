@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/services"
+)
+
+// feverAPIVersion is the Fever API version clients are told we speak.
+const feverAPIVersion = 3
+
+// fever serves the Fever API. Every request carries api_key as a form field;
+// the query string names what to return (groups, feeds, items, ...) and
+// mark/as/id change item state. Failures are reported the Fever way, with
+// auth 0 or an error field in a 200 response, since clients do not look at
+// status codes.
+func (h *Handler) fever(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]interface{}{"api_version": feverAPIVersion, "auth": 0}
+	if !r.URL.Query().Has("api") {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	ctx := r.Context()
+	svc := h.cfg.FeverService
+	userID, err := svc.UserForAPIKey(ctx, r.FormValue("api_key"))
+	if err != nil {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	resp["auth"] = 1
+
+	has := func(key string) bool {
+		_, ok := r.Form[key]
+		return ok
+	}
+	fail := func(err error) {
+		resp["error"] = err.Error()
+		writeJSON(w, http.StatusOK, resp)
+	}
+
+	if mark := r.FormValue("mark"); mark != "" {
+		id, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		as := r.FormValue("as")
+		before := time.Now()
+		if unix, err := strconv.ParseInt(r.FormValue("before"), 10, 64); err == nil && unix > 0 {
+			before = time.Unix(unix, 0)
+		}
+		switch {
+		case mark == "item":
+			err = svc.MarkItem(ctx, userID, id, as)
+		case mark == "feed" && as == "read":
+			err = svc.MarkFeedRead(ctx, userID, id, before)
+		case mark == "group" && as == "read":
+			err = svc.MarkGroupRead(ctx, userID, id, before)
+		default:
+			err = services.ErrInvalidFeverRequest
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fail(err)
+			return
+		}
+		if as == "saved" || as == "unsaved" {
+			r.Form.Set("saved_item_ids", "")
+		} else {
+			r.Form.Set("unread_item_ids", "")
+		}
+	}
+
+	if resp["last_refreshed_on_time"], err = svc.LastRefreshed(ctx, userID); err != nil {
+		fail(err)
+		return
+	}
+	if has("groups") {
+		if resp["groups"], err = svc.Groups(ctx, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+	if has("feeds") {
+		if resp["feeds"], err = svc.Feeds(ctx, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+	if has("groups") || has("feeds") {
+		if resp["feeds_groups"], err = svc.FeedsGroups(ctx, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+	if has("favicons") {
+		resp["favicons"] = []struct{}{}
+	}
+	if has("links") {
+		resp["links"] = []struct{}{}
+	}
+	if has("items") {
+		q := services.FeverItemsQuery{}
+		q.SinceID, _ = strconv.ParseInt(r.FormValue("since_id"), 10, 64)
+		q.MaxID, _ = strconv.ParseInt(r.FormValue("max_id"), 10, 64)
+		for _, raw := range strings.Split(r.FormValue("with_ids"), ",") {
+			if id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64); err == nil {
+				q.WithIDs = append(q.WithIDs, id)
+			}
+		}
+		items, total, err := svc.Items(ctx, userID, q)
+		if err != nil {
+			fail(err)
+			return
+		}
+		resp["items"] = items
+		resp["total_items"] = total
+	}
+	if has("unread_item_ids") {
+		if resp["unread_item_ids"], err = svc.UnreadItemIDs(ctx, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+	if has("saved_item_ids") {
+		if resp["saved_item_ids"], err = svc.SavedItemIDs(ctx, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) getFeverSettings(w http.ResponseWriter, r *http.Request) {
+	enabled, err := h.cfg.FeverService.Enabled(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": enabled})
+}
+
+func (h *Handler) setFeverPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err := h.cfg.FeverService.SetPassword(r.Context(), h.getUserID(r), req.Password)
+	if errors.Is(err, services.ErrInvalidFeverRequest) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": true})
+}
+
+func (h *Handler) disableFever(w http.ResponseWriter, r *http.Request) {
+	if err := h.cfg.FeverService.Disable(r.Context(), h.getUserID(r)); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
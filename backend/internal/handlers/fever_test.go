@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"rss-feed-manager/backend/internal/models"
+	"rss-feed-manager/backend/internal/services"
+)
+
+// feverResponse is the part of a Fever response the tests look at.
+type feverResponse struct {
+	Auth          int                `json:"auth"`
+	Error         string             `json:"error"`
+	Items         []models.FeverItem `json:"items"`
+	TotalItems    int                `json:"total_items"`
+	UnreadItemIDs string             `json:"unread_item_ids"`
+	SavedItemIDs  string             `json:"saved_item_ids"`
+}
+
+// fever posts a Fever API call with the given query and form values.
+func (s *testServer) fever(query string, values url.Values) feverResponse {
+	s.t.Helper()
+	rec := s.form(http.MethodPost, "/api/fever/?api&"+query, "", values)
+	if rec.Code != http.StatusOK {
+		s.t.Fatalf("fever %s: status %d", query, rec.Code)
+	}
+	var resp feverResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		s.t.Fatal(err)
+	}
+	return resp
+}
+
+// TestFeverAuth checks that the API key is the MD5 of email and Fever
+// password, and stops working when the password changes or is removed.
+func TestFeverAuth(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	apiKey := url.Values{"api_key": {services.FeverAPIKey("reader@example.com", "correct horse")}}
+
+	if resp := s.fever("", apiKey); resp.Auth != 0 {
+		t.Errorf("auth before a password is set = %d, want 0", resp.Auth)
+	}
+	if err := s.cfg.FeverService.SetPassword(ctx, s.userID, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if resp := s.fever("", apiKey); resp.Auth != 1 {
+		t.Errorf("auth with the API key = %d, want 1", resp.Auth)
+	}
+	wrong := url.Values{"api_key": {services.FeverAPIKey("reader@example.com", "wrong")}}
+	if resp := s.fever("", wrong); resp.Auth != 0 {
+		t.Errorf("auth with a wrong API key = %d, want 0", resp.Auth)
+	}
+	if rec := s.do(http.MethodPut, "/api/settings/fever", s.session, `{"password":"short"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("short Fever password: status %d, want 400", rec.Code)
+	}
+
+	if rec := s.do(http.MethodDelete, "/api/settings/fever", s.session, ""); rec.Code >= 400 {
+		t.Fatalf("disable Fever: status %d", rec.Code)
+	}
+	if resp := s.fever("", apiKey); resp.Auth != 0 {
+		t.Errorf("auth after disabling = %d, want 0", resp.Auth)
+	}
+}
+
+// TestFeverItems checks item listing and that mark calls change state and
+// return the updated id lists.
+func TestFeverItems(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.cfg.FeverService.SetPassword(context.Background(), s.userID, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	apiKey := url.Values{"api_key": {services.FeverAPIKey("reader@example.com", "correct horse")}}
+	ids := s.entries(3)
+
+	resp := s.fever("items&unread_item_ids", apiKey)
+	if resp.Auth != 1 || resp.Error != "" {
+		t.Fatalf("items: auth=%d error=%q", resp.Auth, resp.Error)
+	}
+	if len(resp.Items) != 3 || resp.TotalItems != 3 {
+		t.Fatalf("items: got %d of %d, want 3 of 3", len(resp.Items), resp.TotalItems)
+	}
+	if resp.UnreadItemIDs == "" {
+		t.Error("unread_item_ids is empty before anything was read")
+	}
+
+	resp = s.fever("items&with_ids="+strconv.FormatInt(ids[1], 10), apiKey)
+	if len(resp.Items) != 1 || resp.Items[0].ID != ids[1] {
+		t.Errorf("with_ids: got %+v, want only item %d", resp.Items, ids[1])
+	}
+
+	markRead := url.Values{"api_key": apiKey["api_key"], "mark": {"item"}, "as": {"read"}, "id": {strconv.FormatInt(ids[0], 10)}}
+	if resp := s.fever("", markRead); resp.Error != "" {
+		t.Fatalf("mark read: %s", resp.Error)
+	}
+	markSaved := url.Values{"api_key": apiKey["api_key"], "mark": {"item"}, "as": {"saved"}, "id": {strconv.FormatInt(ids[2], 10)}}
+	resp = s.fever("", markSaved)
+	if resp.Error != "" {
+		t.Fatalf("mark saved: %s", resp.Error)
+	}
+	if want := strconv.FormatInt(ids[2], 10); resp.SavedItemIDs != want {
+		t.Errorf("saved_item_ids after saving = %q, want %q", resp.SavedItemIDs, want)
+	}
+
+	resp = s.fever("items&with_ids="+strconv.FormatInt(ids[0], 10)+","+strconv.FormatInt(ids[2], 10), apiKey)
+	for _, it := range resp.Items {
+		switch it.ID {
+		case ids[0]:
+			if it.IsRead != 1 || it.IsSaved != 0 {
+				t.Errorf("item %d: is_read=%d is_saved=%d, want read", it.ID, it.IsRead, it.IsSaved)
+			}
+		case ids[2]:
+			if it.IsRead != 0 || it.IsSaved != 1 {
+				t.Errorf("item %d: is_read=%d is_saved=%d, want saved", it.ID, it.IsRead, it.IsSaved)
+			}
+		}
+	}
+
+	bad := url.Values{"api_key": apiKey["api_key"], "mark": {"item"}, "as": {"bogus"}, "id": {strconv.FormatInt(ids[1], 10)}}
+	if resp := s.fever("", bad); resp.Error == "" {
+		t.Error("mark with an unknown state returned no error")
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+type filterRuleRequest struct {
+	Name      string `json:"name"`
+	Field     string `json:"field"`
+	MatchType string `json:"matchType"`
+	Pattern   string `json:"pattern"`
+	Action    string `json:"action"`
+	Tag       string `json:"tag"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+func (req filterRuleRequest) model() models.FilterRule {
+	rule := models.FilterRule{
+		Name:      req.Name,
+		Field:     req.Field,
+		MatchType: req.MatchType,
+		Pattern:   req.Pattern,
+		Action:    req.Action,
+		Tag:       req.Tag,
+		Enabled:   true,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return rule
+}
+
+func (h *Handler) listFilterRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.cfg.FeedService.ListFilterRules(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"filters": rules})
+}
+
+func (h *Handler) createFilterRule(w http.ResponseWriter, r *http.Request) {
+	var req filterRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rule, err := h.cfg.FeedService.CreateFilterRule(r.Context(), h.getUserID(r), req.model())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (h *Handler) updateFilterRule(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	var req filterRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rule := req.model()
+	rule.ID = id
+	if err := h.cfg.FeedService.UpdateFilterRule(r.Context(), h.getUserID(r), rule); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteFilterRule(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err := h.cfg.FeedService.DeleteFilterRule(r.Context(), h.getUserID(r), id); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dryRunFilterRule shows which stored items the rule in the body would act
+// on; nothing is saved or changed.
+func (h *Handler) dryRunFilterRule(w http.ResponseWriter, r *http.Request) {
+	var req filterRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := parseIntDefault(r.URL.Query().Get("limit"), defaultLimit)
+	items, total, err := h.cfg.FeedService.DryRunFilterRule(r.Context(), h.getUserID(r), req.model(), limit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "total": total})
+}
+
+func (h *Handler) listHighlights(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := parseIntDefault(q.Get("limit"), defaultLimit)
+	sort := parseSortPref(q.Get("sort"))
+	cursor := parseItemCursor(q.Get("cursor"))
+	items, next, err := h.cfg.FeedService.ListHighlights(r.Context(), h.getUserID(r), limit, cursor, sort)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	resp := map[string]interface{}{"items": items}
+	if next != nil {
+		resp["nextCursor"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
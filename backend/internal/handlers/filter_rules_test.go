@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+// TestFilterDryRun checks that a dry run reports matching items and the
+// total without saving the rule or touching item state.
+func TestFilterDryRun(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.entries(3)
+
+	rec := s.do(http.MethodPost, "/api/filters/dry-run?limit=2", s.session,
+		`{"name":"all","field":"title","pattern":"entry","action":"mark_read"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dry run: status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Items []models.Item `json:"items"`
+		Total int           `json:"total"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 3 || len(resp.Items) != 2 || resp.Items[0].ID != ids[0] {
+		t.Errorf("dry run: %d items of %d, first %v; want 2 of 3 starting with %d",
+			len(resp.Items), resp.Total, resp.Items, ids[0])
+	}
+
+	var rules, states int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM filter_rules`).Scan(&rules); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM item_state WHERE is_read=1`).Scan(&states); err != nil {
+		t.Fatal(err)
+	}
+	if rules != 0 || states != 0 {
+		t.Errorf("after dry run: %d rules saved, %d items read; want none", rules, states)
+	}
+
+	rec = s.do(http.MethodPost, "/api/filters/dry-run", s.session, `{"name":"x","pattern":"x","action":"delete"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("dry run with an unknown action: status %d, want 400", rec.Code)
+	}
+}
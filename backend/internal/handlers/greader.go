@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"rss-feed-manager/backend/internal/services"
+)
+
+type greaderTokenKey struct{}
+
+// greaderAuth authenticates Google Reader requests by the session token from
+// ClientLogin, sent as "Authorization: GoogleLogin auth=<token>". Those
+// sessions are accepted nowhere else.
+func (h *Handler) greaderAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := h.cfg.AuthService.ValidateAppSession(r.Context(), token)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		ctx = context.WithValue(ctx, greaderTokenKey{}, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// greaderEditToken checks the T parameter that write requests must carry,
+// which is the token returned by /token.
+func (h *Handler) greaderEditToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("T") != greaderToken(r) {
+			w.Header().Set("X-Reader-Google-Bad-Token", "true")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// greaderToken derives the edit token from the session token, so it needs
+// no storage of its own.
+func greaderToken(r *http.Request) string {
+	session, _ := r.Context().Value(greaderTokenKey{}).(string)
+	sum := sha256.Sum256([]byte("greader-edit:" + session))
+	return hex.EncodeToString(sum[:])
+}
+
+// greaderClientLogin only reads credentials from the POST body, so they never
+// end up in URLs and access logs.
+func (h *Handler) greaderClientLogin(w http.ResponseWriter, r *http.Request) {
+	_, token, err := h.cfg.AuthService.ClientLogin(r.Context(), r.PostFormValue("Email"), r.PostFormValue("Passwd"))
+	if err != nil {
+		status := http.StatusUnauthorized
+		if !errors.Is(err, services.ErrBadCredentials) && !errors.Is(err, services.ErrTooManyAttempts) {
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, "Error=BadAuthentication", status)
+		return
+	}
+	if r.FormValue("output") == "json" {
+		writeJSON(w, http.StatusOK, map[string]string{"SID": token, "LSID": token, "Auth": token})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+func (h *Handler) greaderTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, greaderToken(r))
+}
+
+func (h *Handler) greaderUserInfo(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	id := strconv.FormatInt(user.ID, 10)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        id,
+		"userName":      user.Email,
+		"userProfileId": id,
+		"userEmail":     user.Email,
+	})
+}
+
+func (h *Handler) greaderSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.cfg.GReaderService.Subscriptions(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"subscriptions": subs})
+}
+
+func (h *Handler) greaderTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.cfg.GReaderService.Tags(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tags": tags})
+}
+
+func (h *Handler) greaderUnreadCount(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.cfg.GReaderService.UnreadCounts(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	maxCount := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c.Count)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"max": maxCount, "unreadcounts": counts})
+}
+
+// greaderSubscriptionEdit handles subscribe, unsubscribe and edit (rename,
+// or move to the folder named by the a label) for each s.
+func (h *Handler) greaderSubscriptionEdit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserID(r)
+	svc := h.cfg.GReaderService
+	title, label := r.FormValue("t"), r.FormValue("a")
+	for _, stream := range r.Form["s"] {
+		var err error
+		switch r.FormValue("ac") {
+		case "subscribe":
+			_, err = svc.Subscribe(ctx, userID, strings.TrimPrefix(stream, services.GReaderFeedPrefix), title, label)
+		case "unsubscribe":
+			err = svc.Unsubscribe(ctx, userID, stream)
+		case "edit":
+			err = svc.EditSubscription(ctx, userID, stream, title, label)
+		default:
+			err = fmt.Errorf("%w: unknown action %q", services.ErrInvalidGReaderRequest, r.FormValue("ac"))
+		}
+		if err != nil {
+			writeGReaderError(w, err)
+			return
+		}
+	}
+	writeGReaderOK(w)
+}
+
+func (h *Handler) greaderQuickAdd(w http.ResponseWriter, r *http.Request) {
+	feedURL := strings.TrimPrefix(r.FormValue("quickadd"), services.GReaderFeedPrefix)
+	feed, err := h.cfg.GReaderService.Subscribe(r.Context(), h.getUserID(r), feedURL, "", "")
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"numResults": 1,
+		"query":      feed.URL,
+		"streamId":   services.GReaderFeedPrefix + strconv.FormatInt(feed.ID, 10),
+		"streamName": feed.Title,
+	})
+}
+
+func (h *Handler) greaderStreamItemIDs(w http.ResponseWriter, r *http.Request) {
+	refs, next, err := h.cfg.GReaderService.StreamItemIDs(r.Context(), h.getUserID(r), greaderQuery(r, r.FormValue("s")))
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	resp := map[string]interface{}{"itemRefs": refs}
+	if next != nil {
+		resp["continuation"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// greaderStreamContents serves /stream/contents/<stream ID>, where the
+// stream ID may itself contain slashes, or takes it from s.
+func (h *Handler) greaderStreamContents(w http.ResponseWriter, r *http.Request) {
+	stream, err := url.PathUnescape(chi.URLParam(r, "*"))
+	if err != nil || stream == "" {
+		stream = r.FormValue("s")
+	}
+	if stream == "" {
+		stream = services.GReaderReadingList
+	}
+	items, next, err := h.cfg.GReaderService.StreamItems(r.Context(), h.getUserID(r), greaderQuery(r, stream))
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	resp := map[string]interface{}{
+		"id":      stream,
+		"updated": time.Now().Unix(),
+		"items":   items,
+	}
+	if next != nil {
+		resp["continuation"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) greaderItemContents(w http.ResponseWriter, r *http.Request) {
+	ids, err := greaderItemIDs(r)
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	items, err := h.cfg.GReaderService.Items(r.Context(), h.getUserID(r), ids)
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      services.GReaderReadingList,
+		"updated": time.Now().Unix(),
+		"items":   items,
+	})
+}
+
+func (h *Handler) greaderEditTag(w http.ResponseWriter, r *http.Request) {
+	ids, err := greaderItemIDs(r)
+	if err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	r.ParseForm()
+	if err := h.cfg.GReaderService.EditTags(r.Context(), h.getUserID(r), ids, r.Form["a"], r.Form["r"]); err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	writeGReaderOK(w)
+}
+
+func (h *Handler) greaderMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	var before *time.Time
+	if raw := r.FormValue("ts"); raw != "" {
+		usec, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			writeGReaderError(w, fmt.Errorf("%w: bad ts", services.ErrInvalidGReaderRequest))
+			return
+		}
+		t := time.UnixMicro(usec)
+		before = &t
+	}
+	if err := h.cfg.GReaderService.MarkAllRead(r.Context(), h.getUserID(r), r.FormValue("s"), before); err != nil {
+		writeGReaderError(w, err)
+		return
+	}
+	writeGReaderOK(w)
+}
+
+// greaderQuery reads the stream parameters shared by item listings: n, r
+// ("o" for oldest first), xt, it, ot and nt (Unix seconds) and c.
+func greaderQuery(r *http.Request, stream string) services.GReaderQuery {
+	r.ParseForm()
+	q := services.GReaderQuery{
+		Stream:      stream,
+		Exclude:     r.Form["xt"],
+		Include:     r.Form["it"],
+		OldestFirst: r.FormValue("r") == "o",
+		Limit:       parseIntDefault(r.FormValue("n"), 0),
+		Cursor:      parseItemCursor(r.FormValue("c")),
+	}
+	if ot, err := strconv.ParseInt(r.FormValue("ot"), 10, 64); err == nil && ot > 0 {
+		t := time.Unix(ot, 0)
+		q.Since = &t
+	}
+	if nt, err := strconv.ParseInt(r.FormValue("nt"), 10, 64); err == nil && nt > 0 {
+		t := time.Unix(nt, 0)
+		q.Until = &t
+	}
+	return q
+}
+
+// greaderItemIDs parses the repeated i parameter.
+func greaderItemIDs(r *http.Request) ([]int64, error) {
+	r.ParseForm()
+	ids := make([]int64, 0, len(r.Form["i"]))
+	for _, raw := range r.Form["i"] {
+		id, err := services.ParseGReaderItemID(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad item id %q", services.ErrInvalidGReaderRequest, raw)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func writeGReaderOK(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
+
+func writeGReaderError(w http.ResponseWriter, err error) {
+	var ambiguous *services.AmbiguousFeedError
+	switch {
+	case errors.Is(err, services.ErrInvalidGReaderRequest), errors.As(err, &ambiguous):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
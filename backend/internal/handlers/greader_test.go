@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/services"
+)
+
+// form sends a form-encoded request through the router, authenticated with
+// a ClientLogin token when auth is set.
+func (s *testServer) form(method, path, auth string, values url.Values) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if auth != "" {
+		req.Header.Set("Authorization", "GoogleLogin auth="+auth)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// entries subscribes the user to a new feed with n entries, stored the way
+// ingest stores them, and returns their ids.
+func (s *testServer) entries(n int) []int64 {
+	s.t.Helper()
+	var folderID, sourceID int64
+	if err := s.db.QueryRow(`INSERT INTO folders(user_id, name) VALUES(?, 'News') RETURNING id`, s.userID).Scan(&folderID); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.db.QueryRow(`INSERT INTO sources(url, title) VALUES('https://example.com/feed.xml', 'Example') RETURNING id`).Scan(&sourceID); err != nil {
+		s.t.Fatal(err)
+	}
+	if _, err := s.db.Exec(`INSERT INTO feeds(user_id, folder_id, source_id) VALUES(?, ?, ?)`, s.userID, folderID, sourceID); err != nil {
+		s.t.Fatal(err)
+	}
+	var ids []int64
+	for i := 0; i < n; i++ {
+		var id int64
+		guid := "guid-" + string(rune('a'+i))
+		if err := s.db.QueryRow(`
+			INSERT INTO entries(source_id, guid, link, title, author, published_at, summary_text, content_html, media_json)
+			VALUES(?, ?, ?, 'Entry', '', ?, '', '', '[]') RETURNING id`,
+			sourceID, guid, "https://example.com/"+guid, time.Now().Add(-time.Duration(i)*time.Hour)).Scan(&id); err != nil {
+			s.t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// clientLogin signs in through ClientLogin and returns the Auth token.
+func (s *testServer) clientLogin(password string) string {
+	s.t.Helper()
+	rec := s.form(http.MethodPost, "/api/greader/accounts/ClientLogin", "",
+		url.Values{"Email": {"reader@example.com"}, "Passwd": {password}})
+	if rec.Code != http.StatusOK {
+		s.t.Fatalf("ClientLogin: status %d: %s", rec.Code, rec.Body)
+	}
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if token, ok := strings.CutPrefix(line, "Auth="); ok {
+			return token
+		}
+	}
+	s.t.Fatalf("ClientLogin: no Auth line in %q", rec.Body)
+	return ""
+}
+
+// TestGReaderClientLogin checks that ClientLogin takes the Fever password
+// from a POST body only, and that its token authenticates the API.
+func TestGReaderClientLogin(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.cfg.FeverService.SetPassword(context.Background(), s.userID, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	const login = "/api/greader/accounts/ClientLogin"
+
+	query := url.Values{"Email": {"reader@example.com"}, "Passwd": {"correct horse"}}.Encode()
+	if rec := s.form(http.MethodGet, login+"?"+query, "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET ClientLogin: status %d, want 405", rec.Code)
+	}
+	if rec := s.form(http.MethodPost, login+"?"+query, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ClientLogin with credentials in the query: status %d, want 401", rec.Code)
+	}
+	if rec := s.form(http.MethodPost, login, "", url.Values{"Email": {"reader@example.com"}, "Passwd": {"wrong"}}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ClientLogin with a wrong password: status %d, want 401", rec.Code)
+	}
+
+	auth := s.clientLogin("correct horse")
+	if rec := s.form(http.MethodGet, "/api/greader/reader/api/0/user-info", auth, nil); rec.Code != http.StatusOK {
+		t.Errorf("user-info with Auth token: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.form(http.MethodGet, "/api/greader/reader/api/0/user-info", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("user-info without Auth token: status %d, want 401", rec.Code)
+	}
+	if rec := s.form(http.MethodGet, "/api/greader/reader/api/0/user-info", s.session, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("user-info with a web session: status %d, want 401", rec.Code)
+	}
+}
+
+// TestGReaderSessionScope checks that a ClientLogin token, signed in with
+// the app password, cannot be used on the rest of the API.
+func TestGReaderSessionScope(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.cfg.FeverService.SetPassword(context.Background(), s.userID, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	auth := s.clientLogin("correct horse")
+
+	for _, route := range []struct{ method, path, body string }{
+		{http.MethodGet, "/api/folders", ""},
+		{http.MethodGet, "/api/tokens", ""},
+		{http.MethodPost, "/api/tokens", `{"name":"script","scope":"read_write"}`},
+		{http.MethodGet, "/api/account/export", ""},
+		{http.MethodGet, "/api/settings/fever", ""},
+		{http.MethodGet, "/api/settings/publish-token", ""},
+	} {
+		if rec := s.do(route.method, route.path, auth, route.body); rec.Code != http.StatusUnauthorized && rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s with a ClientLogin token: status %d, want 401 or 403", route.method, route.path, rec.Code)
+		}
+	}
+}
+
+// TestGReaderEditTag checks that edit-tag needs the edit token and applies
+// state and labels to every item in one change.
+func TestGReaderEditTag(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	if err := s.cfg.FeverService.SetPassword(ctx, s.userID, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	ids := s.entries(3)
+	auth := s.clientLogin("correct horse")
+
+	rec := s.form(http.MethodGet, "/api/greader/reader/api/0/token", auth, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token: status %d", rec.Code)
+	}
+	editToken := strings.TrimSpace(rec.Body.String())
+
+	events, unsubscribe := s.cfg.Events.Subscribe(s.userID)
+	defer unsubscribe()
+
+	edit := url.Values{
+		"i": {services.GReaderItemID(ids[0]), services.GReaderItemID(ids[1]), "999999"},
+		"a": {services.GReaderRead, services.GReaderStarred, services.GReaderLabelPrefix + "Go"},
+	}
+	if rec := s.form(http.MethodPost, "/api/greader/reader/api/0/edit-tag", auth, edit); rec.Code != http.StatusUnauthorized {
+		t.Errorf("edit-tag without T: status %d, want 401", rec.Code)
+	}
+	edit.Set("T", editToken)
+	if rec := s.form(http.MethodPost, "/api/greader/reader/api/0/edit-tag", auth, edit); rec.Code != http.StatusOK {
+		t.Fatalf("edit-tag: status %d: %s", rec.Code, rec.Body)
+	}
+
+	for i, id := range ids {
+		var read, starred, tags int
+		if err := s.db.QueryRow(`SELECT COALESCE(MAX(is_read), 0), COALESCE(MAX(is_bookmarked), 0) FROM item_state WHERE user_id=? AND item_id=?`,
+			s.userID, id).Scan(&read, &starred); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM item_tags WHERE user_id=? AND item_id=? AND tag='Go'`, s.userID, id).Scan(&tags); err != nil {
+			t.Fatal(err)
+		}
+		want := 0
+		if i < 2 {
+			want = 1
+		}
+		if read != want || starred != want || tags != want {
+			t.Errorf("item %d: read=%d starred=%d tagged=%d, want %d", id, read, starred, tags, want)
+		}
+	}
+
+	select {
+	case ev := <-events:
+		data, _ := ev.Data.(map[string]interface{})
+		if ev.Type != services.EventItemState || len(data["itemIds"].([]int64)) != 2 {
+			t.Errorf("event = %+v, want one item.state for both items", ev)
+		}
+	default:
+		t.Fatal("edit-tag published no event")
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("edit-tag published a second event %+v", ev)
+	default:
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"rss-feed-manager/backend/internal/models"
+	"rss-feed-manager/backend/internal/services"
+)
+
+// publishAuth authenticates published-feed requests by the token in the URL
+// and exposes its owner like AuthMiddleware does for sessions.
+func (h *Handler) publishAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := h.cfg.PublishService.UserForToken(r.Context(), chi.URLParam(r, "token"))
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid publish token"})
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey, &models.User{ID: userID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) publishFolder(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	stream, err := h.cfg.PublishService.FolderStream(r.Context(), h.getUserID(r), id)
+	h.writeStream(w, r, stream, err)
+}
+
+func (h *Handler) publishFeed(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	stream, err := h.cfg.PublishService.FeedStream(r.Context(), h.getUserID(r), id)
+	h.writeStream(w, r, stream, err)
+}
+
+func (h *Handler) publishSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	stream, err := h.cfg.PublishService.SavedSearchStream(r.Context(), h.getUserID(r), id)
+	h.writeStream(w, r, stream, err)
+}
+
+func (h *Handler) publishTag(w http.ResponseWriter, r *http.Request) {
+	stream, err := h.cfg.PublishService.TagStream(r.Context(), h.getUserID(r), chi.URLParam(r, "tag"))
+	h.writeStream(w, r, stream, err)
+}
+
+func (h *Handler) publishBookmarks(w http.ResponseWriter, r *http.Request) {
+	stream, err := h.cfg.PublishService.BookmarksStream(r.Context(), h.getUserID(r))
+	h.writeStream(w, r, stream, err)
+}
+
+func (h *Handler) publishTopNews(w http.ResponseWriter, r *http.Request) {
+	stream, err := h.cfg.PublishService.TopNewsStream(r.Context(), h.getUserID(r))
+	h.writeStream(w, r, stream, err)
+}
+
+// writeStream renders the stream in the format named by the URL extension.
+func (h *Handler) writeStream(w http.ResponseWriter, r *http.Request, stream services.Stream, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if errors.Is(err, services.ErrSearchUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var (
+		data        []byte
+		contentType string
+	)
+	selfURL := requestURL(r)
+	switch chi.URLParam(r, "format") {
+	case "json":
+		data, err = services.RenderJSONFeed(stream, selfURL)
+		contentType = "application/feed+json; charset=utf-8"
+	case "atom", "xml":
+		data, err = services.RenderAtom(stream, selfURL)
+		contentType = "application/atom+xml; charset=utf-8"
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown format, use .json or .atom"))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (h *Handler) getPublishToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.cfg.PublishService.Token(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (h *Handler) rotatePublishToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.cfg.PublishService.RotateToken(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// requestURL reconstructs the absolute URL the client requested, honouring
+// the proxy's X-Forwarded-Proto.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
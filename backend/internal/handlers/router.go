@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"net/http"
 	"net/url"
 	"os"
@@ -27,6 +30,11 @@ type Config struct {
 	SummaryService      *services.SummaryService
 	AuthService         *services.AuthService
 	OPMLService         *services.OPMLService
+	AccountService      *services.AccountService
+	PublishService      *services.PublishService
+	FeverService        *services.FeverService
+	GReaderService      *services.GReaderService
+	Events              *services.EventBus
 	Reader              *reader.Client
 	FrontendOrigin      string
 	ReaderRatePerMinute int
@@ -38,6 +46,7 @@ type Handler struct {
 
 const defaultLimit = 20
 const defaultFrontendOrigin = "http://localhost:5173"
+const discoverTimeout = 30 * time.Second
 
 func NewRouter(cfg Config) http.Handler {
 	h := &Handler{cfg: cfg}
@@ -45,6 +54,7 @@ func NewRouter(cfg Config) http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(clientInfo)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	allowedOrigins := parseAllowedOrigins(cfg.FrontendOrigin)
@@ -67,12 +77,58 @@ func NewRouter(cfg Config) http.Handler {
 			r.Post("/import", h.importOPML)
 			r.Get("/export", h.exportOPML)
 		})
+		r.Route("/api/account", func(r chi.Router) {
+			// An archive is the whole account; API tokens cannot take it out
+			// or merge into it.
+			r.Use(authHandler.RequireSession)
+			r.Get("/export", h.exportAccount)
+			r.Post("/import", h.importAccount)
+		})
 	})
 
 	// Discover is public
-	r.Route("/api/discover", func(r chi.Router) {
-		r.Get("/", h.discover)
-		r.Post("/resolve", h.discoverResolve)
+	r.Get("/api/discover", h.discover)
+
+	// Published feeds authenticate with a token in the path so that other
+	// feed readers can subscribe to them.
+	r.Route("/api/publish/{token}", func(r chi.Router) {
+		r.Use(h.publishAuth)
+		r.Get("/folders/{id}.{format}", h.publishFolder)
+		r.Get("/feeds/{id}.{format}", h.publishFeed)
+		r.Get("/saved-searches/{id}.{format}", h.publishSavedSearch)
+		r.Get("/tags/{tag}.{format}", h.publishTag)
+		r.Get("/bookmarks.{format}", h.publishBookmarks)
+		r.Get("/top-news.{format}", h.publishTopNews)
+	})
+
+	// Fever API for mobile clients; authenticates with its own api_key.
+	r.HandleFunc("/api/fever", h.fever)
+	r.HandleFunc("/api/fever/", h.fever)
+
+	// Google Reader API for desktop and mobile clients; they sign in with
+	// ClientLogin and send the session as a GoogleLogin header.
+	r.Route("/api/greader", func(r chi.Router) {
+		r.Post("/accounts/ClientLogin", h.greaderClientLogin)
+		r.Route("/reader/api/0", func(r chi.Router) {
+			r.Use(h.greaderAuth)
+			r.Get("/token", h.greaderTokenHandler)
+			r.Get("/user-info", h.greaderUserInfo)
+			r.Get("/subscription/list", h.greaderSubscriptions)
+			r.Get("/tag/list", h.greaderTags)
+			r.Get("/unread-count", h.greaderUnreadCount)
+			r.Get("/stream/items/ids", h.greaderStreamItemIDs)
+			r.Get("/stream/items/contents", h.greaderItemContents)
+			r.Post("/stream/items/contents", h.greaderItemContents)
+			r.Get("/stream/contents", h.greaderStreamContents)
+			r.Get("/stream/contents/*", h.greaderStreamContents)
+			r.Group(func(r chi.Router) {
+				r.Use(h.greaderEditToken)
+				r.Post("/subscription/edit", h.greaderSubscriptionEdit)
+				r.Post("/subscription/quickadd", h.greaderQuickAdd)
+				r.Post("/edit-tag", h.greaderEditTag)
+				r.Post("/mark-all-as-read", h.greaderMarkAllRead)
+			})
+		})
 	})
 
 	// Static files (Frontend)
@@ -82,11 +138,30 @@ func NewRouter(cfg Config) http.Handler {
 	filesDir := http.Dir(fmt.Sprintf("%s/dist", workDir))
 	FileServer(r, "/", filesDir)
 
+	// Event stream; EventSource opens it with a ticket from
+	// /api/events/ticket instead of a token in the URL.
+	r.With(h.streamTicketAuth(authHandler.AuthMiddleware)).Get("/api/events", h.events)
+
 	// Protected routes (auth required)
 	r.Group(func(r chi.Router) {
 		r.Use(authHandler.AuthMiddleware)
 
 		r.Get("/api/auth/me", authHandler.Me)
+		r.Post("/api/events/ticket", h.eventTicket)
+
+		r.Route("/api/auth/sessions", func(r chi.Router) {
+			r.Use(authHandler.RequireSession)
+			r.Get("/", authHandler.ListSessions)
+			r.Delete("/", authHandler.RevokeOtherSessions)
+			r.Delete("/{id}", authHandler.RevokeSession)
+		})
+
+		r.Route("/api/tokens", func(r chi.Router) {
+			r.Use(authHandler.RequireSession)
+			r.Get("/", authHandler.ListAPITokens)
+			r.Post("/", authHandler.CreateAPIToken)
+			r.Delete("/{id}", authHandler.RevokeAPIToken)
+		})
 
 		r.Route("/api/folders", func(r chi.Router) {
 			r.Get("/", h.listFolders)
@@ -95,34 +170,74 @@ func NewRouter(cfg Config) http.Handler {
 			r.Delete("/{id}", h.deleteFolder)
 		})
 
+		r.Route("/api/saved-searches", func(r chi.Router) {
+			r.Get("/", h.listSavedSearches)
+			r.Post("/", h.createSavedSearch)
+			r.Put("/{id}", h.updateSavedSearch)
+			r.Delete("/{id}", h.deleteSavedSearch)
+		})
+
 		r.Route("/api/feeds", func(r chi.Router) {
 			r.Post("/", h.addFeed)
 			r.Delete("/{id}", h.deleteFeed)
 			r.Post("/{id}/refresh", h.refreshFeed)
+			r.Get("/{id}/errors", h.feedErrors)
+			r.Post("/{id}/resume", h.resumeFeed)
 		})
 
 		r.Route("/api/items", func(r chi.Router) {
 			r.Get("/", h.listItems)
+			r.Post("/mark-read", h.markAllRead)
 			r.Get("/{id}", h.getItem)
 			r.Get("/{id}/summary", h.itemSummary)
 			r.Post("/{id}/read", h.markRead(true))
 			r.Post("/{id}/unread", h.markRead(false))
 			r.Post("/{id}/bookmark", h.bookmark(true))
 			r.Post("/{id}/unbookmark", h.bookmark(false))
+			r.Put("/{id}/tags", h.setItemTags)
+		})
+
+		r.Route("/api/filters", func(r chi.Router) {
+			r.Get("/", h.listFilterRules)
+			r.Post("/", h.createFilterRule)
+			r.Post("/dry-run", h.dryRunFilterRule)
+			r.Put("/{id}", h.updateFilterRule)
+			r.Delete("/{id}", h.deleteFilterRule)
 		})
 
+		r.Get("/api/tags", h.listTags)
+		r.Delete("/api/tags/{tag}", h.deleteTag)
+
+		r.Get("/api/counts", h.counts)
 		r.Get("/api/bookmarks", h.listBookmarks)
+		r.Get("/api/highlights", h.listHighlights)
+		r.Get("/api/search", h.search)
 		r.Get("/api/top-news", h.topNews)
 
 		r.Get("/api/settings", h.getSettings)
 		r.Put("/api/settings", h.updateSettings)
 
+		// Publish tokens and the Fever password are credentials of their
+		// own; an API token must not be able to read or mint them.
+		r.Group(func(r chi.Router) {
+			r.Use(authHandler.RequireSession)
+			r.Get("/api/settings/publish-token", h.getPublishToken)
+			r.Post("/api/settings/publish-token", h.rotatePublishToken)
+			r.Get("/api/settings/fever", h.getFeverSettings)
+			r.Put("/api/settings/fever", h.setFeverPassword)
+			r.Delete("/api/settings/fever", h.disableFever)
+		})
+
 		r.Post("/api/refresh/all", h.refreshAll)
+		r.Get("/api/jobs/{id}", h.getJob)
 		r.Post("/api/refresh/folder/{id}", h.refreshFolder)
 
 		r.Group(func(r chi.Router) {
 			r.Use(httprate.LimitByIP(cfg.ReaderRatePerMinute, time.Minute))
 			r.Get("/api/reader", h.readerView)
+			// Resolving fetches arbitrary URLs server-side, so it is
+			// authenticated and rate limited like the reader view.
+			r.Post("/api/discover/resolve", h.discoverResolve)
 		})
 	})
 
@@ -254,7 +369,7 @@ func corsMiddleware(cfg Config, allowedOrigins []string) func(http.Handler) http
 			}
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 				w.Header().Set("Vary", "Origin")
@@ -295,11 +410,14 @@ func (h *Handler) discoverResolve(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"feeds": []map[string]string{
-			{"title": "Detected Feed", "url": body.URL},
-		},
-	})
+	ctx, cancel := context.WithTimeout(r.Context(), discoverTimeout)
+	defer cancel()
+	candidates, err := h.cfg.FeedService.DiscoverFeeds(ctx, body.URL)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"feeds": candidates})
 }
 
 func (h *Handler) listFolders(w http.ResponseWriter, r *http.Request) {
@@ -309,7 +427,39 @@ func (h *Handler) listFolders(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{"folders": folders})
+	searches, err := h.cfg.FeedService.ListSavedSearches(ctx, h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"folders": folders, "savedSearches": searches})
+}
+
+// counts serves unread/total counts for polling clients. The ETag lets them
+// poll with If-None-Match and get an empty 304 while nothing changed.
+func (h *Handler) counts(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.cfg.FeedService.Counts(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := json.Marshal(counts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sum := fnv.New64a()
+	sum.Write(data)
+	etag := fmt.Sprintf(`"%x"`, sum.Sum64())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
 
 func (h *Handler) createFolder(w http.ResponseWriter, r *http.Request) {
@@ -363,6 +513,11 @@ func (h *Handler) addFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	feed, err := h.cfg.FeedService.AddFeed(r.Context(), h.getUserID(r), body.FolderID, body.URL)
+	var ambiguous *services.AmbiguousFeedError
+	if errors.As(err, &ambiguous) {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": err.Error(), "candidates": ambiguous.Candidates})
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
@@ -389,8 +544,36 @@ func (h *Handler) refreshFeed(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]int{"itemsFetched": count})
 }
 
+func (h *Handler) feedErrors(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	list, err := h.cfg.FeedService.ListFeedErrors(r.Context(), h.getUserID(r), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errors": list})
+}
+
+func (h *Handler) resumeFeed(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err := h.cfg.FeedService.ResumeFeed(r.Context(), h.getUserID(r), id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) listItems(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
+	if v := q.Get("savedSearchId"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid savedSearchId %q", v))
+			return
+		}
+		h.listSavedSearchItems(w, r, id)
+		return
+	}
 	var folderID, feedID *int64
 	if v := q.Get("folderId"); v != "" {
 		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
@@ -406,7 +589,7 @@ func (h *Handler) listItems(w http.ResponseWriter, r *http.Request) {
 	limit := parseIntDefault(q.Get("limit"), defaultLimit)
 	sort := parseSortPref(q.Get("sort"))
 	cursor := parseItemCursor(q.Get("cursor"))
-	items, nextCursor, err := h.cfg.FeedService.ListItems(r.Context(), h.getUserID(r), folderID, feedID, unread, limit, cursor, sort)
+	items, nextCursor, err := h.cfg.FeedService.ListItems(r.Context(), h.getUserID(r), folderID, feedID, q.Get("tag"), unread, limit, cursor, sort)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
@@ -460,6 +643,69 @@ func (h *Handler) markRead(read bool) http.HandlerFunc {
 	}
 }
 
+type markAllReadRequest struct {
+	All           bool   `json:"all"`
+	FolderID      *int64 `json:"folderId"`
+	FeedID        *int64 `json:"feedId"`
+	Tag           string `json:"tag"`
+	Query         string `json:"q"`
+	SavedSearchID *int64 `json:"savedSearchId"`
+	Before        string `json:"before"`
+	Cursor        string `json:"cursor"`
+	Sort          string `json:"sort"`
+}
+
+// markAllRead marks a folder, feed, tag, search or, with "all", the whole
+// account as read, optionally only up to "before" or a list cursor.
+func (h *Handler) markAllRead(w http.ResponseWriter, r *http.Request) {
+	var req markAllReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	scope := services.MarkReadScope{
+		FolderID:      req.FolderID,
+		FeedID:        req.FeedID,
+		Tag:           req.Tag,
+		SavedSearchID: req.SavedSearchID,
+		Sort:          parseSortPref(req.Sort),
+	}
+	if req.Query != "" {
+		scope.Search = &services.SearchQuery{Query: req.Query}
+	}
+	if !req.All && scope.FolderID == nil && scope.FeedID == nil && scope.Tag == "" && scope.Search == nil && scope.SavedSearchID == nil {
+		writeError(w, http.StatusBadRequest, errors.New("scope required: folderId, feedId, tag, q, savedSearchId or all"))
+		return
+	}
+	before, err := parseSearchDate(req.Before, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	scope.Before = before
+	if req.Cursor != "" {
+		if scope.Cursor = parseItemCursor(req.Cursor); scope.Cursor == nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cursor %q", req.Cursor))
+			return
+		}
+	}
+
+	n, err := h.cfg.FeedService.MarkAllRead(r.Context(), h.getUserID(r), scope)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, errors.New("saved search not found"))
+		return
+	}
+	if errors.Is(err, services.ErrSearchUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"updated": n})
+}
+
 func (h *Handler) bookmark(set bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
@@ -476,7 +722,7 @@ func (h *Handler) listBookmarks(w http.ResponseWriter, r *http.Request) {
 	limit := parseIntDefault(q.Get("limit"), defaultLimit)
 	sort := parseSortPref(q.Get("sort"))
 	cursor := parseItemCursor(q.Get("cursor"))
-	items, next, err := h.cfg.FeedService.ListBookmarks(r.Context(), h.getUserID(r), limit, cursor, sort)
+	items, next, err := h.cfg.FeedService.ListBookmarks(r.Context(), h.getUserID(r), q.Get("tag"), limit, cursor, sort)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
@@ -488,12 +734,25 @@ func (h *Handler) listBookmarks(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// refreshAll starts a background refresh, or joins the one already running
+// for the user, and returns the job to poll at /api/jobs/{id}.
 func (h *Handler) refreshAll(w http.ResponseWriter, r *http.Request) {
-	if err := h.cfg.FeedService.RefreshAll(r.Context(), h.getUserID(r)); err != nil {
+	job, _, err := h.cfg.FeedService.StartRefreshAll(r.Context(), h.getUserID(r))
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
-	w.WriteHeader(http.StatusAccepted)
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"jobId": job.ID, "job": job})
+}
+
+func (h *Handler) getJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.cfg.FeedService.RefreshJob(h.getUserID(r), chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
 }
 
 func (h *Handler) refreshFolder(w http.ResponseWriter, r *http.Request) {
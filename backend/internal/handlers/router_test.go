@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/models"
+	"rss-feed-manager/backend/internal/services"
+)
+
+// testServer is the full router over a fresh SQLite database with one user
+// signed in.
+type testServer struct {
+	t       *testing.T
+	db      *db.DB
+	router  http.Handler
+	cfg     Config
+	userID  int64
+	session string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	d, err := db.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	if err := db.Migrate(d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	feedService := services.NewFeedService(d, nil)
+	topNewsService := services.NewTopNewsService(d)
+	events := services.NewEventBus()
+	feedService.SetEventBus(events)
+	cfg := Config{
+		FeedService:         feedService,
+		TopNewsService:      topNewsService,
+		AuthService:         services.NewAuthService(d, nil),
+		OPMLService:         services.NewOPMLService(feedService),
+		AccountService:      services.NewAccountService(d, feedService),
+		PublishService:      services.NewPublishService(d, feedService, topNewsService),
+		FeverService:        services.NewFeverService(d, feedService),
+		GReaderService:      services.NewGReaderService(d, feedService),
+		Events:              events,
+		ReaderRatePerMinute: 20,
+	}
+	s := &testServer{t: t, db: d, router: NewRouter(cfg), cfg: cfg, session: "test-session"}
+	if err := d.QueryRow(`INSERT INTO users(email) VALUES('reader@example.com') RETURNING id`).Scan(&s.userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Exec(`INSERT INTO sessions(user_id, token, expires_at) VALUES(?, ?, ?)`,
+		s.userID, s.session, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// do sends a request through the router as bearer; a string body is sent
+// as JSON.
+func (s *testServer) do(method, path, bearer, body string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, rd)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// apiToken creates a personal API token for the user.
+func (s *testServer) apiToken(scope string) string {
+	s.t.Helper()
+	token, err := s.cfg.AuthService.CreateAPIToken(context.Background(), s.userID, "test", scope)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return token.Token
+}
+
+// TestSessionOnlyRoutes checks that credentials and the account archive
+// cannot be reached with an API token, even a read_write one.
+func TestSessionOnlyRoutes(t *testing.T) {
+	s := newTestServer(t)
+	token := s.apiToken("read_write")
+
+	for _, route := range []struct{ method, path, body string }{
+		{http.MethodGet, "/api/settings/fever", ""},
+		{http.MethodPut, "/api/settings/fever", `{"password":"correct horse"}`},
+		{http.MethodDelete, "/api/settings/fever", ""},
+		{http.MethodGet, "/api/settings/publish-token", ""},
+		{http.MethodPost, "/api/settings/publish-token", ""},
+		{http.MethodGet, "/api/account/export", ""},
+		{http.MethodGet, "/api/tokens", ""},
+		{http.MethodGet, "/api/auth/sessions", ""},
+	} {
+		if rec := s.do(route.method, route.path, token, route.body); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s with API token: status %d, want 403", route.method, route.path, rec.Code)
+		}
+		if rec := s.do(route.method, route.path, s.session, route.body); rec.Code >= 400 {
+			t.Errorf("%s %s with session: status %d: %s", route.method, route.path, rec.Code, rec.Body)
+		}
+	}
+	if rec := s.do(http.MethodGet, "/api/folders", token, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /api/folders with API token: status %d, want 200", rec.Code)
+	}
+}
+
+// TestPublishToken checks that reading the publish token never creates or
+// changes it; only POST does.
+func TestPublishToken(t *testing.T) {
+	s := newTestServer(t)
+	get := func() string {
+		t.Helper()
+		rec := s.do(http.MethodGet, "/api/settings/publish-token", s.session, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET publish-token: status %d", rec.Code)
+		}
+		var body struct{ Token string }
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Token
+	}
+
+	if token := get(); token != "" {
+		t.Fatalf("GET before POST = %q, want no token", token)
+	}
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM publish_tokens`).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("GET created a publish token (count %d, err %v)", count, err)
+	}
+
+	rec := s.do(http.MethodPost, "/api/settings/publish-token", s.session, "")
+	var created struct{ Token string }
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || created.Token == "" {
+		t.Fatalf("POST publish-token: status %d, err %v", rec.Code, err)
+	}
+	if token := get(); token != created.Token {
+		t.Errorf("GET after POST = %q, want %q", token, created.Token)
+	}
+	if token := get(); token != created.Token {
+		t.Errorf("second GET rotated the token to %q", token)
+	}
+}
+
+// TestAPITokenAuth checks how personal API tokens authenticate: read tokens
+// cannot write, use is recorded, and unknown or revoked tokens are refused.
+func TestAPITokenAuth(t *testing.T) {
+	s := newTestServer(t)
+	var folderID int64
+	if err := s.db.QueryRow(`INSERT INTO folders(user_id, name) VALUES(?, 'News') RETURNING id`, s.userID).Scan(&folderID); err != nil {
+		t.Fatal(err)
+	}
+	folder := fmt.Sprintf("/api/folders/%d", folderID)
+
+	read := s.apiToken("read")
+	if rec := s.do(http.MethodGet, "/api/folders", read, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET with read token: status %d, want 200", rec.Code)
+	}
+	for _, route := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/folders", `{"name":"Tech"}`},
+		{http.MethodPatch, folder, `{"name":"Renamed"}`},
+		{http.MethodDelete, folder, ""},
+	} {
+		if rec := s.do(route.method, route.path, read, route.body); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s with read token: status %d, want 403", route.method, route.path, rec.Code)
+		}
+	}
+	var name string
+	if err := s.db.QueryRow(`SELECT name FROM folders WHERE id=?`, folderID).Scan(&name); err != nil || name != "News" {
+		t.Errorf("folder after read-token writes: %q, %v", name, err)
+	}
+
+	token, err := s.cfg.AuthService.CreateAPIToken(context.Background(), s.userID, "script", "read_write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := s.do(http.MethodPatch, folder, token.Token, `{"name":"Renamed"}`); rec.Code >= 400 {
+		t.Errorf("PATCH with read_write token: status %d", rec.Code)
+	}
+	var lastUsed sql.NullTime
+	if err := s.db.QueryRow(`SELECT last_used_at FROM api_tokens WHERE id=?`, token.ID).Scan(&lastUsed); err != nil {
+		t.Fatal(err)
+	}
+	if !lastUsed.Valid || time.Since(lastUsed.Time) > time.Minute {
+		t.Errorf("last_used_at = %v, want about now", lastUsed)
+	}
+
+	if rec := s.do(http.MethodDelete, fmt.Sprintf("/api/tokens/%d", token.ID), s.session, ""); rec.Code >= 400 {
+		t.Fatalf("revoke: status %d", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/api/folders", token.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET with revoked token: status %d, want 401", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/api/folders", services.APITokenPrefix+"unknown", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET with unknown token: status %d, want 401", rec.Code)
+	}
+}
+
+// TestCountsETag checks that /api/counts answers a repeated request with
+// 304 Not Modified and changes its ETag once an item is read.
+func TestCountsETag(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.entries(2)
+
+	counts := func(etag string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/counts", nil)
+		req.Header.Set("Authorization", "Bearer "+s.session)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := counts("")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("counts: status %d, ETag %q", rec.Code, etag)
+	}
+	var body models.Counts
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Account != (models.ItemCounts{Unread: 2, Total: 2}) {
+		t.Errorf("account counts = %+v, want 2 unread of 2", body.Account)
+	}
+
+	if rec := counts(etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("counts with a matching If-None-Match: status %d, %d bytes; want 304 and no body", rec.Code, rec.Body.Len())
+	}
+
+	if rec := s.do(http.MethodPost, fmt.Sprintf("/api/items/%d/read", ids[0]), s.session, ""); rec.Code >= 400 {
+		t.Fatalf("mark read: status %d", rec.Code)
+	}
+	rec = counts(etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("counts after a read: status %d, want 200", rec.Code)
+	}
+	if next := rec.Header().Get("ETag"); next == "" || next == etag {
+		t.Errorf("ETag after a read = %q, want a new one (was %q)", next, etag)
+	}
+}
+
+// TestCORSPreflight checks that a preflight from the frontend allows every
+// method the router serves.
+func TestCORSPreflight(t *testing.T) {
+	s := newTestServer(t)
+	cfg := s.cfg
+	cfg.FrontendOrigin = "https://reader.example.com"
+	router := NewRouter(cfg)
+	routes, ok := router.(chi.Routes)
+	if !ok {
+		t.Fatalf("router is %T, not chi.Routes", router)
+	}
+	methods := map[string]bool{}
+	if err := chi.Walk(routes, func(method, _ string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		methods[method] = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/filters/1", nil)
+	req.Header.Set("Origin", cfg.FrontendOrigin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight: status %d, want 204", rec.Code)
+	}
+	allowed := map[string]bool{}
+	for _, m := range strings.Split(rec.Header().Get("Access-Control-Allow-Methods"), ",") {
+		allowed[strings.TrimSpace(m)] = true
+	}
+	// HEAD needs no preflight and browsers refuse to send CONNECT or TRACE;
+	// routes mounted for any method list them too.
+	delete(methods, http.MethodHead)
+	delete(methods, http.MethodConnect)
+	delete(methods, http.MethodTrace)
+	for method := range methods {
+		if !allowed[method] {
+			t.Errorf("Access-Control-Allow-Methods %q is missing %s", rec.Header().Get("Access-Control-Allow-Methods"), method)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"rss-feed-manager/backend/internal/models"
+	"rss-feed-manager/backend/internal/services"
+)
+
+type savedSearchRequest struct {
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	FolderID   *int64 `json:"folderId"`
+	FeedID     *int64 `json:"feedId"`
+	UnreadOnly bool   `json:"unreadOnly"`
+	WithinDays int    `json:"withinDays"`
+	Digest     bool   `json:"digest"`
+}
+
+func (req savedSearchRequest) model() models.SavedSearch {
+	return models.SavedSearch{
+		Name:       req.Name,
+		Query:      req.Query,
+		FolderID:   req.FolderID,
+		FeedID:     req.FeedID,
+		UnreadOnly: req.UnreadOnly,
+		WithinDays: req.WithinDays,
+		Digest:     req.Digest,
+	}
+}
+
+func (h *Handler) listSavedSearches(w http.ResponseWriter, r *http.Request) {
+	searches, err := h.cfg.FeedService.ListSavedSearches(r.Context(), h.getUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.cfg.FeedService.CountSavedSearches(r.Context(), h.getUserID(r), searches)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"savedSearches": searches})
+}
+
+func (h *Handler) createSavedSearch(w http.ResponseWriter, r *http.Request) {
+	var req savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ss, err := h.cfg.FeedService.CreateSavedSearch(r.Context(), h.getUserID(r), req.model())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ss)
+}
+
+func (h *Handler) updateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	var req savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ss := req.model()
+	ss.ID = id
+	if err := h.cfg.FeedService.UpdateSavedSearch(r.Context(), h.getUserID(r), ss); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err := h.cfg.FeedService.DeleteSavedSearch(r.Context(), h.getUserID(r), id); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listSavedSearchItems serves /api/items?savedSearchId= with the same
+// cursor as folders and feeds.
+func (h *Handler) listSavedSearchItems(w http.ResponseWriter, r *http.Request, savedSearchID int64) {
+	q := r.URL.Query()
+	items, next, err := h.cfg.FeedService.ListSavedSearchItems(r.Context(), h.getUserID(r), savedSearchID,
+		parseIntDefault(q.Get("limit"), defaultLimit), parseItemCursor(q.Get("cursor")), parseSortPref(q.Get("sort")))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, errors.New("saved search not found"))
+		return
+	}
+	if errors.Is(err, services.ErrSearchUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	resp := map[string]interface{}{"items": items}
+	if next != nil {
+		resp["nextCursor"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
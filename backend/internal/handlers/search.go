@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/services"
+)
+
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sq := services.SearchQuery{
+		Query:      q.Get("q"),
+		UnreadOnly: q.Get("unread") == "true",
+		Sort:       q.Get("sort"),
+		Limit:      parseIntDefault(q.Get("limit"), defaultLimit),
+		Cursor:     parseSearchCursor(q.Get("cursor")),
+	}
+	if v := q.Get("folderId"); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			sq.FolderID = &parsed
+		}
+	}
+	if v := q.Get("feedId"); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			sq.FeedID = &parsed
+		}
+	}
+	var err error
+	if sq.Since, err = parseSearchDate(q.Get("since"), false); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if sq.Until, err = parseSearchDate(q.Get("until"), true); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	hits, next, err := h.cfg.FeedService.SearchItems(r.Context(), h.getUserID(r), sq)
+	if errors.Is(err, services.ErrSearchUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	resp := map[string]interface{}{"items": hits}
+	if next != nil {
+		resp["nextCursor"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseSearchCursor reads a cursor made by SearchCursor.Encode; anything
+// else starts from the first page.
+func parseSearchCursor(raw string) *services.SearchCursor {
+	parts := strings.Split(raw, ":")
+	if len(parts) != 2 {
+		return nil
+	}
+	key, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(key) || math.IsInf(key, 0) {
+		return nil
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || id <= 0 {
+		return nil
+	}
+	return &services.SearchCursor{Key: key, ID: id}
+}
+
+// parseSearchDate accepts RFC 3339 timestamps or plain dates. A plain date
+// used as an upper bound includes that whole day.
+func parseSearchDate(raw string, endOfDay bool) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", raw)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
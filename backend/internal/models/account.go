@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// AccountArchiveFormat names the account archive format; AccountArchiveVersion
+// is raised whenever the layout changes in a way older servers cannot read.
+const (
+	AccountArchiveFormat  = "rss-feed-manager-account"
+	AccountArchiveVersion = 1
+)
+
+// AccountArchive is everything a user keeps in their account, in a form
+// that can be imported on another server. Feeds are identified by URL and
+// items by feed URL and GUID, never by database ids.
+type AccountArchive struct {
+	Format        string               `json:"format"`
+	Version       int                  `json:"version"`
+	ExportedAt    time.Time            `json:"exportedAt"`
+	Settings      ArchiveSettings      `json:"settings"`
+	Folders       []ArchiveFolder      `json:"folders"`
+	Items         []ArchiveItem        `json:"items"`
+	SavedSearches []ArchiveSavedSearch `json:"savedSearches"`
+	FilterRules   []ArchiveFilterRule  `json:"filterRules"`
+}
+
+type ArchiveSettings struct {
+	RetentionDays int `json:"retentionDays,omitempty"`
+}
+
+type ArchiveFolder struct {
+	Name  string        `json:"name"`
+	Feeds []ArchiveFeed `json:"feeds"`
+}
+
+// ArchiveFeed is a subscription. Title is only set when the user renamed
+// the feed; SourceTitle is what the feed calls itself.
+type ArchiveFeed struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	SourceTitle string `json:"sourceTitle,omitempty"`
+	SiteURL     string `json:"siteUrl,omitempty"`
+}
+
+// ArchiveItem is an item the user read, bookmarked, hid, highlighted or
+// tagged. Bookmarked, highlighted and tagged items carry their content as a
+// copy for the user; the rest are just feed URL, GUID and state. Imports
+// only use the feed URL, GUID and state.
+type ArchiveItem struct {
+	FeedURL      string     `json:"feedUrl"`
+	GUID         string     `json:"guid"`
+	Link         string     `json:"link,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	PublishedAt  *time.Time `json:"publishedAt,omitempty"`
+	SummaryText  string     `json:"summaryText,omitempty"`
+	ContentHTML  string     `json:"contentHtml,omitempty"`
+	MediaJSON    string     `json:"mediaJson,omitempty"`
+	Read         bool       `json:"read,omitempty"`
+	Bookmarked   bool       `json:"bookmarked,omitempty"`
+	BookmarkedAt *time.Time `json:"bookmarkedAt,omitempty"`
+	Hidden       bool       `json:"hidden,omitempty"`
+	Highlighted  bool       `json:"highlighted,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+}
+
+// ArchiveSavedSearch scopes by folder name and feed URL instead of ids.
+type ArchiveSavedSearch struct {
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	Folder     string `json:"folder,omitempty"`
+	FeedURL    string `json:"feedUrl,omitempty"`
+	UnreadOnly bool   `json:"unreadOnly,omitempty"`
+	WithinDays int    `json:"withinDays,omitempty"`
+	Digest     bool   `json:"digest,omitempty"`
+}
+
+type ArchiveFilterRule struct {
+	Name      string `json:"name"`
+	Field     string `json:"field"`
+	MatchType string `json:"matchType"`
+	Pattern   string `json:"pattern"`
+	Action    string `json:"action"`
+	Tag       string `json:"tag,omitempty"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// AccountImportResult counts what an import added; things the account
+// already had are not counted.
+type AccountImportResult struct {
+	Folders       int `json:"folders"`
+	Feeds         int `json:"feeds"`
+	Items         int `json:"items"`
+	SavedSearches int `json:"savedSearches"`
+	FilterRules   int `json:"filterRules"`
+}
@@ -8,25 +8,144 @@ type User struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// Session is a signed-in browser or client. Kind is "web" for app sign-ins
+// and "app" for Google Reader clients; Current marks the session making the
+// request.
+type Session struct {
+	ID         int64      `json:"id"`
+	Kind       string     `json:"kind"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"userAgent,omitempty"`
+	Current    bool       `json:"current"`
+}
+
+// APIToken is a personal token for scripts and other clients. Token is only
+// set when the token is created; afterwards Prefix identifies it.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"userId"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
 type Folder struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Feeds       []Feed    `json:"feeds,omitempty"`
+	UnreadCount int       `json:"unreadCount"`
+	TotalCount  int       `json:"totalCount"`
+}
+
+type Feed struct {
+	ID                  int64      `json:"id"`
+	UserID              int64      `json:"userId"`
+	FolderID            int64      `json:"folderId"`
+	URL                 string     `json:"url"`
+	Title               string     `json:"title"`
+	SiteURL             string     `json:"siteUrl"`
+	Etag                string     `json:"etag"`
+	LastModified        string     `json:"lastModified"`
+	LastCheckedAt       *time.Time `json:"lastCheckedAt,omitempty"`
+	NextCheckAt         *time.Time `json:"nextCheckAt,omitempty"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+	LastHTTPStatus      int        `json:"lastHttpStatus,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Paused              bool       `json:"paused"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UnreadCount         int        `json:"unreadCount"`
+	TotalCount          int        `json:"totalCount"`
+}
+
+// ItemCounts is the number of unread and total visible items.
+type ItemCounts struct {
+	Unread int `json:"unread"`
+	Total  int `json:"total"`
+}
+
+// Counts holds item counts for the whole account and per folder and feed,
+// and the number of starred (bookmarked) items in the account.
+type Counts struct {
+	Account ItemCounts           `json:"account"`
+	Starred int                  `json:"starred"`
+	Folders map[int64]ItemCounts `json:"folders"`
+	Feeds   map[int64]ItemCounts `json:"feeds"`
+}
+
+// SavedSearch is a named full-text query shown alongside folders.
+// WithinDays limits it to recent items; zero means no limit.
+type SavedSearch struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	Name        string    `json:"name"`
+	Query       string    `json:"query"`
+	FolderID    *int64    `json:"folderId,omitempty"`
+	FeedID      *int64    `json:"feedId,omitempty"`
+	UnreadOnly  bool      `json:"unreadOnly"`
+	WithinDays  int       `json:"withinDays,omitempty"`
+	Digest      bool      `json:"digest"`
+	UnreadCount *int      `json:"unreadCount,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// FilterRule acts on incoming items whose Field matches Pattern. MatchType is
+// keyword (comma-separated, case-insensitive), regex or media_type (for
+// example "audio/*"); Tag is only used by the tag action.
+type FilterRule struct {
 	ID        int64     `json:"id"`
 	UserID    int64     `json:"userId"`
 	Name      string    `json:"name"`
+	Field     string    `json:"field"`
+	MatchType string    `json:"matchType"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	Tag       string    `json:"tag,omitempty"`
+	Enabled   bool      `json:"enabled"`
 	CreatedAt time.Time `json:"createdAt"`
-	Feeds     []Feed    `json:"feeds,omitempty"`
 }
 
-type Feed struct {
-	ID            int64      `json:"id"`
-	UserID        int64      `json:"userId"`
-	FolderID      int64      `json:"folderId"`
-	URL           string     `json:"url"`
-	Title         string     `json:"title"`
-	SiteURL       string     `json:"siteUrl"`
-	Etag          string     `json:"etag"`
-	LastModified  string     `json:"lastModified"`
-	LastCheckedAt *time.Time `json:"lastCheckedAt,omitempty"`
-	CreatedAt     time.Time  `json:"createdAt"`
+// RefreshJob is the progress of a background refresh run. Status is
+// "running" or "finished".
+type RefreshJob struct {
+	ID         string           `json:"id"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Completed  int              `json:"completed"`
+	Failed     int              `json:"failed"`
+	NewItems   int              `json:"newItems"`
+	Feeds      []RefreshJobFeed `json:"feeds"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+}
+
+// RefreshJobFeed is one feed's outcome within a refresh job. Status is
+// "pending", "done" or "failed".
+type RefreshJobFeed struct {
+	FeedID   int64  `json:"feedId"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Fetched  int    `json:"fetched"`
+	NewItems int    `json:"newItems"`
+	Error    string `json:"error,omitempty"`
+}
+
+// FeedError is one recorded fetch failure for a feed.
+type FeedError struct {
+	ID         int64     `json:"id"`
+	FeedID     int64     `json:"feedId"`
+	HTTPStatus int       `json:"httpStatus,omitempty"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurredAt"`
 }
 
 type Media struct {
@@ -50,6 +169,14 @@ type Item struct {
 	CreatedAt   time.Time  `json:"createdAt"`
 	State       ItemState  `json:"state"`
 	Source      *Feed      `json:"source,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+}
+
+// Tag is a user-defined label with the number of items carrying it.
+type Tag struct {
+	Name        string `json:"name"`
+	Count       int    `json:"count"`
+	UnreadCount int    `json:"unreadCount"`
 }
 
 type ItemState struct {
@@ -79,3 +206,10 @@ type SummaryResult struct {
 	Source string   `json:"source,omitempty"`
 	Reason string   `json:"reason,omitempty"`
 }
+
+// SearchHit is an item matched by full-text search, with an HTML snippet
+// whose matching terms are wrapped in <mark>.
+type SearchHit struct {
+	Item
+	Snippet string `json:"snippet"`
+}
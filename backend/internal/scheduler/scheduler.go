@@ -5,21 +5,24 @@ import (
 	"log"
 	"time"
 
+	"rss-feed-manager/backend/internal/backup"
 	"rss-feed-manager/backend/internal/services"
 )
 
 type Config struct {
-	UserID         int64
-	PollInterval   time.Duration
+	UserID         int64         // Digest recipient; polling covers every user with feeds
+	CheckInterval  time.Duration // How often to look for feeds that are due
 	DigestEnabled  bool
 	DigestInterval time.Duration
 }
 
 type Scheduler struct {
-	feedService   *services.FeedService
-	digestService *services.DigestService
-	cfg           Config
-	stopCh        chan struct{}
+	feedService    *services.FeedService
+	digestService  *services.DigestService
+	backups        *backup.Backuper
+	backupInterval time.Duration
+	cfg            Config
+	stopCh         chan struct{}
 }
 
 func NewScheduler(feedService *services.FeedService, digestService *services.DigestService, cfg Config) *Scheduler {
@@ -36,6 +39,15 @@ func (s *Scheduler) Start() {
 	if s.cfg.DigestEnabled {
 		go s.sendDigests()
 	}
+	if s.backups != nil {
+		go s.takeBackups()
+	}
+}
+
+// SetBackups makes the scheduler take a backup every interval. Call it
+// before Start.
+func (s *Scheduler) SetBackups(b *backup.Backuper, interval time.Duration) {
+	s.backups, s.backupInterval = b, interval
 }
 
 func (s *Scheduler) Stop() {
@@ -43,15 +55,17 @@ func (s *Scheduler) Stop() {
 }
 
 func (s *Scheduler) pollFeeds() {
-	ticker := time.NewTicker(s.cfg.PollInterval)
+	ticker := time.NewTicker(s.cfg.CheckInterval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-s.stopCh:
 			return
 		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-			if err := s.feedService.RefreshAll(ctx, s.cfg.UserID); err != nil {
+			// Budget the whole run to one tick; feeds left over stay due
+			// and are picked up first next time.
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.CheckInterval)
+			if err := s.pollAllUsers(ctx); err != nil {
 				log.Printf("background refresh error: %v", err)
 			}
 			cancel()
@@ -59,6 +73,68 @@ func (s *Scheduler) pollFeeds() {
 	}
 }
 
+// pollAllUsers refreshes the due feeds of every user that has subscriptions.
+// Each feed carries its own next-check time (see FeedService.ListDueFeedIDs).
+// Feeds are interleaved round-robin across users so an account with many
+// subscriptions cannot starve the others when the run is cut short.
+func (s *Scheduler) pollAllUsers(ctx context.Context) error {
+	userIDs, err := s.feedService.ListUserIDsWithFeeds(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	feedsByUser := make(map[int64][]int64, len(userIDs))
+	for _, userID := range userIDs {
+		feedIDs, err := s.feedService.ListDueFeedIDs(ctx, userID, now)
+		if err != nil {
+			log.Printf("background refresh: list feeds for user %d: %v", userID, err)
+			continue
+		}
+		feedsByUser[userID] = feedIDs
+	}
+
+	targets := interleaveFeeds(userIDs, feedsByUser)
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// Stop starting new feeds as soon as the scheduler is stopped.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	summary := s.feedService.RefreshMany(ctx, targets, nil)
+	log.Printf("background refresh: %d/%d due feeds across %d users (%d failed, %d skipped)",
+		summary.Completed, summary.Total, len(userIDs), summary.Failed, summary.Skipped)
+	return nil
+}
+
+// interleaveFeeds flattens per-user feed lists into a single round-robin
+// order: the first feed of each user, then the second of each, and so on.
+// Users are visited in the order given by userIDs.
+func interleaveFeeds(userIDs []int64, feedsByUser map[int64][]int64) []services.RefreshTarget {
+	var out []services.RefreshTarget
+	for round := 0; ; round++ {
+		added := false
+		for _, userID := range userIDs {
+			feedIDs := feedsByUser[userID]
+			if round < len(feedIDs) {
+				out = append(out, services.RefreshTarget{UserID: userID, FeedID: feedIDs[round]})
+				added = true
+			}
+		}
+		if !added {
+			return out
+		}
+	}
+}
+
 func (s *Scheduler) sendDigests() {
 	ticker := time.NewTicker(s.cfg.DigestInterval)
 	defer ticker.Stop()
@@ -75,3 +151,22 @@ func (s *Scheduler) sendDigests() {
 		}
 	}
 }
+
+func (s *Scheduler) takeBackups() {
+	ticker := time.NewTicker(s.backupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.backupInterval)
+			if name, err := s.backups.Run(ctx); err != nil {
+				log.Printf("backup error: %v", err)
+			} else {
+				log.Printf("backup stored as %s", name)
+			}
+			cancel()
+		}
+	}
+}
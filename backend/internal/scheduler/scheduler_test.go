@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+
+	"rss-feed-manager/backend/internal/services"
+)
+
+func TestInterleaveFeeds(t *testing.T) {
+	userIDs := []int64{1, 2, 3}
+	feedsByUser := map[int64][]int64{
+		1: {10, 11, 12},
+		2: {20},
+		3: {30, 31},
+	}
+	result := interleaveFeeds(userIDs, feedsByUser)
+	expected := []services.RefreshTarget{
+		{UserID: 1, FeedID: 10},
+		{UserID: 2, FeedID: 20},
+		{UserID: 3, FeedID: 30},
+		{UserID: 1, FeedID: 11},
+		{UserID: 3, FeedID: 31},
+		{UserID: 1, FeedID: 12},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("interleaveFeeds() = %v, expected %v", result, expected)
+	}
+}
+
+func TestInterleaveFeeds_Empty(t *testing.T) {
+	if result := interleaveFeeds(nil, nil); result != nil {
+		t.Errorf("Expected nil, got %v", result)
+	}
+	if result := interleaveFeeds([]int64{1}, map[int64][]int64{}); result != nil {
+		t.Errorf("Expected nil for user without feeds, got %v", result)
+	}
+}
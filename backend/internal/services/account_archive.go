@@ -0,0 +1,582 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// accountArchiveEntry is the file holding the archive inside a zip bundle.
+const accountArchiveEntry = "account.json"
+
+// MaxAccountArchiveSize bounds the JSON an import will decode, whether
+// uploaded as is or compressed in a zip bundle.
+const MaxAccountArchiveSize = 512 << 20
+
+var ErrArchiveTooLarge = errors.New("archive too large")
+
+// AccountService exports a user's account as a portable archive and merges
+// archives into accounts, on this server or another one.
+type AccountService struct {
+	db          *db.DB
+	feedService *FeedService
+}
+
+func NewAccountService(db *db.DB, feedService *FeedService) *AccountService {
+	return &AccountService{db: db, feedService: feedService}
+}
+
+// Export collects the user's settings, folders, feeds, saved searches,
+// filter rules and the items they have state or tags on. Content is kept
+// for bookmarked, highlighted and tagged items; for items that were only
+// read or hidden the state is enough.
+func (s *AccountService) Export(ctx context.Context, userID int64) (*models.AccountArchive, error) {
+	archive := &models.AccountArchive{
+		Format:        models.AccountArchiveFormat,
+		Version:       models.AccountArchiveVersion,
+		ExportedAt:    time.Now().UTC(),
+		Settings:      models.ArchiveSettings{RetentionDays: s.feedService.GetRetentionDays(ctx, userID)},
+		Folders:       []models.ArchiveFolder{},
+		Items:         []models.ArchiveItem{},
+		SavedSearches: []models.ArchiveSavedSearch{},
+		FilterRules:   []models.ArchiveFilterRule{},
+	}
+	folderNames, feedURLs, err := s.exportFolders(ctx, userID, archive)
+	if err != nil {
+		return nil, fmt.Errorf("export folders: %w", err)
+	}
+	if err := s.exportItems(ctx, userID, archive); err != nil {
+		return nil, fmt.Errorf("export items: %w", err)
+	}
+
+	searches, err := s.feedService.ListSavedSearches(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("export saved searches: %w", err)
+	}
+	for _, ss := range searches {
+		out := models.ArchiveSavedSearch{
+			Name:       ss.Name,
+			Query:      ss.Query,
+			UnreadOnly: ss.UnreadOnly,
+			WithinDays: ss.WithinDays,
+			Digest:     ss.Digest,
+		}
+		if ss.FolderID != nil {
+			out.Folder = folderNames[*ss.FolderID]
+		}
+		if ss.FeedID != nil {
+			out.FeedURL = feedURLs[*ss.FeedID]
+		}
+		archive.SavedSearches = append(archive.SavedSearches, out)
+	}
+
+	rules, err := s.feedService.ListFilterRules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("export filter rules: %w", err)
+	}
+	for _, rule := range rules {
+		archive.FilterRules = append(archive.FilterRules, models.ArchiveFilterRule{
+			Name:      rule.Name,
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			Tag:       rule.Tag,
+			Enabled:   rule.Enabled,
+		})
+	}
+	return archive, nil
+}
+
+// exportFolders fills in the folders and their feeds, returning folder names
+// and feed URLs by id for the saved searches that refer to them.
+func (s *AccountService) exportFolders(ctx context.Context, userID int64, archive *models.AccountArchive) (map[int64]string, map[int64]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name FROM folders WHERE user_id=? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	folderNames := map[int64]string{}
+	position := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		folderNames[id] = name
+		position[id] = len(archive.Folders)
+		archive.Folders = append(archive.Folders, models.ArchiveFolder{Name: name, Feeds: []models.ArchiveFeed{}})
+	}
+	if err := rows.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT feeds.id, feeds.folder_id, sources.url, COALESCE(feeds.title, ''),
+			   COALESCE(sources.title, ''), COALESCE(sources.site_url, '')
+		FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=?
+		ORDER BY feeds.id`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	feedURLs := map[int64]string{}
+	for rows.Next() {
+		var id, folderID int64
+		var feed models.ArchiveFeed
+		if err := rows.Scan(&id, &folderID, &feed.URL, &feed.Title, &feed.SourceTitle, &feed.SiteURL); err != nil {
+			return nil, nil, err
+		}
+		feedURLs[id] = feed.URL
+		if i, ok := position[folderID]; ok {
+			archive.Folders[i].Feeds = append(archive.Folders[i].Feeds, feed)
+		}
+	}
+	return folderNames, feedURLs, rows.Err()
+}
+
+func (s *AccountService) exportItems(ctx context.Context, userID int64, archive *models.AccountArchive) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT item_id, tag FROM item_tags WHERE user_id=? ORDER BY item_id, lower(tag)`, userID)
+	if err != nil {
+		return err
+	}
+	tags := map[int64][]string{}
+	for rows.Next() {
+		var itemID int64
+		var tag string
+		if err := rows.Scan(&itemID, &tag); err != nil {
+			rows.Close()
+			return err
+		}
+		tags[itemID] = append(tags[itemID], tag)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT items.id, sources.url, items.guid, COALESCE(items.link, ''), COALESCE(items.title, ''),
+			   COALESCE(items.author, ''), items.published_at, COALESCE(items.summary_text, ''),
+			   COALESCE(items.content_html, ''), COALESCE(items.media_json, ''),
+			   COALESCE(item_state.is_read, 0), COALESCE(item_state.is_bookmarked, 0), item_state.bookmarked_at,
+			   COALESCE(item_state.is_hidden, 0), COALESCE(item_state.is_highlighted, 0)
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.id = feeds.source_id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE items.user_id=?
+		  AND (item_state.is_read = 1 OR item_state.is_bookmarked = 1 OR item_state.is_hidden = 1
+			   OR item_state.is_highlighted = 1
+			   OR items.id IN (SELECT item_id FROM item_tags WHERE user_id=?))
+		ORDER BY items.id`, userID, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var it models.ArchiveItem
+		var published, bookmarkedAt sql.NullTime
+		if err := rows.Scan(&id, &it.FeedURL, &it.GUID, &it.Link, &it.Title, &it.Author, &published,
+			&it.SummaryText, &it.ContentHTML, &it.MediaJSON,
+			&it.Read, &it.Bookmarked, &bookmarkedAt, &it.Hidden, &it.Highlighted); err != nil {
+			return err
+		}
+		it.Tags = tags[id]
+		if !it.Bookmarked && !it.Highlighted && len(it.Tags) == 0 {
+			it.Link, it.Title, it.Author, it.SummaryText, it.ContentHTML, it.MediaJSON = "", "", "", "", "", ""
+		} else if published.Valid {
+			it.PublishedAt = &published.Time
+		}
+		if bookmarkedAt.Valid {
+			it.BookmarkedAt = &bookmarkedAt.Time
+		}
+		archive.Items = append(archive.Items, it)
+	}
+	return rows.Err()
+}
+
+// Import merges an archive into the user's account in one transaction.
+// Nothing the account already has is changed or removed: folders are
+// matched by name, feeds by URL, and item state only ever gains flags, so
+// importing the same archive twice is harmless. Feeds new to this server
+// are fetched by the scheduler on its next pass.
+func (s *AccountService) Import(ctx context.Context, userID int64, archive *models.AccountArchive) (models.AccountImportResult, error) {
+	var result models.AccountImportResult
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if days := archive.Settings.RetentionDays; days > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_settings (user_id, retention_days) VALUES (?, ?)
+			ON CONFLICT(user_id) DO NOTHING`, userID, days); err != nil {
+			return result, err
+		}
+	}
+	folderIDs, err := s.importFolders(ctx, tx, userID, archive.Folders, &result)
+	if err != nil {
+		return result, fmt.Errorf("import folders: %w", err)
+	}
+	subs, err := s.importFeeds(ctx, tx, userID, archive.Folders, folderIDs, &result)
+	if err != nil {
+		return result, fmt.Errorf("import feeds: %w", err)
+	}
+	if err := s.importItems(ctx, tx, userID, archive.Items, subs, &result); err != nil {
+		return result, fmt.Errorf("import items: %w", err)
+	}
+	if err := s.importSavedSearches(ctx, tx, userID, archive.SavedSearches, folderIDs, subs, &result); err != nil {
+		return result, fmt.Errorf("import saved searches: %w", err)
+	}
+	if err := s.importFilterRules(ctx, tx, userID, archive.FilterRules, &result); err != nil {
+		return result, fmt.Errorf("import filter rules: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// importFolders creates the archive's folders the user does not have yet
+// and returns the user's folder ids by name.
+func (s *AccountService) importFolders(ctx context.Context, tx *db.Tx, userID int64, folders []models.ArchiveFolder, result *models.AccountImportResult) (map[string]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name FROM folders WHERE user_id=? ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]int64{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// Descending, so the oldest of several same-named folders wins.
+		ids[name] = id
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	for _, folder := range folders {
+		name := strings.TrimSpace(folder.Name)
+		if name == "" || ids[name] != 0 {
+			continue
+		}
+		var id int64
+		if err := tx.QueryRowContext(ctx, `INSERT INTO folders(user_id, name) VALUES(?, ?) RETURNING id`, userID, name).Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[name] = id
+		result.Folders++
+	}
+	return ids, nil
+}
+
+// archiveSubscription is one of the user's feeds, found by URL on import.
+type archiveSubscription struct {
+	feedID, sourceID int64
+}
+
+// importFeeds subscribes the user to the archive's feeds they do not follow
+// yet and returns all their subscriptions by URL. Sources new to the server
+// are left unscheduled, which makes them due at once.
+func (s *AccountService) importFeeds(ctx context.Context, tx *db.Tx, userID int64, folders []models.ArchiveFolder, folderIDs map[string]int64, result *models.AccountImportResult) (map[string]archiveSubscription, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sources.url, feeds.id, feeds.source_id
+		FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	subs := map[string]archiveSubscription{}
+	for rows.Next() {
+		var url string
+		var sub archiveSubscription
+		if err := rows.Scan(&url, &sub.feedID, &sub.sourceID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		subs[url] = sub
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, folder := range folders {
+		folderID := folderIDs[strings.TrimSpace(folder.Name)]
+		if folderID == 0 {
+			continue
+		}
+		for _, feed := range folder.Feeds {
+			url := strings.TrimSpace(feed.URL)
+			if _, ok := subs[url]; ok || !validURL(url) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO sources(url, title, site_url) VALUES(?, ?, ?)
+				ON CONFLICT(url) DO NOTHING`, url, feed.SourceTitle, feed.SiteURL); err != nil {
+				return nil, err
+			}
+			var sub archiveSubscription
+			if err := tx.QueryRowContext(ctx, `SELECT id FROM sources WHERE url=?`, url).Scan(&sub.sourceID); err != nil {
+				return nil, err
+			}
+			var title interface{}
+			if feed.Title != "" {
+				title = feed.Title
+			}
+			if err := tx.QueryRowContext(ctx, `INSERT INTO feeds(user_id, folder_id, source_id, title) VALUES(?, ?, ?, ?) RETURNING id`,
+				userID, folderID, sub.sourceID, title).Scan(&sub.feedID); err != nil {
+				return nil, err
+			}
+			// As in AddFeed, entries the server already has are new to this
+			// user and go through their filters.
+			entryIDs, err := s.feedService.queryIDsTx(ctx, tx, `SELECT id FROM entries WHERE source_id=? ORDER BY id`, sub.sourceID)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.feedService.filterNewItems(ctx, tx, userID, sub.feedID, entryIDs); err != nil {
+				return nil, err
+			}
+			subs[url] = sub
+			result.Feeds++
+		}
+	}
+	return subs, nil
+}
+
+// importItems applies the archive's item state and tags to the entries
+// this server already has. Entries are shared by every subscriber of a
+// source, so they only ever come from the feed itself: archived content for
+// items the server no longer has is dropped, as are items of feeds the user
+// does not follow.
+func (s *AccountService) importItems(ctx context.Context, tx *db.Tx, userID int64, items []models.ArchiveItem, subs map[string]archiveSubscription, result *models.AccountImportResult) error {
+	for _, it := range items {
+		sub, ok := subs[strings.TrimSpace(it.FeedURL)]
+		if !ok || it.GUID == "" {
+			continue
+		}
+		var entryID int64
+		err := tx.QueryRowContext(ctx, `SELECT id FROM entries WHERE source_id=? AND guid=?`, sub.sourceID, it.GUID).Scan(&entryID)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if it.Read || it.Bookmarked || it.Hidden || it.Highlighted {
+			var bookmarkedAt interface{}
+			if it.Bookmarked {
+				bookmarkedAt = time.Now()
+				if it.BookmarkedAt != nil {
+					bookmarkedAt = *it.BookmarkedAt
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO item_state(item_id, user_id, is_read, is_bookmarked, bookmarked_at, is_hidden, is_highlighted)
+				VALUES(?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET
+					is_read = CASE WHEN excluded.is_read = 1 THEN 1 ELSE item_state.is_read END,
+					is_bookmarked = CASE WHEN excluded.is_bookmarked = 1 THEN 1 ELSE item_state.is_bookmarked END,
+					bookmarked_at = CASE WHEN item_state.is_bookmarked = 1 THEN item_state.bookmarked_at ELSE excluded.bookmarked_at END,
+					is_hidden = CASE WHEN excluded.is_hidden = 1 THEN 1 ELSE item_state.is_hidden END,
+					is_highlighted = CASE WHEN excluded.is_highlighted = 1 THEN 1 ELSE item_state.is_highlighted END`,
+				entryID, userID, boolToInt(it.Read), boolToInt(it.Bookmarked), bookmarkedAt,
+				boolToInt(it.Hidden), boolToInt(it.Highlighted)); err != nil {
+				return err
+			}
+		}
+		for _, raw := range it.Tags {
+			tag, err := normalizeTag(raw)
+			if err != nil {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO item_tags(item_id, user_id, tag) VALUES(?, ?, ?)
+				ON CONFLICT(user_id, item_id, tag) DO NOTHING`, entryID, userID, tag); err != nil {
+				return err
+			}
+		}
+		result.Items++
+	}
+	return nil
+}
+
+// importSavedSearches adds the searches whose name and query the user does
+// not have yet. Searches that no longer parse, or whose folder or feed is
+// missing, are skipped rather than widened.
+func (s *AccountService) importSavedSearches(ctx context.Context, tx *db.Tx, userID int64, searches []models.ArchiveSavedSearch, folderIDs map[string]int64, subs map[string]archiveSubscription, result *models.AccountImportResult) error {
+	rows, err := tx.QueryContext(ctx, `SELECT name, query FROM saved_searches WHERE user_id=?`, userID)
+	if err != nil {
+		return err
+	}
+	have := map[[2]string]bool{}
+	for rows.Next() {
+		var key [2]string
+		if err := rows.Scan(&key[0], &key[1]); err != nil {
+			rows.Close()
+			return err
+		}
+		have[key] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, ss := range searches {
+		key := [2]string{strings.TrimSpace(ss.Name), strings.TrimSpace(ss.Query)}
+		if key[0] == "" || have[key] || ss.WithinDays < 0 {
+			continue
+		}
+		if _, err := buildMatchQuery(key[1]); err != nil {
+			continue
+		}
+		var folderID, feedID *int64
+		if ss.Folder != "" {
+			id, ok := folderIDs[strings.TrimSpace(ss.Folder)]
+			if !ok {
+				continue
+			}
+			folderID = &id
+		}
+		if ss.FeedURL != "" {
+			sub, ok := subs[strings.TrimSpace(ss.FeedURL)]
+			if !ok {
+				continue
+			}
+			feedID = &sub.feedID
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO saved_searches(user_id, name, query, folder_id, feed_id, unread_only, within_days, digest)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+			userID, key[0], key[1], folderID, feedID, boolToInt(ss.UnreadOnly), ss.WithinDays, boolToInt(ss.Digest)); err != nil {
+			return err
+		}
+		have[key] = true
+		result.SavedSearches++
+	}
+	return nil
+}
+
+// importFilterRules adds the rules the user does not have yet; a rule is
+// the same when it matches the same way and does the same thing, whatever
+// its name. Rules this server cannot compile are skipped.
+func (s *AccountService) importFilterRules(ctx context.Context, tx *db.Tx, userID int64, rules []models.ArchiveFilterRule, result *models.AccountImportResult) error {
+	type ruleKey struct{ field, matchType, pattern, action, tag string }
+	rows, err := tx.QueryContext(ctx, `SELECT field, match_type, pattern, action, tag FROM filter_rules WHERE user_id=?`, userID)
+	if err != nil {
+		return err
+	}
+	have := map[ruleKey]bool{}
+	for rows.Next() {
+		var k ruleKey
+		if err := rows.Scan(&k.field, &k.matchType, &k.pattern, &k.action, &k.tag); err != nil {
+			rows.Close()
+			return err
+		}
+		have[k] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		compiled, err := compileFilterRule(models.FilterRule{
+			Name: r.Name, Field: r.Field, MatchType: r.MatchType, Pattern: r.Pattern,
+			Action: r.Action, Tag: r.Tag, Enabled: r.Enabled,
+		})
+		if err != nil {
+			continue
+		}
+		rule := compiled.rule
+		k := ruleKey{rule.Field, rule.MatchType, rule.Pattern, rule.Action, rule.Tag}
+		if have[k] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO filter_rules(user_id, name, field, match_type, pattern, action, tag, enabled)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+			userID, rule.Name, rule.Field, rule.MatchType, rule.Pattern, rule.Action, rule.Tag, boolToInt(rule.Enabled)); err != nil {
+			return err
+		}
+		have[k] = true
+		result.FilterRules++
+	}
+	return nil
+}
+
+// WriteAccountArchiveZip writes the archive as a zip bundle holding
+// account.json.
+func WriteAccountArchiveZip(w io.Writer, archive *models.AccountArchive) error {
+	zw := zip.NewWriter(w)
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: accountArchiveEntry, Method: zip.Deflate, Modified: archive.ExportedAt})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(archive); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadAccountArchive decodes an archive from its JSON or zip form and checks
+// this server can read its version. Neither form may hold more than
+// MaxAccountArchiveSize bytes of JSON.
+func ReadAccountArchive(r io.ReaderAt, size int64) (*models.AccountArchive, error) {
+	return readAccountArchive(r, size, MaxAccountArchiveSize)
+}
+
+func readAccountArchive(r io.ReaderAt, size, limit int64) (*models.AccountArchive, error) {
+	var body io.Reader = io.NewSectionReader(r, 0, size)
+	magic := make([]byte, 4)
+	if n, _ := r.ReadAt(magic, 0); n == len(magic) && bytes.Equal(magic, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		f, err := zr.Open(accountArchiveEntry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: no %s", accountArchiveEntry)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		size, body = info.Size(), f
+	}
+	if size > limit {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrArchiveTooLarge, accountArchiveEntry, limit)
+	}
+
+	// The size in a zip header is only what the archive claims, so the
+	// decoder is bounded as well; a longer entry fails as truncated JSON.
+	var archive models.AccountArchive
+	if err := json.NewDecoder(io.LimitReader(body, limit)).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	if archive.Format != models.AccountArchiveFormat {
+		return nil, errors.New("invalid archive: not an account export")
+	}
+	if archive.Version < 1 || archive.Version > models.AccountArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is not supported by this server (up to %d)", archive.Version, models.AccountArchiveVersion)
+	}
+	return &archive, nil
+}
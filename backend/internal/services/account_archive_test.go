@@ -0,0 +1,318 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// TestAccountArchive exports an account, round-trips it through a zip
+// bundle and merges it into a second account, including an item the server
+// does not have and a feed it has never seen.
+func TestAccountArchive(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+		accounts := NewAccountService(d, svc)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, feedID := subscribe(t, d, svc, "old@example.com", sourceID)
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", []*gofeed.Item{
+			{GUID: "a", Title: "Read me", Link: "https://example.com/a"},
+			{GUID: "b", Title: "Keep me", Link: "https://example.com/b", Content: "<p>worth keeping</p>"},
+			{GUID: "c", Title: "Untouched", Link: "https://example.com/c"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		ids := map[string]int64{}
+		items, _, err := svc.ListItems(ctx, userID, nil, nil, "", false, 10, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range items {
+			ids[it.GUID] = it.ID
+		}
+		if err := svc.MarkRead(ctx, userID, ids["a"], true); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.Bookmark(ctx, userID, ids["b"], true); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.SetItemTags(ctx, userID, ids["b"], []string{"keep"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.CreateSavedSearch(ctx, userID, models.SavedSearch{Name: "Golang", Query: "golang", FeedID: &feedID}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.CreateFilterRule(ctx, userID, models.FilterRule{
+			Name: "Ads", Field: "title", MatchType: "keyword", Pattern: "sponsored", Action: "hide", Enabled: true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		exported, err := accounts.Export(ctx, userID)
+		if err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		if len(exported.Items) != 2 {
+			t.Fatalf("exported %d items, want the read and the bookmarked one", len(exported.Items))
+		}
+		for _, it := range exported.Items {
+			if it.GUID == "a" && it.Title != "" {
+				t.Errorf("read-only item exported with content")
+			}
+			if it.GUID == "b" && (it.ContentHTML == "" || !it.Bookmarked || len(it.Tags) != 1) {
+				t.Errorf("bookmarked item exported as %+v", it)
+			}
+		}
+		exported.Folders = append(exported.Folders, models.ArchiveFolder{Name: "Tech", Feeds: []models.ArchiveFeed{
+			{URL: "https://new.example.com/feed", SourceTitle: "New"},
+		}})
+		var buf bytes.Buffer
+		if err := WriteAccountArchiveZip(&buf, exported); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := ReadAccountArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("ReadAccountArchive: %v", err)
+		}
+
+		// Entries are shared with every subscriber, so an archive must not
+		// be able to add one, nor to replace what a GUID points to.
+		archive.Items = append(archive.Items, models.ArchiveItem{
+			FeedURL: "https://example.com/feed", GUID: "forged", Title: "Injected",
+			Link: "https://evil.example/", ContentHTML: "<p>injected</p>", Bookmarked: true,
+		})
+		for i := range archive.Items {
+			if archive.Items[i].GUID == "b" {
+				archive.Items[i].ContentHTML = "<p>replaced</p>"
+			}
+		}
+
+		var newUserID int64
+		if err := d.QueryRow(`INSERT INTO users(email) VALUES('new@example.com') RETURNING id`).Scan(&newUserID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.CreateFolder(ctx, newUserID, "News"); err != nil {
+			t.Fatal(err)
+		}
+		result, err := accounts.Import(ctx, newUserID, archive)
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+		want := models.AccountImportResult{Folders: 1, Feeds: 2, Items: 2, SavedSearches: 1, FilterRules: 1}
+		if result != want {
+			t.Errorf("Import = %+v, want %+v", result, want)
+		}
+
+		bookmarks, _, err := svc.ListBookmarks(ctx, newUserID, "keep", 10, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bookmarks) != 1 || bookmarks[0].GUID != "b" || !strings.Contains(bookmarks[0].ContentHTML, "worth keeping") {
+			t.Fatalf("bookmarks after import = %+v", bookmarks)
+		}
+		var forged int
+		if err := d.QueryRow(`SELECT COUNT(*) FROM entries WHERE guid='forged'`).Scan(&forged); err != nil {
+			t.Fatal(err)
+		}
+		if forged != 0 {
+			t.Errorf("import created a shared entry from archive content")
+		}
+		items, _, err = svc.ListItems(ctx, newUserID, nil, nil, "", true, 10, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 2 || items[0].GUID == "a" || items[1].GUID == "a" {
+			t.Errorf("unread items after import = %+v, want b and c", items)
+		}
+		var paused, scheduled int
+		if err := d.QueryRow(`
+			SELECT feeds.paused, COUNT(sources.next_check_at)
+			FROM feeds JOIN sources ON sources.id = feeds.source_id
+			WHERE sources.url='https://new.example.com/feed' GROUP BY feeds.paused`).Scan(&paused, &scheduled); err != nil {
+			t.Fatalf("new source: %v", err)
+		}
+		if paused != 0 || scheduled != 0 {
+			t.Errorf("new source should be due at once")
+		}
+
+		// Importing again adds nothing.
+		again, err := accounts.Import(ctx, newUserID, archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Folders+again.Feeds+again.SavedSearches+again.FilterRules != 0 {
+			t.Errorf("second import = %+v, want nothing new", again)
+		}
+	})
+}
+
+// TestAccountArchiveMerge imports one user's archive into another who
+// shares the source and already has some of the same folders, feeds and
+// flags: duplicates are skipped, state only gains flags, and the exporting
+// user is left alone.
+func TestAccountArchiveMerge(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+		accounts := NewAccountService(d, svc)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		alice, _ := subscribe(t, d, svc, "alice@example.com", sourceID)
+		bob, _ := subscribe(t, d, svc, "bob@example.com", sourceID)
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", []*gofeed.Item{
+			{GUID: "a", Title: "A"}, {GUID: "b", Title: "B"}, {GUID: "c", Title: "C"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		ids := map[string]int64{}
+		items, _, err := svc.ListItems(ctx, alice, nil, nil, "", false, 10, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range items {
+			ids[it.GUID] = it.ID
+		}
+
+		// Alice read a and b; Bob bookmarked a and read c.
+		for _, guid := range []string{"a", "b"} {
+			if err := svc.MarkRead(ctx, alice, ids[guid], true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := svc.Bookmark(ctx, bob, ids["a"], true); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.MarkRead(ctx, bob, ids["c"], true); err != nil {
+			t.Fatal(err)
+		}
+
+		archive, err := accounts.Export(ctx, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := accounts.Import(ctx, bob, archive)
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+		if want := (models.AccountImportResult{Items: 2}); result != want {
+			t.Errorf("Import = %+v, want %+v", result, want)
+		}
+		var folders, feeds int
+		if err := d.QueryRow(`SELECT (SELECT COUNT(*) FROM folders WHERE user_id=?), (SELECT COUNT(*) FROM feeds WHERE user_id=?)`,
+			bob, bob).Scan(&folders, &feeds); err != nil {
+			t.Fatal(err)
+		}
+		if folders != 1 || feeds != 1 {
+			t.Errorf("bob has %d folders and %d feeds, want 1 and 1", folders, feeds)
+		}
+
+		state := func(userID int64) map[string]models.ItemState {
+			t.Helper()
+			items, _, err := svc.ListItems(ctx, userID, nil, nil, "", false, 10, nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := map[string]models.ItemState{}
+			for _, it := range items {
+				out[it.GUID] = it.State
+			}
+			return out
+		}
+		got := state(bob)
+		if !got["a"].IsRead || !got["a"].IsBookmarked {
+			t.Errorf("bob a = %+v, want read and still bookmarked", got["a"])
+		}
+		if !got["b"].IsRead {
+			t.Errorf("bob b = %+v, want read", got["b"])
+		}
+		if !got["c"].IsRead {
+			t.Errorf("bob c = %+v, want still read", got["c"])
+		}
+		got = state(alice)
+		if got["a"].IsBookmarked || got["c"].IsRead {
+			t.Errorf("alice changed by bob's import: %+v", got)
+		}
+	})
+}
+
+func TestReadAccountArchive(t *testing.T) {
+	read := func(data []byte, limit int64) (*models.AccountArchive, error) {
+		return readAccountArchive(bytes.NewReader(data), int64(len(data)), limit)
+	}
+	zipped := func(name, body string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(body))
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	valid := `{"format":"rss-feed-manager-account","version":1,"folders":[{"name":"News"}]}`
+
+	for name, data := range map[string][]byte{
+		"not json":        []byte(`not json`),
+		"other format":    []byte(`{"format":"something-else","version":1}`),
+		"newer version":   []byte(`{"format":"rss-feed-manager-account","version":99}`),
+		"truncated zip":   zipped(accountArchiveEntry, valid)[:40],
+		"no account.json": zipped("other.json", valid),
+		"zipped garbage":  zipped(accountArchiveEntry, "not json"),
+	} {
+		if _, err := read(data, MaxAccountArchiveSize); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"json": []byte(valid),
+		"zip":  zipped(accountArchiveEntry, valid),
+	} {
+		archive, err := read(data, MaxAccountArchiveSize)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(archive.Folders) != 1 || archive.Folders[0].Name != "News" {
+			t.Errorf("%s: archive = %+v", name, archive)
+		}
+		if _, err := read(data, 32); !errors.Is(err, ErrArchiveTooLarge) {
+			t.Errorf("%s over the limit: err = %v, want ErrArchiveTooLarge", name, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+// Personal API token scopes.
+const (
+	APITokenScopeRead      = "read"
+	APITokenScopeReadWrite = "read_write"
+)
+
+const (
+	// APITokenPrefix marks personal API tokens so they can be told apart
+	// from session tokens without a lookup.
+	APITokenPrefix = "rfm_"
+	// apiTokenUseResolution limits how often last_used_at is written.
+	apiTokenUseResolution = time.Minute
+	maxAPITokenNameLen    = 100
+)
+
+var ErrInvalidAPIToken = errors.New("invalid api token request")
+
+// hashAPIToken is how API tokens are stored; only the owner ever sees the
+// token itself.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken creates a long-lived token for scripts and other clients.
+// The returned token is only available now; the listing shows its prefix.
+func (s *AuthService) CreateAPIToken(ctx context.Context, userID int64, name, scope string) (models.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPITokenNameLen {
+		return models.APIToken{}, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidAPIToken, maxAPITokenNameLen)
+	}
+	if scope == "" {
+		scope = APITokenScopeRead
+	}
+	if scope != APITokenScopeRead && scope != APITokenScopeReadWrite {
+		return models.APIToken{}, fmt.Errorf("%w: scope must be %q or %q", ErrInvalidAPIToken, APITokenScopeRead, APITokenScopeReadWrite)
+	}
+	secret, err := generateToken(24)
+	if err != nil {
+		return models.APIToken{}, err
+	}
+	token := APITokenPrefix + secret
+	tok := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		Prefix:    token[:len(APITokenPrefix)+6],
+		CreatedAt: time.Now(),
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens(user_id, name, token_hash, prefix, scope, created_at) VALUES(?, ?, ?, ?, ?, ?) RETURNING id`,
+		userID, tok.Name, hashAPIToken(token), tok.Prefix, tok.Scope, tok.CreatedAt).Scan(&tok.ID)
+	if err != nil {
+		return models.APIToken{}, err
+	}
+	tok.Token = token
+	return tok, nil
+}
+
+// ListAPITokens lists the user's API tokens, newest first.
+func (s *AuthService) ListAPITokens(ctx context.Context, userID int64) ([]models.APIToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, prefix, scope, created_at, last_used_at
+		FROM api_tokens WHERE user_id=? ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		tok := models.APIToken{UserID: userID}
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&tok.ID, &tok.Name, &tok.Prefix, &tok.Scope, &tok.CreatedAt, &lastUsed); err != nil {
+			return nil, err
+		}
+		if lastUsed.Valid {
+			tok.LastUsedAt = &lastUsed.Time
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken deletes one of the user's API tokens.
+func (s *AuthService) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id=? AND user_id=?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ValidateAPIToken resolves an API token to its owner and records its use.
+func (s *AuthService) ValidateAPIToken(ctx context.Context, token string) (*models.User, models.APIToken, error) {
+	var (
+		user models.User
+		tok  models.APIToken
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT api_tokens.id, api_tokens.name, api_tokens.prefix, api_tokens.scope, api_tokens.created_at,
+			users.id, users.email, users.created_at
+		FROM api_tokens JOIN users ON users.id = api_tokens.user_id
+		WHERE api_tokens.token_hash=?`, hashAPIToken(token)).
+		Scan(&tok.ID, &tok.Name, &tok.Prefix, &tok.Scope, &tok.CreatedAt, &user.ID, &user.Email, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.APIToken{}, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, models.APIToken{}, err
+	}
+	tok.UserID = user.ID
+
+	now := time.Now()
+	_, _ = s.db.ExecContext(ctx, `
+		UPDATE api_tokens SET last_used_at=?
+		WHERE id=? AND (last_used_at IS NULL OR last_used_at < ?)`, now, tok.ID, now.Add(-apiTokenUseResolution))
+	tok.LastUsedAt = &now
+	return &user, tok, nil
+}
@@ -0,0 +1,16 @@
+package services
+
+import "testing"
+
+func TestHashAPIToken(t *testing.T) {
+	a := hashAPIToken(APITokenPrefix + "abc")
+	if len(a) != 64 {
+		t.Fatalf("hash length %d, expected 64", len(a))
+	}
+	if a != hashAPIToken(APITokenPrefix+"abc") {
+		t.Error("hash is not deterministic")
+	}
+	if a == hashAPIToken(APITokenPrefix+"abd") {
+		t.Error("different tokens share a hash")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"rss-feed-manager/backend/internal/db"
@@ -23,6 +24,7 @@ var (
 	ErrTooManyAttempts = errors.New("too many attempts, please try again later")
 	ErrUserNotFound   = errors.New("user not found")
 	ErrSessionExpired = errors.New("session expired")
+	ErrBadCredentials = errors.New("invalid email or password")
 )
 
 // Security constants
@@ -36,14 +38,14 @@ const (
 )
 
 type AuthService struct {
-	db                *sql.DB
+	db                *db.DB
 	mailer            mailer.Mailer
 	magicLinkExpiry   time.Duration
 	sessionExpiry     time.Duration
 	frontendURL       string
 }
 
-func NewAuthService(db *sql.DB, m mailer.Mailer) *AuthService {
+func NewAuthService(db *db.DB, m mailer.Mailer) *AuthService {
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:5173"
@@ -277,12 +279,7 @@ func (s *AuthService) VerifyOTP(ctx context.Context, email, code string) (*model
 	err = s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE email = ?`, email).Scan(&userID)
 	if err == sql.ErrNoRows {
 		// Create new user
-		result, err := s.db.ExecContext(ctx, `INSERT INTO users (email) VALUES (?)`, email)
-		if err != nil {
-			return nil, "", err
-		}
-		userID, err = result.LastInsertId()
-		if err != nil {
+		if err := s.db.QueryRowContext(ctx, `INSERT INTO users (email) VALUES (?) RETURNING id`, email).Scan(&userID); err != nil {
 			return nil, "", err
 		}
 		isNewUser = true
@@ -298,16 +295,7 @@ func (s *AuthService) VerifyOTP(ctx context.Context, email, code string) (*model
 	}
 
 	// Create session
-	sessionToken, err := generateToken(32)
-	if err != nil {
-		return nil, "", err
-	}
-	sessionExpires := time.Now().Add(s.sessionExpiry)
-
-	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES (?, ?, ?)
-	`, userID, sessionToken, sessionExpires)
+	sessionToken, err := s.createSession(ctx, userID, SessionKindWeb)
 	if err != nil {
 		return nil, "", err
 	}
@@ -373,12 +361,7 @@ func (s *AuthService) VerifyMagicLink(ctx context.Context, token string) (*model
 	isNewUser := false
 	err = s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE email = ?`, email).Scan(&userID)
 	if err == sql.ErrNoRows {
-		result, err := s.db.ExecContext(ctx, `INSERT INTO users (email) VALUES (?)`, email)
-		if err != nil {
-			return nil, "", err
-		}
-		userID, err = result.LastInsertId()
-		if err != nil {
+		if err := s.db.QueryRowContext(ctx, `INSERT INTO users (email) VALUES (?) RETURNING id`, email).Scan(&userID); err != nil {
 			return nil, "", err
 		}
 		isNewUser = true
@@ -392,16 +375,7 @@ func (s *AuthService) VerifyMagicLink(ctx context.Context, token string) (*model
 		}
 	}
 
-	sessionToken, err := generateToken(32)
-	if err != nil {
-		return nil, "", err
-	}
-	sessionExpires := time.Now().Add(s.sessionExpiry)
-
-	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES (?, ?, ?)
-	`, userID, sessionToken, sessionExpires)
+	sessionToken, err := s.createSession(ctx, userID, SessionKindWeb)
 	if err != nil {
 		return nil, "", err
 	}
@@ -415,13 +389,59 @@ func (s *AuthService) VerifyMagicLink(ctx context.Context, token string) (*model
 	return user, sessionToken, nil
 }
 
+// ClientLogin signs in API clients that send an email and password, such as
+// Google Reader clients. The password is the one set for Fever access, since
+// accounts otherwise sign in by email code. It returns a new session token.
+func (s *AuthService) ClientLogin(ctx context.Context, email, password string) (*models.User, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := s.checkRateLimit(ctx, email, "client_login"); err != nil {
+		return nil, "", err
+	}
+
+	var user models.User
+	var apiKey string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT users.id, users.email, users.created_at, fever_credentials.api_key
+		FROM users JOIN fever_credentials ON fever_credentials.user_id = users.id
+		WHERE users.email = ?
+	`, email).Scan(&user.ID, &user.Email, &user.CreatedAt, &apiKey)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", err
+	}
+	if err == sql.ErrNoRows || !secureCompare(FeverAPIKey(email, password), apiKey) {
+		s.incrementRateLimit(ctx, email, "client_login")
+		return nil, "", ErrBadCredentials
+	}
+	s.resetRateLimit(ctx, email, "client_login")
+
+	sessionToken, err := s.createSession(ctx, user.ID, SessionKindApp)
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, sessionToken, nil
+}
+
+// ValidateSession resolves a web session token to its user. Sessions expire
+// after sessionExpiry without use; each use pushes the expiry back. App
+// sessions from ClientLogin are rejected.
 func (s *AuthService) ValidateSession(ctx context.Context, token string) (*models.User, error) {
-	var userID int64
+	return s.validateSession(ctx, token, SessionKindWeb)
+}
+
+// ValidateAppSession resolves a session token from ClientLogin to its user,
+// for the Google Reader API only.
+func (s *AuthService) ValidateAppSession(ctx context.Context, token string) (*models.User, error) {
+	return s.validateSession(ctx, token, SessionKindApp)
+}
+
+func (s *AuthService) validateSession(ctx context.Context, token, kind string) (*models.User, error) {
+	var userID, sessionID int64
 	var expiresAt time.Time
+	var lastSeen sql.NullTime
 
 	err := s.db.QueryRowContext(ctx, `
-		SELECT user_id, expires_at FROM sessions WHERE token = ?
-	`, token).Scan(&userID, &expiresAt)
+		SELECT id, user_id, expires_at, last_seen_at FROM sessions WHERE token = ? AND kind = ?
+	`, token, kind).Scan(&sessionID, &userID, &expiresAt, &lastSeen)
 	if err == sql.ErrNoRows {
 		return nil, ErrInvalidToken
 	}
@@ -433,6 +453,9 @@ func (s *AuthService) ValidateSession(ctx context.Context, token string) (*model
 		_, _ = s.db.ExecContext(ctx, `DELETE FROM sessions WHERE token = ?`, token)
 		return nil, ErrSessionExpired
 	}
+	if !lastSeen.Valid || time.Since(lastSeen.Time) > sessionTouchInterval {
+		s.touchSession(ctx, sessionID)
+	}
 
 	var user models.User
 	err = s.db.QueryRowContext(ctx, `
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+// Counts returns unread and total item counts for the account and for each
+// of its folders and feeds, and the account's starred count, in one grouped
+// query over the items(feed_id) index. Hidden items are not counted.
+func (s *FeedService) Counts(ctx context.Context, userID int64) (models.Counts, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT folders.id, feeds.id, COUNT(items.id),
+			   SUM(CASE WHEN items.id IS NOT NULL AND COALESCE(item_state.is_read,0)=0 THEN 1 ELSE 0 END),
+			   SUM(CASE WHEN COALESCE(item_state.is_bookmarked,0)=1 THEN 1 ELSE 0 END)
+		FROM folders
+		LEFT JOIN feeds ON feeds.folder_id = folders.id
+		LEFT JOIN items ON items.feed_id = feeds.id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE folders.user_id=? AND COALESCE(item_state.is_hidden,0)=0
+		GROUP BY folders.id, feeds.id`, userID)
+	if err != nil {
+		return models.Counts{}, err
+	}
+	defer rows.Close()
+
+	counts := models.Counts{
+		Folders: map[int64]models.ItemCounts{},
+		Feeds:   map[int64]models.ItemCounts{},
+	}
+	for rows.Next() {
+		var (
+			folderID               int64
+			feedID                 sql.NullInt64
+			total, unread, starred int
+		)
+		if err := rows.Scan(&folderID, &feedID, &total, &unread, &starred); err != nil {
+			return models.Counts{}, err
+		}
+		folder := counts.Folders[folderID]
+		folder.Total += total
+		folder.Unread += unread
+		counts.Folders[folderID] = folder
+		if feedID.Valid {
+			counts.Feeds[feedID.Int64] = models.ItemCounts{Unread: unread, Total: total}
+		}
+		counts.Account.Total += total
+		counts.Account.Unread += unread
+		counts.Starred += starred
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// TestCounts seeds two folders of feeds plus an empty folder and checks the
+// account, starred, per-folder and per-feed numbers, that hidden items are
+// left out, and that another subscriber's state does not leak in.
+func TestCounts(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var userID int64
+		if err := d.QueryRow(`INSERT INTO users(email) VALUES(?) RETURNING id`, "reader@example.com").Scan(&userID); err != nil {
+			t.Fatal(err)
+		}
+		folder := func(name string) int64 {
+			t.Helper()
+			f, err := svc.CreateFolder(ctx, userID, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return f.ID
+		}
+		// feed subscribes the user to a new source with n entries in the
+		// given folder and returns the feed, source and entry ids.
+		feed := func(folderID int64, name string, n int) (feedID, sourceID int64, ids []int64) {
+			t.Helper()
+			if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+				"https://example.com/"+name, name).Scan(&sourceID); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.QueryRow(`INSERT INTO feeds(user_id, folder_id, source_id) VALUES(?, ?, ?) RETURNING id`,
+				userID, folderID, sourceID).Scan(&feedID); err != nil {
+				t.Fatal(err)
+			}
+			var entries []*gofeed.Item
+			for i := 0; i < n; i++ {
+				published := time.Now().Add(-time.Duration(i) * time.Hour)
+				entries = append(entries, &gofeed.Item{GUID: fmt.Sprint(name, i), Title: "Entry", PublishedParsed: &published})
+			}
+			tx, err := d.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			ids, err = svc.saveEntries(ctx, tx, sourceID, "https://example.com", entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			return feedID, sourceID, ids
+		}
+
+		news, tech, empty := folder("News"), folder("Tech"), folder("Empty")
+		worldFeed, _, world := feed(news, "world", 3)
+		localFeed, _, local := feed(news, "local", 2)
+		goFeed, goSource, golang := feed(tech, "go", 4)
+
+		for _, id := range []int64{world[0], local[0], local[1], golang[0]} {
+			if err := svc.MarkRead(ctx, userID, id, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, id := range []int64{world[0], golang[1], golang[2]} {
+			if err := svc.Bookmark(ctx, userID, id, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := d.Exec(`INSERT INTO item_state(item_id, user_id, is_hidden) VALUES(?, ?, 1)`, golang[3], userID); err != nil {
+			t.Fatal(err)
+		}
+
+		// A second subscriber to the go source reads everything.
+		otherID, _ := subscribe(t, d, svc, "other@example.com", goSource)
+		for _, id := range golang {
+			if err := svc.MarkRead(ctx, otherID, id, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		counts, err := svc.Counts(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (models.ItemCounts{Unread: 4, Total: 8}); counts.Account != want {
+			t.Errorf("account = %+v, want %+v", counts.Account, want)
+		}
+		if counts.Starred != 3 {
+			t.Errorf("starred = %d, want 3", counts.Starred)
+		}
+		for name, tc := range map[string]struct {
+			got, want models.ItemCounts
+		}{
+			"news folder": {counts.Folders[news], models.ItemCounts{Unread: 2, Total: 5}},
+			"tech folder": {counts.Folders[tech], models.ItemCounts{Unread: 2, Total: 3}},
+			"empty":       {counts.Folders[empty], models.ItemCounts{}},
+			"world feed":  {counts.Feeds[worldFeed], models.ItemCounts{Unread: 2, Total: 3}},
+			"local feed":  {counts.Feeds[localFeed], models.ItemCounts{Unread: 0, Total: 2}},
+			"go feed":     {counts.Feeds[goFeed], models.ItemCounts{Unread: 2, Total: 3}},
+		} {
+			if tc.got != tc.want {
+				t.Errorf("%s = %+v, want %+v", name, tc.got, tc.want)
+			}
+		}
+		if _, ok := counts.Folders[empty]; !ok {
+			t.Error("empty folder missing from counts")
+		}
+
+		counts, err = svc.Counts(ctx, otherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (models.ItemCounts{Unread: 0, Total: 4}); counts.Account != want || counts.Starred != 0 {
+			t.Errorf("other account = %+v, starred %d; want %+v, none starred", counts.Account, counts.Starred, want)
+		}
+	})
+}
@@ -3,20 +3,29 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
+	"rss-feed-manager/backend/internal/db"
 	"rss-feed-manager/backend/internal/mailer"
 )
 
 type DigestService struct {
-	db     *sql.DB
-	mailer mailer.Mailer
+	db          *db.DB
+	mailer      mailer.Mailer
+	feedService *FeedService
 }
 
-func NewDigestService(db *sql.DB, mailer mailer.Mailer) *DigestService {
-	return &DigestService{db: db, mailer: mailer}
+func NewDigestService(db *db.DB, mailer mailer.Mailer, feedService *FeedService) *DigestService {
+	return &DigestService{db: db, mailer: mailer, feedService: feedService}
+}
+
+type digestLine struct {
+	title, link string
+	published   *time.Time
 }
 
 func (d *DigestService) SendDigest(ctx context.Context, userID int64, interval time.Duration) error {
@@ -30,28 +39,17 @@ func (d *DigestService) SendDigest(ctx context.Context, userID int64, interval t
 		since = lastSent.Time
 	}
 
-	rows, err := d.db.QueryContext(ctx, `
-		SELECT title, link, published_at FROM items
-		WHERE user_id=? AND created_at>?
-		ORDER BY created_at DESC
-		LIMIT 50`, userID, since)
+	entries, err := d.digestEntries(ctx, userID, since)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
 	var lines []string
-	for rows.Next() {
-		var title, link string
-		var published sql.NullTime
-		if err := rows.Scan(&title, &link, &published); err != nil {
-			return err
-		}
+	for _, e := range entries {
 		dateStr := ""
-		if published.Valid {
-			dateStr = published.Time.Format(time.RFC822)
+		if e.published != nil {
+			dateStr = e.published.Format(time.RFC822)
 		}
-		lines = append(lines, fmt.Sprintf("- %s (%s) %s", title, dateStr, link))
+		lines = append(lines, fmt.Sprintf("- %s (%s) %s", e.title, dateStr, e.link))
 	}
 	if len(lines) == 0 {
 		return nil
@@ -64,3 +62,70 @@ func (d *DigestService) SendDigest(ctx context.Context, userID int64, interval t
 	_, err = d.db.ExecContext(ctx, `UPDATE users SET digest_last_sent_at=? WHERE id=?`, time.Now(), userID)
 	return err
 }
+
+// digestEntries returns the items for the digest: the matches of the user's
+// saved searches marked for the digest, or every new item if there are none.
+func (d *DigestService) digestEntries(ctx context.Context, userID int64, since time.Time) ([]digestLine, error) {
+	searches, err := d.feedService.ListSavedSearches(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var entries []digestLine
+	seen := map[int64]bool{}
+	usedSearch := false
+	for _, ss := range searches {
+		if !ss.Digest {
+			continue
+		}
+		usedSearch = true
+		sq := savedSearchQuery(ss, time.Now())
+		sq.AddedSince = &since
+		sq.Sort = "latest"
+		sq.Limit = 50
+		hits, _, err := d.feedService.SearchItems(ctx, userID, sq)
+		if errors.Is(err, ErrSearchUnavailable) {
+			// Without a search index, send every new item instead.
+			log.Printf("digest for user %d: %v; sending all new items", userID, err)
+			entries, usedSearch = nil, false
+			break
+		}
+		if err != nil {
+			log.Printf("digest for user %d: saved search %q: %v", userID, ss.Name, err)
+			continue
+		}
+		for _, hit := range hits {
+			if !seen[hit.ID] {
+				seen[hit.ID] = true
+				entries = append(entries, digestLine{title: hit.Title, link: hit.Link, published: hit.PublishedAt})
+			}
+		}
+	}
+	if usedSearch {
+		return entries, nil
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT title, link, published_at FROM items
+		WHERE user_id=? AND created_at>?
+		  AND id NOT IN (SELECT item_id FROM item_state WHERE user_id=? AND is_hidden=1)
+		ORDER BY created_at DESC
+		LIMIT 50`, userID, since, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var title, link string
+		var published sql.NullTime
+		if err := rows.Scan(&title, &link, &published); err != nil {
+			return nil, err
+		}
+		e := digestLine{title: title, link: link}
+		if published.Valid {
+			e.published = &published.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to clients.
+const (
+	EventItemsNew        = "items.new"        // a refresh stored new items
+	EventItemState       = "item.state"       // an item was marked read/unread or (un)bookmarked
+	EventItemsRead       = "items.read"       // many items were marked read at once
+	EventRefreshStarted  = "refresh.started"  // a manual refresh run began
+	EventRefreshProgress = "refresh.progress" // a feed in the run finished
+	EventRefreshFinished = "refresh.finished" // the run ended
+)
+
+// eventBufferSize is how many events a subscriber may fall behind before
+// further events are dropped for it.
+const eventBufferSize = 64
+
+// streamTicketTTL is how long a stream ticket may wait to be redeemed.
+const streamTicketTTL = 30 * time.Second
+
+// Event is a change pushed to a user's open clients. Data is encoded as
+// JSON.
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// EventBus fans events out to each user's subscribers within this process.
+// Publishing never blocks: slow subscribers miss events rather than stall
+// refreshes.
+type EventBus struct {
+	mu      sync.Mutex
+	subs    map[int64]map[chan Event]struct{}
+	tickets map[string]streamTicket
+}
+
+// streamTicket lets one event stream open without a credential in its URL.
+type streamTicket struct {
+	userID  int64
+	expires time.Time
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int64]map[chan Event]struct{}), tickets: make(map[string]streamTicket)}
+}
+
+// IssueTicket returns a single-use ticket that opens the user's event
+// stream within streamTicketTTL. EventSource cannot send headers, and a
+// ticket in the URL is harmless once used, unlike a session token.
+func (b *EventBus) IssueTicket(userID int64) (string, error) {
+	ticket, err := generateToken(24)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for t, st := range b.tickets {
+		if now.After(st.expires) {
+			delete(b.tickets, t)
+		}
+	}
+	b.tickets[ticket] = streamTicket{userID: userID, expires: now.Add(streamTicketTTL)}
+	return ticket, nil
+}
+
+// RedeemTicket consumes a ticket and returns the user it was issued to.
+func (b *EventBus) RedeemTicket(ticket string) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.tickets[ticket]
+	if !ok {
+		return 0, false
+	}
+	delete(b.tickets, ticket)
+	if time.Now().After(st.expires) {
+		return 0, false
+	}
+	return st.userID, true
+}
+
+// Subscribe returns a channel of the user's events and a function that
+// unsubscribes and closes it.
+func (b *EventBus) Subscribe(userID int64) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan Event]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event to every subscriber of the user.
+func (b *EventBus) Publish(userID int64, ev Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[userID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	a, unsubA := bus.Subscribe(1)
+	b, unsubB := bus.Subscribe(1)
+	other, unsubOther := bus.Subscribe(2)
+	defer unsubOther()
+
+	bus.Publish(1, Event{Type: EventItemsNew})
+	for name, ch := range map[string]<-chan Event{"a": a, "b": b} {
+		select {
+		case ev := <-ch:
+			if ev.Type != EventItemsNew {
+				t.Errorf("%s got %q, expected %q", name, ev.Type, EventItemsNew)
+			}
+		default:
+			t.Errorf("%s got no event", name)
+		}
+	}
+	select {
+	case ev := <-other:
+		t.Errorf("other user got %q", ev.Type)
+	default:
+	}
+
+	unsubA()
+	unsubA() // idempotent
+	if _, ok := <-a; ok {
+		t.Error("channel still open after unsubscribe")
+	}
+
+	// A full subscriber does not block publishing.
+	for i := 0; i < eventBufferSize+10; i++ {
+		bus.Publish(1, Event{Type: EventItemState})
+	}
+	if got := len(b); got != eventBufferSize {
+		t.Errorf("buffered %d events, expected %d", got, eventBufferSize)
+	}
+	unsubB()
+	if len(bus.subs) != 1 {
+		t.Errorf("subscriptions left: %d, expected 1", len(bus.subs))
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(1, Event{Type: EventItemsNew})
+}
+
+func TestStreamTickets(t *testing.T) {
+	bus := NewEventBus()
+	ticket, err := bus.IssueTicket(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, ok := bus.RedeemTicket(ticket); !ok || userID != 7 {
+		t.Fatalf("redeem = %d, %v; want 7, true", userID, ok)
+	}
+	if _, ok := bus.RedeemTicket(ticket); ok {
+		t.Error("ticket redeemed twice")
+	}
+
+	expired, err := bus.IssueTicket(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.tickets[expired] = streamTicket{userID: 7, expires: time.Now().Add(-time.Second)}
+	if _, ok := bus.RedeemTicket(expired); ok {
+		t.Error("expired ticket redeemed")
+	}
+	if _, ok := bus.RedeemTicket(""); ok {
+		t.Error("empty ticket redeemed")
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"rss-feed-manager/backend/internal/feeds"
+	"rss-feed-manager/backend/internal/models"
+)
+
+const (
+	// maxConsecutiveFailures is how many failed fetches in a row pause a feed.
+	// With exponential backoff this is several days of continuous failure.
+	maxConsecutiveFailures = 10
+	// maxFeedErrorsKept bounds the per-feed error history.
+	maxFeedErrorsKept = 20
+)
+
+// recordFetchSuccess clears the source's failure state. Subscriptions that
+// were paused stay paused until their user resumes or refreshes them.
+func (s *FeedService) recordFetchSuccess(ctx context.Context, q dbtx, sourceID int64, status int) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE sources SET last_success_at=?, last_http_status=?, consecutive_failures=0
+		WHERE id=?`, time.Now(), nullableStatus(status), sourceID)
+	return err
+}
+
+// recordFetchFailure bumps the source's failure count, stores the error in
+// its history, backs off its next check and pauses every subscription to it
+// once it looks dead. The count is the source's, so a user who resumes gets
+// another maxConsecutiveFailures attempts before being paused again.
+// A 410 Gone pauses the feed immediately; a 429/503 with Retry-After is
+// retried when the server asked and never counts towards pausing.
+// Cancellation by the caller is not the feed's fault and is not recorded.
+func (s *FeedService) recordFetchFailure(ctx context.Context, sourceID int64, fetchErr error) error {
+	if errors.Is(fetchErr, context.Canceled) {
+		return nil
+	}
+	// The refresh context may already have timed out; record regardless.
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	status := feeds.StatusCode(fetchErr)
+	httpErr, _ := feeds.AsHTTPError(fetchErr)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sources SET consecutive_failures=consecutive_failures+1, last_error=?, last_error_at=?,
+			last_http_status=?, last_checked_at=?
+		WHERE id=?`, fetchErr.Error(), now, nullableStatus(status), now, sourceID); err != nil {
+		return err
+	}
+	var failures int
+	if err := tx.QueryRowContext(ctx, `SELECT consecutive_failures FROM sources WHERE id=?`, sourceID).Scan(&failures); err != nil {
+		return err
+	}
+	next := now.Add(feeds.FailureBackoff(s.pollPolicy, failures))
+	pause := failures%maxConsecutiveFailures == 0
+	if httpErr != nil {
+		switch {
+		case httpErr.Gone():
+			pause = true
+		case httpErr.Throttled():
+			pause = false
+			if httpErr.RetryAfter > 0 {
+				next = now.Add(httpErr.RetryAfter)
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE sources SET next_check_at=? WHERE id=?`, next, sourceID); err != nil {
+		return err
+	}
+	if pause {
+		if _, err := tx.ExecContext(ctx, `UPDATE feeds SET paused=1 WHERE source_id=?`, sourceID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO feed_errors(source_id, http_status, message, occurred_at) VALUES(?, ?, ?, ?)`,
+		sourceID, nullableStatus(status), fetchErr.Error(), now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM feed_errors
+		WHERE source_id=? AND id NOT IN (
+			SELECT id FROM feed_errors WHERE source_id=? ORDER BY id DESC LIMIT ?
+		)`, sourceID, sourceID, maxFeedErrorsKept); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyPermanentRedirect points the source at the URL it permanently moved
+// to, unless another source is already fetched from that URL.
+func (s *FeedService) applyPermanentRedirect(ctx context.Context, q dbtx, sourceID int64, newURL string) error {
+	if newURL == "" {
+		return nil
+	}
+	res, err := q.ExecContext(ctx, `
+		UPDATE sources SET url=?
+		WHERE id=? AND NOT EXISTS (SELECT 1 FROM sources WHERE url=?)`,
+		newURL, sourceID, newURL)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("source %d moved permanently to %s", sourceID, newURL)
+	}
+	return nil
+}
+
+// ListFeedErrors returns the feed's recorded fetch failures, newest first.
+func (s *FeedService) ListFeedErrors(ctx context.Context, userID, feedID int64) ([]models.FeedError, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT feed_errors.id, feed_errors.http_status, feed_errors.message, feed_errors.occurred_at
+		FROM feed_errors
+		JOIN feeds ON feeds.source_id = feed_errors.source_id
+		WHERE feeds.id=? AND feeds.user_id=?
+		ORDER BY feed_errors.id DESC`, feedID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.FeedError{}
+	for rows.Next() {
+		var fe models.FeedError
+		var status sql.NullInt64
+		if err := rows.Scan(&fe.ID, &status, &fe.Message, &fe.OccurredAt); err != nil {
+			return nil, err
+		}
+		fe.FeedID = feedID
+		fe.HTTPStatus = int(status.Int64)
+		list = append(list, fe)
+	}
+	return list, rows.Err()
+}
+
+// ResumeFeed unpauses the user's subscription and makes its source due on
+// the next scheduler tick. Other subscribers' pauses and the source's
+// failure history are left alone.
+func (s *FeedService) ResumeFeed(ctx context.Context, userID, feedID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `UPDATE feeds SET paused=0 WHERE id=? AND user_id=?`, feedID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("feed not found")
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sources SET next_check_at=NULL
+		WHERE id = (SELECT source_id FROM feeds WHERE id=?)`, feedID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullableStatus(status int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(status), Valid: status > 0}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+	"rss-feed-manager/backend/internal/feeds"
+)
+
+// healthFixture is a source with two subscriptions for the feed health tests.
+type healthFixture struct {
+	t          *testing.T
+	d          *db.DB
+	svc        *FeedService
+	sourceID   int64
+	firstUser  int64
+	firstFeed  int64
+	secondFeed int64
+}
+
+// newHealthFixture creates the source and its subscribers; name keeps
+// several fixtures in one database apart.
+func newHealthFixture(t *testing.T, d *db.DB, name string) *healthFixture {
+	t.Helper()
+	f := &healthFixture{t: t, d: d, svc: NewFeedService(d, nil)}
+	if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+		"https://example.com/"+name, "Example").Scan(&f.sourceID); err != nil {
+		t.Fatal(err)
+	}
+	f.firstUser, f.firstFeed = subscribe(t, d, f.svc, name+"-first@example.com", f.sourceID)
+	_, f.secondFeed = subscribe(t, d, f.svc, name+"-second@example.com", f.sourceID)
+	return f
+}
+
+// fail records n failed fetches with err.
+func (f *healthFixture) fail(n int, err error) {
+	f.t.Helper()
+	for i := 0; i < n; i++ {
+		if err := f.svc.recordFetchFailure(context.Background(), f.sourceID, err); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+// paused reports whether the first and second subscriptions are paused.
+func (f *healthFixture) paused() (first, second bool) {
+	f.t.Helper()
+	for id, p := range map[int64]*bool{f.firstFeed: &first, f.secondFeed: &second} {
+		if err := f.d.QueryRow(`SELECT paused FROM feeds WHERE id=?`, id).Scan(p); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	return first, second
+}
+
+// errorCount returns the number of feed_errors rows kept for the source.
+func (f *healthFixture) errorCount() int {
+	f.t.Helper()
+	var n int
+	if err := f.d.QueryRow(`SELECT COUNT(*) FROM feed_errors WHERE source_id=?`, f.sourceID).Scan(&n); err != nil {
+		f.t.Fatal(err)
+	}
+	return n
+}
+
+// TestAutoPause checks that every maxConsecutiveFailures-th failure pauses
+// all subscriptions, and that resuming gives the feed a fresh run of
+// attempts before the next pause.
+func TestAutoPause(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		f := newHealthFixture(t, d, "feed")
+
+		f.fail(maxConsecutiveFailures-1, errors.New("connection refused"))
+		if first, second := f.paused(); first || second {
+			t.Fatalf("paused after %d failures", maxConsecutiveFailures-1)
+		}
+		f.fail(1, errors.New("connection refused"))
+		if first, second := f.paused(); !first || !second {
+			t.Fatalf("after %d failures: paused = %v, %v, want both", maxConsecutiveFailures, first, second)
+		}
+
+		if err := f.svc.ResumeFeed(context.Background(), f.firstUser, f.firstFeed); err != nil {
+			t.Fatal(err)
+		}
+		var next *time.Time
+		if err := d.QueryRow(`SELECT next_check_at FROM sources WHERE id=?`, f.sourceID).Scan(&next); err != nil {
+			t.Fatal(err)
+		}
+		if next != nil {
+			t.Errorf("next_check_at after resume = %v, want NULL", next)
+		}
+		if first, second := f.paused(); first || !second {
+			t.Fatalf("after resume: paused = %v, %v, want only the second", first, second)
+		}
+
+		f.fail(maxConsecutiveFailures-1, errors.New("connection refused"))
+		if first, _ := f.paused(); first {
+			t.Fatal("resumed feed paused before another full run of failures")
+		}
+		f.fail(1, errors.New("connection refused"))
+		if first, _ := f.paused(); !first {
+			t.Fatalf("resumed feed not paused after %d more failures", maxConsecutiveFailures)
+		}
+	})
+}
+
+// TestFailureStatus checks that 410 Gone pauses at once and that 429/503
+// never pause and are retried when Retry-After asks.
+func TestFailureStatus(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		gone := newHealthFixture(t, d, "gone")
+		gone.fail(1, fmt.Errorf("fetch: %w", &feeds.HTTPError{StatusCode: http.StatusGone}))
+		if first, second := gone.paused(); !first || !second {
+			t.Errorf("after 410: paused = %v, %v, want both", first, second)
+		}
+
+		for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+			t.Run(http.StatusText(status), func(t *testing.T) {
+				f := newHealthFixture(t, d, fmt.Sprint(status))
+				f.fail(2*maxConsecutiveFailures, &feeds.HTTPError{StatusCode: status})
+				if first, second := f.paused(); first || second {
+					t.Errorf("%d throttled responses paused the feed", 2*maxConsecutiveFailures)
+				}
+
+				before := time.Now()
+				f.fail(1, &feeds.HTTPError{StatusCode: status, RetryAfter: 2 * time.Hour})
+				var next time.Time
+				if err := d.QueryRow(`SELECT next_check_at FROM sources WHERE id=?`, f.sourceID).Scan(&next); err != nil {
+					t.Fatal(err)
+				}
+				if want := before.Add(2 * time.Hour); next.Before(want.Add(-time.Second)) || next.After(want.Add(time.Minute)) {
+					t.Errorf("next_check_at = %v, want about %v", next, want)
+				}
+			})
+		}
+	})
+}
+
+// TestFeedErrorHistory checks that the error history keeps only the newest
+// maxFeedErrorsKept entries and that cancellation is not recorded.
+func TestFeedErrorHistory(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		f := newHealthFixture(t, d, "feed")
+
+		f.fail(1, context.Canceled)
+		if n := f.errorCount(); n != 0 {
+			t.Errorf("cancelled fetch recorded %d errors", n)
+		}
+
+		for i := 0; i < maxFeedErrorsKept+5; i++ {
+			f.fail(1, fmt.Errorf("failure %d", i))
+		}
+		if n := f.errorCount(); n != maxFeedErrorsKept {
+			t.Fatalf("feed_errors rows = %d, want %d", n, maxFeedErrorsKept)
+		}
+		errs, err := f.svc.ListFeedErrors(context.Background(), f.firstUser, f.firstFeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != maxFeedErrorsKept || errs[0].Message != fmt.Sprintf("failure %d", maxFeedErrorsKept+4) {
+			t.Errorf("ListFeedErrors = %d errors, newest %+v", len(errs), errs[0])
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/mmcdole/gofeed"
 	ext "github.com/mmcdole/gofeed/extensions"
 
+	"rss-feed-manager/backend/internal/db"
 	"rss-feed-manager/backend/internal/feeds"
 	"rss-feed-manager/backend/internal/models"
 )
@@ -58,12 +60,39 @@ func itemSortTimestamp(item models.Item) int64 {
 }
 
 type FeedService struct {
-	db      *sql.DB
-	fetcher *feeds.Fetcher
+	db                 *db.DB
+	fetcher            *feeds.Fetcher
+	pollPolicy         feeds.PollPolicy
+	refreshConcurrency int
+	events             *EventBus
+	jobs               *refreshJobs
 }
 
-func NewFeedService(db *sql.DB, fetcher *feeds.Fetcher) *FeedService {
-	return &FeedService{db: db, fetcher: fetcher}
+// dbtx is satisfied by both *db.DB and *db.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func NewFeedService(db *db.DB, fetcher *feeds.Fetcher) *FeedService {
+	return &FeedService{
+		db:                 db,
+		fetcher:            fetcher,
+		pollPolicy:         feeds.DefaultPollPolicy(),
+		refreshConcurrency: defaultRefreshConcurrency,
+		jobs:               newRefreshJobs(),
+	}
+}
+
+// SetPollPolicy overrides the bounds used to schedule each feed's next check.
+func (s *FeedService) SetPollPolicy(policy feeds.PollPolicy) {
+	s.pollPolicy = policy
+}
+
+// SetEventBus makes the service publish item and refresh events to bus.
+func (s *FeedService) SetEventBus(bus *EventBus) {
+	s.events = bus
 }
 
 // GetRetentionDays returns the user's item retention setting in days.
@@ -106,12 +135,26 @@ func (s *FeedService) ListFolders(ctx context.Context, userID int64) ([]models.F
 		folders = append(folders, f)
 	}
 
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	counts, err := s.Counts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 	for i := range folders {
 		feeds, err := s.listFeedsForFolder(ctx, userID, folders[i].ID)
 		if err != nil {
 			return nil, err
 		}
+		for j := range feeds {
+			c := counts.Feeds[feeds[j].ID]
+			feeds[j].UnreadCount, feeds[j].TotalCount = c.Unread, c.Total
+		}
 		folders[i].Feeds = feeds
+		c := counts.Folders[folders[i].ID]
+		folders[i].UnreadCount, folders[i].TotalCount = c.Unread, c.Total
 	}
 	return folders, nil
 }
@@ -130,47 +173,72 @@ func (s *FeedService) GetFirstFolder(ctx context.Context, userID int64) (*models
 	return &f, nil
 }
 
+// feedColumns selects a subscription with its source's fetch state; scanFeed
+// reads the row.
+const feedColumns = `
+	SELECT feeds.id, feeds.user_id, feeds.folder_id, sources.url, COALESCE(feeds.title, sources.title), sources.site_url,
+		COALESCE(sources.etag, ''), COALESCE(sources.last_modified, ''), sources.last_checked_at, sources.next_check_at,
+		sources.last_success_at, COALESCE(sources.last_error, ''), sources.last_error_at, COALESCE(sources.last_http_status, 0),
+		sources.consecutive_failures, feeds.paused, feeds.created_at
+	FROM feeds
+	JOIN sources ON sources.id = feeds.source_id`
+
+func scanFeed(row interface{ Scan(...interface{}) error }) (models.Feed, error) {
+	var f models.Feed
+	var lastChecked, nextCheck, lastSuccess, lastErrorAt sql.NullTime
+	if err := row.Scan(&f.ID, &f.UserID, &f.FolderID, &f.URL, &f.Title, &f.SiteURL, &f.Etag, &f.LastModified, &lastChecked, &nextCheck,
+		&lastSuccess, &f.LastError, &lastErrorAt, &f.LastHTTPStatus, &f.ConsecutiveFailures, &f.Paused,
+		&f.CreatedAt); err != nil {
+		return models.Feed{}, err
+	}
+	if lastChecked.Valid {
+		f.LastCheckedAt = &lastChecked.Time
+	}
+	if nextCheck.Valid {
+		f.NextCheckAt = &nextCheck.Time
+	}
+	if lastSuccess.Valid {
+		f.LastSuccessAt = &lastSuccess.Time
+	}
+	if lastErrorAt.Valid {
+		f.LastErrorAt = &lastErrorAt.Time
+	}
+	return f, nil
+}
+
 func (s *FeedService) listFeedsForFolder(ctx context.Context, userID, folderID int64) ([]models.Feed, error) {
-	rows, err := s.db.QueryContext(
-		ctx,
-		`SELECT id, url, title, site_url, COALESCE(etag, ''), COALESCE(last_modified, ''), last_checked_at, created_at
-		 FROM feeds
-		 WHERE user_id=? AND folder_id=?
-		 ORDER BY created_at`,
-		userID,
-		folderID,
-	)
+	rows, err := s.db.QueryContext(ctx, feedColumns+`
+		WHERE feeds.user_id=? AND feeds.folder_id=?
+		ORDER BY feeds.created_at`, userID, folderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var feedsList []models.Feed
 	for rows.Next() {
-		var f models.Feed
-		var lastChecked sql.NullTime
-		if err := rows.Scan(&f.ID, &f.URL, &f.Title, &f.SiteURL, &f.Etag, &f.LastModified, &lastChecked, &f.CreatedAt); err != nil {
+		f, err := scanFeed(rows)
+		if err != nil {
 			return nil, err
 		}
-		f.UserID = userID
-		f.FolderID = folderID
-		if lastChecked.Valid {
-			f.LastCheckedAt = &lastChecked.Time
-		}
 		feedsList = append(feedsList, f)
 	}
 	return feedsList, nil
 }
 
+func (s *FeedService) getFeed(ctx context.Context, userID, feedID int64) (models.Feed, error) {
+	return scanFeed(s.db.QueryRowContext(ctx, feedColumns+` WHERE feeds.id=? AND feeds.user_id=?`, feedID, userID))
+}
+
 func (s *FeedService) CreateFolder(ctx context.Context, userID int64, name string) (models.Folder, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return models.Folder{}, errors.New("name required")
 	}
-	res, err := s.db.ExecContext(ctx, `INSERT INTO folders(user_id, name) VALUES(?, ?)`, userID, name)
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO folders(user_id, name) VALUES(?, ?) RETURNING id`, userID, name).Scan(&id)
 	if err != nil {
 		return models.Folder{}, err
 	}
-	id, _ := res.LastInsertId()
 	return models.Folder{ID: id, UserID: userID, Name: name, CreatedAt: time.Now()}, nil
 }
 
@@ -180,8 +248,28 @@ func (s *FeedService) RenameFolder(ctx context.Context, userID, folderID int64,
 }
 
 func (s *FeedService) DeleteFolder(ctx context.Context, userID, folderID int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM folders WHERE id=? AND user_id=?`, folderID, userID)
-	return err
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM folders WHERE id=? AND user_id=?`, folderID, userID); err != nil {
+		return err
+	}
+	if err := unsubscribeCleanup(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AmbiguousFeedError is returned by AddFeed when the URL is a page that
+// advertises several feeds; the caller should pick one of Candidates.
+type AmbiguousFeedError struct {
+	Candidates []feeds.FeedLink
+}
+
+func (e *AmbiguousFeedError) Error() string {
+	return fmt.Sprintf("page advertises %d feeds, choose one", len(e.Candidates))
 }
 
 func (s *FeedService) AddFeed(ctx context.Context, userID, folderID int64, feedURL string) (models.Feed, error) {
@@ -198,9 +286,27 @@ func (s *FeedService) AddFeed(ctx context.Context, userID, folderID int64, feedU
 		return models.Feed{}, errors.New("folder not found")
 	}
 
-	result, _, err := s.fetcher.Fetch(ctx, feedURL, "", "")
+	// A URL someone already subscribes to is not fetched again; the new
+	// subscriber sees the entries stored so far.
+	sourceID, err := s.sourceByURL(ctx, feedURL)
 	if err != nil {
-		return models.Feed{}, fmt.Errorf("fetch feed: %w", err)
+		return models.Feed{}, err
+	}
+	var result *feeds.FetchResult
+	if sourceID == 0 {
+		result, _, err = s.fetcher.Fetch(ctx, feedURL, "", "")
+		if err != nil {
+			return models.Feed{}, fmt.Errorf("fetch feed: %w", err)
+		}
+		if feeds.AmbiguousFeedLinks(result.Candidates) {
+			return models.Feed{}, &AmbiguousFeedError{Candidates: result.Candidates}
+		}
+		if canonical := result.CanonicalURL(); canonical != "" {
+			feedURL = canonical
+		}
+		if sourceID, err = s.sourceByURL(ctx, feedURL); err != nil {
+			return models.Feed{}, err
+		}
 	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -209,145 +315,387 @@ func (s *FeedService) AddFeed(ctx context.Context, userID, folderID int64, feedU
 	}
 	defer tx.Rollback()
 
-	res, err := tx.ExecContext(ctx, `INSERT INTO feeds(user_id, folder_id, url, title, site_url, etag, last_modified, last_checked_at) 
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, folderID, feedURL, result.Title, result.SiteURL, result.Etag, result.LastModified, time.Now())
+	if sourceID == 0 {
+		if sourceID, err = s.createSource(ctx, tx, feedURL, result); err != nil {
+			return models.Feed{}, err
+		}
+	}
+	var feedID int64
+	if err := tx.QueryRowContext(ctx, `INSERT INTO feeds(user_id, folder_id, source_id) VALUES(?, ?, ?) RETURNING id`,
+		userID, folderID, sourceID).Scan(&feedID); err != nil {
+		return models.Feed{}, err
+	}
+	entryIDs, err := s.queryIDsTx(ctx, tx, `SELECT id FROM entries WHERE source_id=? ORDER BY id`, sourceID)
 	if err != nil {
 		return models.Feed{}, err
 	}
-	feedID, _ := res.LastInsertId()
-	feed := models.Feed{
-		ID:            feedID,
-		UserID:        userID,
-		FolderID:      folderID,
-		URL:           feedURL,
-		Title:         result.Title,
-		SiteURL:       result.SiteURL,
-		Etag:          result.Etag,
-		LastModified:  result.LastModified,
-		LastCheckedAt: ptrTime(time.Now()),
-		CreatedAt:     time.Now(),
-	}
-
-	if err := s.saveItems(ctx, tx, userID, feedID, feedURL, result.Items); err != nil {
+	if err := s.filterNewItems(ctx, tx, userID, feedID, entryIDs); err != nil {
 		return models.Feed{}, err
 	}
-
 	if err := tx.Commit(); err != nil {
 		return models.Feed{}, err
 	}
-	return feed, nil
+	return s.getFeed(ctx, userID, feedID)
+}
+
+// sourceByURL returns the id of the source fetched from the URL, or 0.
+func (s *FeedService) sourceByURL(ctx context.Context, feedURL string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM sources WHERE url=?`, feedURL).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// createSource stores a newly fetched source and its entries. If another
+// request created the source meanwhile, its entries are merged into that one.
+func (s *FeedService) createSource(ctx context.Context, tx *db.Tx, feedURL string, result *feeds.FetchResult) (int64, error) {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO sources(url, title, site_url, etag, last_modified, last_checked_at) VALUES(?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO NOTHING`,
+		feedURL, result.Title, result.SiteURL, result.Etag, result.LastModified, time.Now()); err != nil {
+		return 0, err
+	}
+	var sourceID int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM sources WHERE url=?`, feedURL).Scan(&sourceID); err != nil {
+		return 0, err
+	}
+	if _, err := s.saveEntries(ctx, tx, sourceID, feedURL, result.Items); err != nil {
+		return 0, err
+	}
+	if _, err := s.scheduleNextCheck(ctx, tx, sourceID, result.Hints); err != nil {
+		return 0, err
+	}
+	if err := s.recordFetchSuccess(ctx, tx, sourceID, result.StatusCode); err != nil {
+		return 0, err
+	}
+	return sourceID, nil
+}
+
+// DiscoverFeeds resolves a page or feed URL into validated feed candidates.
+func (s *FeedService) DiscoverFeeds(ctx context.Context, pageURL string) ([]feeds.Candidate, error) {
+	return s.fetcher.Discover(ctx, pageURL)
 }
 
 func (s *FeedService) DeleteFeed(ctx context.Context, userID, feedID int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM feeds WHERE id=? AND user_id=?`, feedID, userID)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM feeds WHERE id=? AND user_id=?`, feedID, userID); err != nil {
+		return err
+	}
+	if err := unsubscribeCleanup(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// unsubscribeCleanup runs after the user dropped subscriptions: their read
+// state and tags on entries of sources they no longer subscribe to go, and
+// so do sources nobody subscribes to any more, with their entries. State on
+// entries merely hidden by retention stays, for when retention grows again.
+func unsubscribeCleanup(ctx context.Context, tx *db.Tx, userID int64) error {
+	for _, table := range []string{"item_state", "item_tags"} {
+		stmt := fmt.Sprintf(`
+			DELETE FROM %[1]s WHERE user_id=? AND NOT EXISTS (
+				SELECT 1 FROM entries JOIN feeds ON feeds.source_id = entries.source_id
+				WHERE entries.id = %[1]s.item_id AND feeds.user_id = %[1]s.user_id)`, table)
+		if _, err := tx.ExecContext(ctx, stmt, userID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM sources WHERE id NOT IN (SELECT source_id FROM feeds)`)
 	return err
 }
 
 func (s *FeedService) RefreshFeed(ctx context.Context, userID, feedID int64) (int, error) {
-	var feed models.Feed
-	err := s.db.QueryRowContext(ctx, `SELECT url, COALESCE(etag, ''), COALESCE(last_modified, '') FROM feeds WHERE id=? AND user_id=?`, feedID, userID).
-		Scan(&feed.URL, &feed.Etag, &feed.LastModified)
+	fetched, _, err := s.refreshFeed(ctx, userID, feedID)
+	return fetched, err
+}
+
+// refreshFeed fetches and stores the feed's source, returning how many
+// entries the feed carried and how many of them were new. A successful
+// fetch unpauses the user's subscription.
+func (s *FeedService) refreshFeed(ctx context.Context, userID, feedID int64) (int, int, error) {
+	var sourceID int64
+	if err := s.db.QueryRowContext(ctx, `SELECT source_id FROM feeds WHERE id=? AND user_id=?`, feedID, userID).Scan(&sourceID); err != nil {
+		return 0, 0, err
+	}
+	fetched, added, err := s.refreshSource(ctx, sourceID)
 	if err != nil {
-		return 0, err
+		return fetched, added, err
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE feeds SET paused=0 WHERE id=? AND paused=1`, feedID); err != nil {
+		return fetched, added, err
+	}
+	return fetched, added, nil
+}
+
+// subscription is one user's feed on a source.
+type subscription struct {
+	feedID, userID int64
+}
+
+// refreshSource fetches a source once for all its subscribers. New entries
+// go through each subscriber's filters and are announced to each of them.
+func (s *FeedService) refreshSource(ctx context.Context, sourceID int64) (int, int, error) {
+	var feedURL, etag, lastModified string
+	err := s.db.QueryRowContext(ctx, `SELECT url, COALESCE(etag, ''), COALESCE(last_modified, '') FROM sources WHERE id=?`, sourceID).
+		Scan(&feedURL, &etag, &lastModified)
+	if err != nil {
+		return 0, 0, err
 	}
-	result, notModified, err := s.fetcher.Fetch(ctx, feed.URL, feed.Etag, feed.LastModified)
+	result, notModified, err := s.fetcher.Fetch(ctx, feedURL, etag, lastModified)
 	if err != nil {
-		return 0, err
+		if recordErr := s.recordFetchFailure(ctx, sourceID, err); recordErr != nil {
+			log.Printf("record fetch failure for source %d: %v", sourceID, recordErr)
+		}
+		return 0, 0, err
+	}
+	if canonical := result.CanonicalURL(); canonical != "" && canonical != feedURL {
+		// Either a permanent redirect or a page URL that now resolves to its feed.
+		if err := s.applyPermanentRedirect(ctx, s.db, sourceID, canonical); err != nil {
+			log.Printf("apply redirect for source %d: %v", sourceID, err)
+		}
 	}
 	if notModified {
-		_, _ = s.db.ExecContext(ctx, `UPDATE feeds SET last_checked_at=? WHERE id=?`, time.Now(), feedID)
-		return 0, nil
+		_, _ = s.db.ExecContext(ctx, `UPDATE sources SET last_checked_at=? WHERE id=?`, time.Now(), sourceID)
+		_, _ = s.scheduleNextCheck(ctx, s.db, sourceID, result.Hints)
+		_ = s.recordFetchSuccess(ctx, s.db, sourceID, result.StatusCode)
+		return 0, 0, nil
 	}
 
-	// Get user's retention setting
-	retentionDays := s.GetRetentionDays(ctx, userID)
+	subscribers, err := s.subscribers(ctx, sourceID)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Entries are kept as long as the subscriber with the longest retention
+	// wants them; the items view hides them from each other subscriber
+	// once their own retention has passed.
+	retentionDays := 0
+	for _, sub := range subscribers {
+		retentionDays = max(retentionDays, s.GetRetentionDays(ctx, sub.userID))
+	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.ExecContext(ctx, `UPDATE feeds SET title=?, site_url=?, etag=?, last_modified=?, last_checked_at=? WHERE id=?`,
-		result.Title, result.SiteURL, result.Etag, result.LastModified, time.Now(), feedID); err != nil {
-		return 0, err
+	if _, err := tx.ExecContext(ctx, `UPDATE sources SET title=?, site_url=?, etag=?, last_modified=?, last_checked_at=? WHERE id=?`,
+		result.Title, result.SiteURL, result.Etag, result.LastModified, time.Now(), sourceID); err != nil {
+		return 0, 0, err
 	}
 
-	if err := s.saveItems(ctx, tx, userID, feedID, feed.URL, result.Items); err != nil {
-		return 0, err
+	added, err := s.saveEntries(ctx, tx, sourceID, feedURL, result.Items)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, sub := range subscribers {
+		if err := s.filterNewItems(ctx, tx, sub.userID, sub.feedID, added); err != nil {
+			return 0, 0, err
+		}
 	}
 
-	// Cleanup old items based on retention setting (excluding bookmarked)
-	if err := s.pruneOldItems(ctx, tx, userID, feedID, retentionDays); err != nil {
-		return 0, err
+	if err := s.pruneEntries(ctx, tx, sourceID, retentionDays); err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := s.scheduleNextCheck(ctx, tx, sourceID, result.Hints); err != nil {
+		return 0, 0, err
+	}
+	if err := s.recordFetchSuccess(ctx, tx, sourceID, result.StatusCode); err != nil {
+		return 0, 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	return len(result.Items), nil
+	if len(added) > 0 {
+		for _, sub := range subscribers {
+			s.events.Publish(sub.userID, Event{Type: EventItemsNew, Data: map[string]interface{}{"feedId": sub.feedID, "count": len(added)}})
+		}
+	}
+	return len(result.Items), len(added), nil
+}
+
+// subscribers lists the subscriptions to a source.
+func (s *FeedService) subscribers(ctx context.Context, sourceID int64) ([]subscription, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id FROM feeds WHERE source_id=? ORDER BY id`, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subs []subscription
+	for rows.Next() {
+		var sub subscription
+		if err := rows.Scan(&sub.feedID, &sub.userID); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
 }
 
 func (s *FeedService) RefreshFolder(ctx context.Context, userID, folderID int64) error {
-	rows, err := s.db.QueryContext(ctx, `SELECT id FROM feeds WHERE user_id=? AND folder_id=?`, userID, folderID)
+	feedIDs, err := s.queryIDs(ctx, `
+		SELECT feeds.id FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=? AND feeds.folder_id=? AND feeds.paused=0`, userID, folderID)
+	if err != nil {
+		return err
+	}
+	// Refresh all feeds in folder, continuing even if some fail
+	s.refreshWithEvents(ctx, userID, &folderID, "", feedIDs, nil)
+	return ctx.Err()
+}
+
+// RefreshAll refreshes every feed the user has that is not paused.
+func (s *FeedService) RefreshAll(ctx context.Context, userID int64) error {
+	feedIDs, err := s.queryIDs(ctx, `
+		SELECT feeds.id FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=? AND feeds.paused=0 ORDER BY feeds.id`, userID)
 	if err != nil {
 		return err
 	}
-	var feedIDs []int64
+
+	// Continue even if individual feeds fail - don't let one bad feed block others
+	s.refreshWithEvents(ctx, userID, nil, "", feedIDs, nil)
+	return ctx.Err()
+}
+
+// refreshWithEvents refreshes the feeds, publishing the run's start, per-feed
+// progress and end. folderID is nil for a refresh of everything; jobID, if
+// set, is included in the events. onProgress may be nil.
+func (s *FeedService) refreshWithEvents(ctx context.Context, userID int64, folderID *int64, jobID string, feedIDs []int64, onProgress func(RefreshProgress)) RefreshSummary {
+	event := func(typ string, data map[string]interface{}) {
+		data["folderId"] = folderID
+		if jobID != "" {
+			data["jobId"] = jobID
+		}
+		s.events.Publish(userID, Event{Type: typ, Data: data})
+	}
+	event(EventRefreshStarted, map[string]interface{}{"total": len(feedIDs)})
+	summary := s.RefreshMany(ctx, refreshTargets(userID, feedIDs), func(p RefreshProgress) {
+		data := map[string]interface{}{
+			"total":     p.Total,
+			"completed": p.Completed,
+			"failed":    p.Failed,
+			"feedId":    p.Last.FeedID,
+			"newItems":  p.Last.NewItems,
+		}
+		if p.Last.Err != nil {
+			data["error"] = p.Last.Err.Error()
+		}
+		event(EventRefreshProgress, data)
+		if onProgress != nil {
+			onProgress(p)
+		}
+	})
+	event(EventRefreshFinished, map[string]interface{}{
+		"total":     summary.Total,
+		"completed": summary.Completed,
+		"failed":    summary.Failed,
+		"skipped":   summary.Skipped,
+	})
+	return summary
+}
+
+// ListUserIDsWithFeeds returns every user that has at least one feed, in a stable order.
+// The scheduler uses it to discover which accounts need background polling.
+func (s *FeedService) ListUserIDsWithFeeds(ctx context.Context) ([]int64, error) {
+	return s.queryIDs(ctx, `SELECT DISTINCT user_id FROM feeds ORDER BY user_id`)
+}
+
+// ListDueFeedIDs returns the user's feeds whose next check is at or before now,
+// most overdue first. Feeds that have never been scheduled are always due;
+// paused feeds never are. A source is polled through its oldest unpaused
+// subscription only, so it is fetched once however many users follow it,
+// and not at all once every one of them has paused it.
+func (s *FeedService) ListDueFeedIDs(ctx context.Context, userID int64, now time.Time) ([]int64, error) {
+	return s.queryIDs(ctx, `
+		SELECT feeds.id FROM feeds
+		JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=? AND feeds.paused=0 AND (sources.next_check_at IS NULL OR sources.next_check_at <= ?)
+		  AND feeds.id = (SELECT MIN(id) FROM feeds owner WHERE owner.source_id = feeds.source_id AND owner.paused=0)
+		ORDER BY sources.next_check_at IS NOT NULL, sources.next_check_at, feeds.id`, userID, now)
+}
+
+// scheduleNextCheck derives the source's next poll time from the publish
+// dates of its stored entries plus the publisher's hints, and persists it.
+func (s *FeedService) scheduleNextCheck(ctx context.Context, q dbtx, sourceID int64, hints feeds.PollHints) (time.Time, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT published_at, created_at FROM entries
+		WHERE source_id=?
+		ORDER BY COALESCE(published_at, created_at) DESC
+		LIMIT ?`, sourceID, feeds.MaxObservedItems)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var published []time.Time
 	for rows.Next() {
-		var feedID int64
-		if err := rows.Scan(&feedID); err != nil {
+		var publishedAt sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&publishedAt, &createdAt); err != nil {
 			rows.Close()
-			return err
+			return time.Time{}, err
+		}
+		if publishedAt.Valid {
+			published = append(published, publishedAt.Time)
+		} else {
+			published = append(published, createdAt)
 		}
-		feedIDs = append(feedIDs, feedID)
-	}
-	if err := rows.Err(); err != nil {
-		rows.Close()
-		return err
 	}
 	if err := rows.Close(); err != nil {
-		return err
+		return time.Time{}, err
 	}
-	// Refresh all feeds in folder, continuing even if some fail
-	for _, feedID := range feedIDs {
-		_, _ = s.RefreshFeed(ctx, userID, feedID)
+
+	now := time.Now()
+	next := now.Add(feeds.NextPollInterval(s.pollPolicy, hints, published, now))
+	if _, err := q.ExecContext(ctx, `UPDATE sources SET next_check_at=? WHERE id=?`, next, sourceID); err != nil {
+		return time.Time{}, err
 	}
-	return nil
+	return next, nil
 }
 
-func (s *FeedService) RefreshAll(ctx context.Context, userID int64) error {
-	rows, err := s.db.QueryContext(ctx, `SELECT id FROM feeds WHERE user_id=?`, userID)
+// queryIDs runs a single-column query and collects the results. The rows are
+// fully drained and closed before returning so callers can issue further
+// statements on the single SQLite connection.
+func (s *FeedService) queryIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	return s.queryIDsTx(ctx, s.db, query, args...)
+}
+
+// queryIDsTx is queryIDs on q, typically a transaction.
+func (s *FeedService) queryIDsTx(ctx context.Context, q dbtx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var feedIDs []int64
+	var ids []int64
 	for rows.Next() {
-		var feedID int64
-		if err := rows.Scan(&feedID); err != nil {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
 			rows.Close()
-			return err
+			return nil, err
 		}
-		feedIDs = append(feedIDs, feedID)
+		ids = append(ids, id)
 	}
 	if err := rows.Err(); err != nil {
 		rows.Close()
-		return err
+		return nil, err
 	}
 	if err := rows.Close(); err != nil {
-		return err
-	}
-
-	// Refresh all feeds, continuing even if some fail
-	for _, feedID := range feedIDs {
-		// Continue even if individual feeds fail - don't let one bad feed block others
-		_, _ = s.RefreshFeed(ctx, userID, feedID)
+		return nil, err
 	}
-	return nil
+	return ids, nil
 }
 
-func (s *FeedService) ListItems(ctx context.Context, userID int64, folderID, feedID *int64, unreadOnly bool, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
+// ListItems lists the user's items, optionally limited to a folder, feed or
+// tag.
+func (s *FeedService) ListItems(ctx context.Context, userID int64, folderID, feedID *int64, tag string, unreadOnly bool, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
 	if limit <= 0 {
 		limit = defaultPageSize
 	}
@@ -361,11 +709,16 @@ func (s *FeedService) ListItems(ctx context.Context, userID int64, folderID, fee
 		clauses = append(clauses, "items.feed_id=?")
 		args = append(args, *feedID)
 	}
+	if tag != "" {
+		clauses = append(clauses, "items.id IN (SELECT item_id FROM item_tags WHERE user_id=? AND tag=?)")
+		args = append(args, userID, tag)
+	}
 	if unreadOnly {
-		clauses = append(clauses, "IFNULL(item_state.is_read,0)=0")
+		clauses = append(clauses, "COALESCE(item_state.is_read,0)=0")
 	}
+	clauses = append(clauses, "COALESCE(item_state.is_hidden,0)=0")
 	sortPref := normalizeItemSort(sort)
-	orderExpr := "CAST(COALESCE(strftime('%s', items.published_at), strftime('%s', items.created_at)) AS INTEGER)"
+	orderExpr := itemTimeExpr(s.db.Dialect())
 	orderDir := "DESC"
 	cursorOp := "<"
 	if sortPref == SortOldest {
@@ -380,11 +733,12 @@ func (s *FeedService) ListItems(ctx context.Context, userID int64, folderID, fee
 	query := fmt.Sprintf(`
 		SELECT items.id, items.feed_id, items.guid, items.link, items.title, items.author, items.published_at, items.summary_text,
 			   items.content_html, items.media_json, items.created_at,
-			   IFNULL(item_state.is_read,0), IFNULL(item_state.is_bookmarked,0), item_state.bookmarked_at,
-			   feeds.title, feeds.site_url
+			   COALESCE(item_state.is_read,0), COALESCE(item_state.is_bookmarked,0), item_state.bookmarked_at,
+			   COALESCE(feeds.title, sources.title), sources.site_url
 		FROM items
-		LEFT JOIN item_state ON item_state.item_id = items.id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
 		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.id = feeds.source_id
 		WHERE %s
 		ORDER BY %s %s, items.id %s
 		LIMIT ?`, strings.Join(clauses, " AND "), orderExpr, orderDir, orderDir)
@@ -431,12 +785,20 @@ func (s *FeedService) ListItems(ctx context.Context, userID int64, folderID, fee
 		items = append(items, it)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
 	var nextCursor *ItemCursor
 	if len(items) > limit {
 		items = items[:limit]
 		last := items[len(items)-1]
 		nextCursor = &ItemCursor{Timestamp: itemSortTimestamp(last), ID: last.ID}
 	}
+	if err := s.attachTags(ctx, userID, items); err != nil {
+		return nil, nil, err
+	}
 	return items, nextCursor, nil
 }
 
@@ -444,11 +806,12 @@ func (s *FeedService) GetItem(ctx context.Context, userID, itemID int64) (models
 	row := s.db.QueryRowContext(ctx, `
 		SELECT items.id, items.feed_id, items.guid, items.link, items.title, items.author, items.published_at, items.summary_text,
 			   items.content_html, items.media_json, items.created_at,
-			   IFNULL(item_state.is_read,0), IFNULL(item_state.is_bookmarked,0), item_state.bookmarked_at,
-			   feeds.title, feeds.site_url
+			   COALESCE(item_state.is_read,0), COALESCE(item_state.is_bookmarked,0), item_state.bookmarked_at,
+			   COALESCE(feeds.title, sources.title), sources.site_url
 		FROM items
-		LEFT JOIN item_state ON item_state.item_id = items.id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
 		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.id = feeds.source_id
 		WHERE items.user_id=? AND items.id=?`, userID, itemID)
 	var it models.Item
 	var published sql.NullTime
@@ -472,33 +835,143 @@ func (s *FeedService) GetItem(ctx context.Context, userID, itemID int64) (models
 	if sourceTitle.Valid || sourceSite.Valid {
 		it.Source = &models.Feed{ID: it.FeedID, Title: sourceTitle.String, SiteURL: sourceSite.String}
 	}
-	return it, nil
+	items := []models.Item{it}
+	if err := s.attachTags(ctx, userID, items); err != nil {
+		return models.Item{}, err
+	}
+	return items[0], nil
 }
 
 func (s *FeedService) MarkRead(ctx context.Context, userID, itemID int64, read bool) error {
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO item_state(item_id, user_id, is_read) VALUES(?, ?, ?)
-		ON CONFLICT(item_id) DO UPDATE SET is_read=excluded.is_read`, itemID, userID, boolToInt(read))
+		ON CONFLICT(user_id, item_id) DO UPDATE SET is_read=excluded.is_read`, itemID, userID, boolToInt(read))
+	if err == nil {
+		s.events.Publish(userID, Event{Type: EventItemState, Data: map[string]interface{}{"itemId": itemID, "isRead": read}})
+	}
 	return err
 }
 
+// MarkReadScope selects the items MarkAllRead marks. Zero fields do not
+// restrict; Search and SavedSearchID limit it to full-text matches. Before
+// keeps items newer than it unread. Cursor is a position in a listing in
+// Sort order, as passed to ListItems: the item it names and everything
+// listed after it are marked, and the items above it are left alone.
+type MarkReadScope struct {
+	FolderID      *int64
+	FeedID        *int64
+	Tag           string
+	Search        *SearchQuery
+	SavedSearchID *int64
+	Before        *time.Time
+	Cursor        *ItemCursor
+	Sort          string
+}
+
+// MarkAllRead marks every unread item in scope as read with a single
+// statement and returns how many items changed.
+func (s *FeedService) MarkAllRead(ctx context.Context, userID int64, scope MarkReadScope) (int64, error) {
+	if scope.SavedSearchID != nil {
+		ss, err := s.GetSavedSearch(ctx, userID, *scope.SavedSearchID)
+		if err != nil {
+			return 0, err
+		}
+		sq := savedSearchQuery(ss, time.Now())
+		scope.Search = &sq
+	}
+	from := "items"
+	clauses := []string{"items.user_id=?"}
+	args := []interface{}{userID}
+	if scope.Search != nil {
+		match, err := matchQuery(s.db.Dialect(), scope.Search.Query)
+		if err != nil {
+			return 0, err
+		}
+		if !s.hasSearchIndex(ctx, s.db) {
+			return 0, ErrSearchUnavailable
+		}
+		from = "items_fts JOIN items ON items.id = items_fts.rowid"
+		clauses, args = scope.Search.filters(s.db.Dialect(), match, userID)
+	}
+	if scope.FolderID != nil {
+		clauses = append(clauses, "feeds.folder_id=?")
+		args = append(args, *scope.FolderID)
+	}
+	if scope.FeedID != nil {
+		clauses = append(clauses, "items.feed_id=?")
+		args = append(args, *scope.FeedID)
+	}
+	if scope.Tag != "" {
+		clauses = append(clauses, "items.id IN (SELECT item_id FROM item_tags WHERE user_id=? AND tag=?)")
+		args = append(args, userID, scope.Tag)
+	}
+	if scope.Before != nil {
+		clauses = append(clauses, "COALESCE(items.published_at, items.created_at) < ?")
+		args = append(args, *scope.Before)
+	}
+	if scope.Cursor != nil {
+		orderExpr := itemTimeExpr(s.db.Dialect())
+		cursorOp := "<"
+		if normalizeItemSort(scope.Sort) == SortOldest {
+			cursorOp = ">"
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s %s ? OR (%s = ? AND items.id %s= ?))", orderExpr, cursorOp, orderExpr, cursorOp))
+		args = append(args, scope.Cursor.Timestamp, scope.Cursor.Timestamp, scope.Cursor.ID)
+	}
+	clauses = append(clauses, "COALESCE(item_state.is_read,0)=0")
+
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO item_state(item_id, user_id, is_read)
+		SELECT items.id, items.user_id, 1
+		FROM %s
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		JOIN feeds ON feeds.id = items.feed_id
+		WHERE %s
+		ON CONFLICT(user_id, item_id) DO UPDATE SET is_read=1`, from, strings.Join(clauses, " AND ")), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err == nil && n > 0 {
+		s.events.Publish(userID, Event{Type: EventItemsRead, Data: map[string]interface{}{"count": n}})
+	}
+	return n, err
+}
+
 func (s *FeedService) Bookmark(ctx context.Context, userID, itemID int64, bookmarked bool) error {
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO item_state(item_id, user_id, is_bookmarked, bookmarked_at) VALUES(?, ?, ?, CASE WHEN ?=1 THEN CURRENT_TIMESTAMP ELSE NULL END)
-		ON CONFLICT(item_id) DO UPDATE SET is_bookmarked=excluded.is_bookmarked,
+		ON CONFLICT(user_id, item_id) DO UPDATE SET is_bookmarked=excluded.is_bookmarked,
 		bookmarked_at=CASE WHEN excluded.is_bookmarked=1 THEN CURRENT_TIMESTAMP ELSE NULL END`,
 		itemID, userID, boolToInt(bookmarked), boolToInt(bookmarked))
+	if err == nil {
+		s.events.Publish(userID, Event{Type: EventItemState, Data: map[string]interface{}{"itemId": itemID, "isBookmarked": bookmarked}})
+	}
 	return err
 }
 
-func (s *FeedService) ListBookmarks(ctx context.Context, userID int64, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
+func (s *FeedService) ListBookmarks(ctx context.Context, userID int64, tag string, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
+	return s.listFlaggedItems(ctx, userID, "is_bookmarked", tag, limit, cursor, sort)
+}
+
+// ListHighlights returns the items filter rules moved to the highlight list.
+func (s *FeedService) ListHighlights(ctx context.Context, userID int64, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
+	return s.listFlaggedItems(ctx, userID, "is_highlighted", "", limit, cursor, sort)
+}
+
+// listFlaggedItems lists items whose item_state flag column is set.
+func (s *FeedService) listFlaggedItems(ctx context.Context, userID int64, flag, tag string, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
 	if limit <= 0 {
 		limit = defaultPageSize
 	}
-	clauses := []string{"items.user_id=?", "IFNULL(item_state.is_bookmarked,0)=1"}
+	clauses := []string{"items.user_id=?", fmt.Sprintf("COALESCE(item_state.%s,0)=1", flag)}
 	args := []interface{}{userID}
+	if tag != "" {
+		clauses = append(clauses, "items.id IN (SELECT item_id FROM item_tags WHERE user_id=? AND tag=?)")
+		args = append(args, userID, tag)
+	}
 	sortPref := normalizeItemSort(sort)
-	orderExpr := "CAST(COALESCE(strftime('%s', items.published_at), strftime('%s', items.created_at)) AS INTEGER)"
+	orderExpr := itemTimeExpr(s.db.Dialect())
 	orderDir := "DESC"
 	cursorOp := "<"
 	if sortPref == SortOldest {
@@ -512,11 +985,12 @@ func (s *FeedService) ListBookmarks(ctx context.Context, userID int64, limit int
 	query := fmt.Sprintf(`
 		SELECT items.id, items.feed_id, items.guid, items.link, items.title, items.author, items.published_at, items.summary_text,
 			   items.content_html, items.media_json, items.created_at,
-			   IFNULL(item_state.is_read,0), IFNULL(item_state.is_bookmarked,0), item_state.bookmarked_at,
-			   feeds.title, feeds.site_url
+			   COALESCE(item_state.is_read,0), COALESCE(item_state.is_bookmarked,0), item_state.bookmarked_at,
+			   COALESCE(feeds.title, sources.title), sources.site_url
 		FROM items
-		LEFT JOIN item_state ON item_state.item_id = items.id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
 		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.id = feeds.source_id
 		WHERE %s
 		ORDER BY %s %s, items.id %s
 		LIMIT ?`, strings.Join(clauses, " AND "), orderExpr, orderDir, orderDir)
@@ -555,16 +1029,28 @@ func (s *FeedService) ListBookmarks(ctx context.Context, userID int64, limit int
 		}
 		items = append(items, it)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
 	var nextCursor *ItemCursor
 	if len(items) > limit {
 		items = items[:limit]
 		last := items[len(items)-1]
 		nextCursor = &ItemCursor{Timestamp: itemSortTimestamp(last), ID: last.ID}
 	}
+	if err := s.attachTags(ctx, userID, items); err != nil {
+		return nil, nil, err
+	}
 	return items, nextCursor, nil
 }
 
-func (s *FeedService) saveItems(ctx context.Context, tx *sql.Tx, userID, feedID int64, baseURL string, entries []*gofeed.Item) error {
+// saveEntries upserts the source's entries and returns the ids of the new
+// ones. Stored entries only gain media and content they were missing.
+func (s *FeedService) saveEntries(ctx context.Context, tx *db.Tx, sourceID int64, baseURL string, entries []*gofeed.Item) ([]int64, error) {
+	indexed := s.hasSearchIndex(ctx, tx)
+	var added []int64
 	for _, entry := range entries {
 		guid := feeds.NormalizeGUID(entry)
 		var published sql.NullTime
@@ -598,57 +1084,99 @@ func (s *FeedService) saveItems(ctx context.Context, tx *sql.Tx, userID, feedID
 		media := collectMedia(entry, mediaBaseURL)
 		mediaJSON, _ := json.Marshal(media)
 
-		_, err := tx.ExecContext(ctx, `
-			INSERT INTO items(user_id, feed_id, guid, link, title, author, published_at, summary_text, content_html, media_json)
-			VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-			ON CONFLICT(user_id, feed_id, guid) DO UPDATE SET
-				media_json = CASE
-					WHEN excluded.media_json IS NOT NULL
-						AND excluded.media_json != ''
-						AND excluded.media_json != '[]'
-						THEN excluded.media_json
-					ELSE media_json
-				END,
-				content_html = CASE
-					WHEN content_html IS NULL OR content_html = '' THEN excluded.content_html
-					ELSE content_html
-				END,
-				summary_text = CASE
-					WHEN summary_text IS NULL OR summary_text = '' THEN excluded.summary_text
-					ELSE summary_text
-				END`,
-			userID, feedID, guid, entry.Link, entry.Title, author, published, summaryText, content, string(mediaJSON))
-		if err != nil {
+		var entryID int64
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO entries(source_id, guid, link, title, author, published_at, summary_text, content_html, media_json)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source_id, guid) DO NOTHING
+			RETURNING id`,
+			sourceID, guid, entry.Link, entry.Title, author, published, summaryText, content, string(mediaJSON)).Scan(&entryID)
+		switch {
+		case err == nil:
+			added = append(added, entryID)
+		case err == sql.ErrNoRows:
+			update := `
+				UPDATE entries SET
+					content_html = CASE WHEN content_html IS NULL OR content_html = '' THEN ? ELSE content_html END,
+					summary_text = CASE WHEN summary_text IS NULL OR summary_text = '' THEN ? ELSE summary_text END`
+			args := []interface{}{content, summaryText}
+			if len(media) > 0 {
+				update += `, media_json = ?`
+				args = append(args, string(mediaJSON))
+			}
+			args = append(args, sourceID, guid)
+			if err := tx.QueryRowContext(ctx, update+` WHERE source_id=? AND guid=? RETURNING id`, args...).Scan(&entryID); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+		if indexed {
+			if err := s.indexItem(ctx, tx, entryID); err != nil {
+				return nil, fmt.Errorf("index item: %w", err)
+			}
+		}
+	}
+	return added, nil
+}
+
+// filterNewItems runs the user's filters over entries that just appeared in
+// their feed; filters only act on items the first time they are stored.
+func (s *FeedService) filterNewItems(ctx context.Context, tx *db.Tx, userID, feedID int64, entryIDs []int64) error {
+	if len(entryIDs) == 0 {
+		return nil
+	}
+	filters, err := s.loadFilters(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("load filters: %w", err)
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	var feedTitle, folderName string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(feeds.title, sources.title, ''), folders.name
+		FROM feeds
+		JOIN sources ON sources.id = feeds.source_id
+		JOIN folders ON folders.id = feeds.folder_id
+		WHERE feeds.id=?`,
+		feedID).Scan(&feedTitle, &folderName); err != nil {
+		return err
+	}
+	withContent := false
+	for _, f := range filters {
+		withContent = withContent || f.needsContent()
+	}
+	for _, id := range entryIDs {
+		var title, author, link, summary, content, mediaJSON string
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(title,''), COALESCE(author,''), COALESCE(link,''),
+				   COALESCE(summary_text,''), COALESCE(content_html,''), COALESCE(media_json,'')
+			FROM entries WHERE id=?`, id).Scan(&title, &author, &link, &summary, &content, &mediaJSON); err != nil {
+			return err
+		}
+		subj := newFilterSubject(title, author, link, summary, content, mediaJSON, feedTitle, folderName, withContent)
+		if err := s.applyFilters(ctx, tx, userID, id, filters, subj); err != nil {
 			return err
 		}
-		_, _ = tx.ExecContext(ctx, `
-			INSERT OR IGNORE INTO item_state(item_id, user_id, is_read, is_bookmarked) 
-			SELECT id, ?, 0, 0 FROM items WHERE guid=? AND feed_id=?`, userID, guid, feedID)
 	}
 	return nil
 }
 
-// pruneOldItems removes items older than the specified retention period.
-// Bookmarked items are never deleted regardless of age.
-func (s *FeedService) pruneOldItems(ctx context.Context, tx *sql.Tx, userID, feedID int64, retentionDays int) error {
+// pruneEntries removes the source's entries older than the retention period.
+// Entries any subscriber bookmarked or tagged are never deleted regardless
+// of age. Their search index rows go with them.
+func (s *FeedService) pruneEntries(ctx context.Context, tx *db.Tx, sourceID int64, retentionDays int) error {
 	if retentionDays <= 0 {
 		retentionDays = defaultRetentionDays
 	}
-	// Calculate cutoff date
 	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
-
-	// Delete items that are:
-	// 1. Older than cutoff date (based on published_at or created_at)
-	// 2. NOT bookmarked
 	_, err := tx.ExecContext(ctx, `
-		DELETE FROM items 
-		WHERE id IN (
-			SELECT items.id FROM items
-			LEFT JOIN item_state ON item_state.item_id = items.id
-			WHERE items.feed_id = ? AND items.user_id = ?
-			  AND IFNULL(item_state.is_bookmarked, 0) = 0
-			  AND COALESCE(items.published_at, items.created_at) < ?
-		)`, feedID, userID, cutoffDate)
+		DELETE FROM entries
+		WHERE source_id = ?
+		  AND COALESCE(published_at, created_at) < ?
+		  AND id NOT IN (SELECT item_id FROM item_state WHERE is_bookmarked = 1)
+		  AND id NOT IN (SELECT item_id FROM item_tags)`, sourceID, cutoffDate)
 	return err
 }
 
@@ -877,6 +1405,12 @@ func normalizeContent(html, baseURL string) string {
 	return out
 }
 
+// itemTimeExpr is an item's publish time, or when it was stored, in Unix
+// seconds. Listings sort and page by it.
+func itemTimeExpr(d db.Dialect) string {
+	return fmt.Sprintf("COALESCE(%s, %s)", d.UnixTime("items.published_at"), d.UnixTime("items.created_at"))
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/models"
+)
+
+const (
+	// feverItemLimit is the most items one Fever items request returns.
+	feverItemLimit = 50
+	// minFeverPasswordLen keeps the md5-based Fever key from being trivially
+	// guessable.
+	minFeverPasswordLen = 8
+)
+
+var ErrInvalidFeverRequest = errors.New("invalid fever request")
+
+// FeverService exposes a user's folders, feeds and items in the shape of the
+// Fever API, which mobile clients such as Reeder and Unread speak. Fever
+// clients authenticate with md5("email:password"); accounts here log in by
+// email code, so the password is a separate one the user sets for Fever.
+type FeverService struct {
+	db          *db.DB
+	feedService *FeedService
+}
+
+func NewFeverService(db *db.DB, feedService *FeedService) *FeverService {
+	return &FeverService{db: db, feedService: feedService}
+}
+
+// FeverItemsQuery selects a page of items. SinceID returns items after it in
+// ascending order, MaxID items before it in descending order and WithIDs
+// exactly those items; with none set the oldest items come first.
+type FeverItemsQuery struct {
+	SinceID int64
+	MaxID   int64
+	WithIDs []int64
+}
+
+// Enabled reports whether the user has set a Fever password.
+func (s *FeverService) Enabled(ctx context.Context, userID int64) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM fever_credentials WHERE user_id=?`, userID).Scan(&n)
+	return n > 0, err
+}
+
+// SetPassword stores the Fever API key for the user's email and password,
+// replacing any earlier one.
+func (s *FeverService) SetPassword(ctx context.Context, userID int64, password string) error {
+	if len(password) < minFeverPasswordLen {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalidFeverRequest, minFeverPasswordLen)
+	}
+	var email string
+	if err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id=?`, userID).Scan(&email); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO fever_credentials(user_id, api_key, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET api_key=excluded.api_key, created_at=excluded.created_at`,
+		userID, FeverAPIKey(email, password), time.Now())
+	return err
+}
+
+// Disable removes the user's Fever API key.
+func (s *FeverService) Disable(ctx context.Context, userID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM fever_credentials WHERE user_id=?`, userID)
+	return err
+}
+
+// FeverAPIKey is the key a Fever client sends for the given credentials.
+func FeverAPIKey(email, password string) string {
+	sum := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email)) + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// UserForAPIKey resolves a Fever API key to its owner.
+func (s *FeverService) UserForAPIKey(ctx context.Context, apiKey string) (int64, error) {
+	apiKey = strings.ToLower(strings.TrimSpace(apiKey))
+	if apiKey == "" {
+		return 0, ErrInvalidToken
+	}
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `SELECT user_id FROM fever_credentials WHERE api_key=?`, apiKey).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	return userID, err
+}
+
+// LastRefreshed returns when any of the user's feeds was last checked, as
+// Unix seconds.
+func (s *FeverService) LastRefreshed(ctx context.Context, userID int64) (int64, error) {
+	var last int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(MAX(%s), 0) FROM feeds JOIN sources ON sources.id = feeds.source_id WHERE feeds.user_id=?`,
+		s.db.Dialect().UnixTime("sources.last_checked_at")), userID).Scan(&last)
+	return last, err
+}
+
+// Groups returns the user's folders as Fever groups.
+func (s *FeverService) Groups(ctx context.Context, userID int64) ([]models.FeverGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name FROM folders WHERE user_id=? ORDER BY name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groups := []models.FeverGroup{}
+	for rows.Next() {
+		var g models.FeverGroup
+		if err := rows.Scan(&g.ID, &g.Title); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// Feeds returns the user's feeds as Fever feeds.
+func (s *FeverService) Feeds(ctx context.Context, userID int64) ([]models.FeverFeed, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT feeds.id, COALESCE(feeds.title, sources.title, ''), sources.url, COALESCE(sources.site_url, ''), COALESCE(%s, 0)
+		FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=? ORDER BY COALESCE(feeds.title, sources.title)`, s.db.Dialect().UnixTime("sources.last_checked_at")), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	feeds := []models.FeverFeed{}
+	for rows.Next() {
+		var f models.FeverFeed
+		if err := rows.Scan(&f.ID, &f.Title, &f.URL, &f.SiteURL, &f.LastUpdatedOnTime); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// FeedsGroups lists which feeds belong to each folder.
+func (s *FeverService) FeedsGroups(ctx context.Context, userID int64) ([]models.FeverFeedsGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT folder_id, id FROM feeds WHERE user_id=? ORDER BY folder_id, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var (
+		groups []models.FeverFeedsGroup
+		ids    []int64
+	)
+	flush := func(groupID int64) {
+		if len(ids) > 0 {
+			groups = append(groups, models.FeverFeedsGroup{GroupID: groupID, FeedIDs: joinIDs(ids)})
+		}
+		ids = nil
+	}
+	current := int64(-1)
+	for rows.Next() {
+		var folderID, feedID int64
+		if err := rows.Scan(&folderID, &feedID); err != nil {
+			return nil, err
+		}
+		if folderID != current {
+			flush(current)
+			current = folderID
+		}
+		ids = append(ids, feedID)
+	}
+	flush(current)
+	if groups == nil {
+		groups = []models.FeverFeedsGroup{}
+	}
+	return groups, rows.Err()
+}
+
+// Items returns up to feverItemLimit visible items and the user's total
+// number of visible items.
+func (s *FeverService) Items(ctx context.Context, userID int64, q FeverItemsQuery) ([]models.FeverItem, int, error) {
+	clauses := []string{"items.user_id=?", "COALESCE(item_state.is_hidden,0)=0"}
+	args := []interface{}{userID}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM items LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE `+strings.Join(clauses, " AND "), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := "ASC"
+	switch {
+	case len(q.WithIDs) > 0:
+		if len(q.WithIDs) > feverItemLimit {
+			q.WithIDs = q.WithIDs[:feverItemLimit]
+		}
+		clauses = append(clauses, "items.id IN ("+strings.TrimSuffix(strings.Repeat("?,", len(q.WithIDs)), ",")+")")
+		for _, id := range q.WithIDs {
+			args = append(args, id)
+		}
+	case q.SinceID > 0:
+		clauses = append(clauses, "items.id > ?")
+		args = append(args, q.SinceID)
+	case q.MaxID > 0:
+		clauses = append(clauses, "items.id < ?")
+		args = append(args, q.MaxID)
+		order = "DESC"
+	}
+	args = append(args, feverItemLimit)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT items.id, items.feed_id, COALESCE(items.title, ''), COALESCE(items.author, ''),
+			COALESCE(NULLIF(items.content_html, ''), items.summary_text, ''), COALESCE(items.link, ''),
+			COALESCE(item_state.is_bookmarked,0), COALESCE(item_state.is_read,0),
+			%s
+		FROM items
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE %s
+		ORDER BY items.id %s
+		LIMIT ?`, itemTimeExpr(s.db.Dialect()), strings.Join(clauses, " AND "), order), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := []models.FeverItem{}
+	for rows.Next() {
+		var it models.FeverItem
+		if err := rows.Scan(&it.ID, &it.FeedID, &it.Title, &it.Author, &it.HTML, &it.URL,
+			&it.IsSaved, &it.IsRead, &it.CreatedOnTime); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, it)
+	}
+	return items, total, rows.Err()
+}
+
+// UnreadItemIDs returns the IDs of the user's unread visible items.
+func (s *FeverService) UnreadItemIDs(ctx context.Context, userID int64) (string, error) {
+	return s.itemIDs(ctx, `
+		SELECT items.id FROM items LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE items.user_id=? AND COALESCE(item_state.is_read,0)=0 AND COALESCE(item_state.is_hidden,0)=0
+		ORDER BY items.id`, userID)
+}
+
+// SavedItemIDs returns the IDs of the user's bookmarked items.
+func (s *FeverService) SavedItemIDs(ctx context.Context, userID int64) (string, error) {
+	return s.itemIDs(ctx, `
+		SELECT items.id FROM items JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE items.user_id=? AND item_state.is_bookmarked=1
+		ORDER BY items.id`, userID)
+}
+
+func (s *FeverService) itemIDs(ctx context.Context, query string, userID int64) (string, error) {
+	ids, err := s.feedService.queryIDs(ctx, query, userID)
+	if err != nil {
+		return "", err
+	}
+	return joinIDs(ids), nil
+}
+
+// MarkItem applies a Fever item action: read, unread, saved or unsaved.
+func (s *FeverService) MarkItem(ctx context.Context, userID, itemID int64, as string) error {
+	var owned int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM items WHERE id=? AND user_id=?`, itemID, userID).Scan(&owned); err != nil {
+		return err
+	}
+	if owned == 0 {
+		return sql.ErrNoRows
+	}
+	switch as {
+	case "read", "unread":
+		return s.feedService.MarkRead(ctx, userID, itemID, as == "read")
+	case "saved", "unsaved":
+		return s.feedService.Bookmark(ctx, userID, itemID, as == "saved")
+	}
+	return fmt.Errorf("%w: cannot mark item as %q", ErrInvalidFeverRequest, as)
+}
+
+// MarkFeedRead marks the feed's items published before the cutoff as read.
+func (s *FeverService) MarkFeedRead(ctx context.Context, userID, feedID int64, before time.Time) error {
+	_, err := s.feedService.MarkAllRead(ctx, userID, MarkReadScope{FeedID: &feedID, Before: &before})
+	return err
+}
+
+// MarkGroupRead marks a folder's items published before the cutoff as read.
+// Group 0 is Fever's "Kindling", every item; negative groups (Sparks) hold
+// nothing here.
+func (s *FeverService) MarkGroupRead(ctx context.Context, userID, groupID int64, before time.Time) error {
+	scope := MarkReadScope{Before: &before}
+	switch {
+	case groupID < 0:
+		return nil
+	case groupID > 0:
+		scope.FolderID = &groupID
+	}
+	_, err := s.feedService.MarkAllRead(ctx, userID, scope)
+	return err
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestFeverAPIKey(t *testing.T) {
+	const want = "e6540b905d197d2c0dfa98e87ca57cf0"
+	for _, email := range []string{"user@example.com", " User@Example.com "} {
+		if got := FeverAPIKey(email, "secret-pass"); got != want {
+			t.Errorf("FeverAPIKey(%q) = %q, expected %q", email, got, want)
+		}
+	}
+	if FeverAPIKey("user@example.com", "other-pass") == want {
+		t.Error("different password gave the same key")
+	}
+}
+
+func TestJoinIDs(t *testing.T) {
+	tests := []struct {
+		ids  []int64
+		want string
+	}{
+		{nil, ""},
+		{[]int64{7}, "7"},
+		{[]int64{1, 20, 300}, "1,20,300"},
+	}
+	for _, tc := range tests {
+		if got := joinIDs(tc.ids); got != tc.want {
+			t.Errorf("joinIDs(%v) = %q, expected %q", tc.ids, got, tc.want)
+		}
+	}
+}
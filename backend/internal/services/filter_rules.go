@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+var (
+	filterFields     = map[string]bool{"any": true, "title": true, "author": true, "content": true, "link": true, "feed": true, "folder": true}
+	filterMatchTypes = map[string]bool{"keyword": true, "regex": true, "media_type": true}
+	filterActions    = map[string]bool{"mark_read": true, "bookmark": true, "hide": true, "tag": true, "highlight": true}
+)
+
+const maxFilterPatternLen = 500
+
+// filterSubject is the text of an item that filter rules match against.
+type filterSubject struct {
+	Title, Author, Content, Link, Feed, Folder string
+	MediaTypes                                 []string
+}
+
+// compiledFilter is a validated rule ready to be matched.
+type compiledFilter struct {
+	rule     models.FilterRule
+	keywords []string
+	re       *regexp.Regexp
+}
+
+// compileFilterRule normalises and validates a rule.
+func compileFilterRule(rule models.FilterRule) (compiledFilter, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Pattern = strings.TrimSpace(rule.Pattern)
+	if rule.Field == "" {
+		rule.Field = "any"
+	}
+	if rule.MatchType == "" {
+		rule.MatchType = "keyword"
+	}
+	switch {
+	case rule.Name == "":
+		return compiledFilter{}, errors.New("name required")
+	case rule.Pattern == "":
+		return compiledFilter{}, errors.New("pattern required")
+	case len(rule.Pattern) > maxFilterPatternLen:
+		return compiledFilter{}, fmt.Errorf("pattern longer than %d characters", maxFilterPatternLen)
+	case !filterFields[rule.Field]:
+		return compiledFilter{}, fmt.Errorf("unknown field %q", rule.Field)
+	case !filterMatchTypes[rule.MatchType]:
+		return compiledFilter{}, fmt.Errorf("unknown match type %q", rule.MatchType)
+	case !filterActions[rule.Action]:
+		return compiledFilter{}, fmt.Errorf("unknown action %q", rule.Action)
+	}
+	if rule.Action == "tag" {
+		tag, err := normalizeTag(rule.Tag)
+		if err != nil {
+			return compiledFilter{}, err
+		}
+		rule.Tag = tag
+	} else {
+		rule.Tag = ""
+	}
+
+	f := compiledFilter{rule: rule}
+	switch rule.MatchType {
+	case "regex":
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiledFilter{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		f.re = re
+	default:
+		for _, kw := range strings.Split(rule.Pattern, ",") {
+			if kw = strings.ToLower(strings.TrimSpace(kw)); kw != "" {
+				f.keywords = append(f.keywords, kw)
+			}
+		}
+		if len(f.keywords) == 0 {
+			return compiledFilter{}, errors.New("pattern required")
+		}
+	}
+	return f, nil
+}
+
+// needsContent reports whether matching reads the item body, which is
+// costly to extract from HTML.
+func (f compiledFilter) needsContent() bool {
+	return f.rule.MatchType != "media_type" && (f.rule.Field == "any" || f.rule.Field == "content")
+}
+
+func (f compiledFilter) matches(subj filterSubject) bool {
+	if f.rule.MatchType == "media_type" {
+		for _, mt := range subj.MediaTypes {
+			mt = strings.ToLower(mt)
+			for _, want := range f.keywords {
+				if mt == want || (strings.HasSuffix(want, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(want, "*"))) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var texts []string
+	switch f.rule.Field {
+	case "title":
+		texts = []string{subj.Title}
+	case "author":
+		texts = []string{subj.Author}
+	case "content":
+		texts = []string{subj.Content}
+	case "link":
+		texts = []string{subj.Link}
+	case "feed":
+		texts = []string{subj.Feed}
+	case "folder":
+		texts = []string{subj.Folder}
+	default:
+		texts = []string{subj.Title, subj.Author, subj.Content, subj.Link}
+	}
+	for _, text := range texts {
+		if f.re != nil {
+			if f.re.MatchString(text) {
+				return true
+			}
+			continue
+		}
+		lower := strings.ToLower(text)
+		for _, kw := range f.keywords {
+			if strings.Contains(lower, kw) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newFilterSubject builds the matchable text of an item; the body is only
+// extracted when withContent is set.
+func newFilterSubject(title, author, link, summary, content, mediaJSON, feed, folder string, withContent bool) filterSubject {
+	subj := filterSubject{Title: title, Author: author, Link: link, Feed: feed, Folder: folder}
+	if withContent {
+		subj.Content = plainText(summary) + "\n" + plainText(content)
+	}
+	var media []models.Media
+	if mediaJSON != "" && json.Unmarshal([]byte(mediaJSON), &media) == nil {
+		for _, m := range media {
+			if m.Type != "" {
+				subj.MediaTypes = append(subj.MediaTypes, m.Type)
+			}
+		}
+	}
+	return subj
+}
+
+func (s *FeedService) ListFilterRules(ctx context.Context, userID int64) ([]models.FilterRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, field, match_type, pattern, action, tag, enabled, created_at
+		FROM filter_rules WHERE user_id=? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rules := []models.FilterRule{}
+	for rows.Next() {
+		rule := models.FilterRule{UserID: userID}
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Field, &rule.MatchType, &rule.Pattern, &rule.Action, &rule.Tag,
+			&rule.Enabled, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *FeedService) CreateFilterRule(ctx context.Context, userID int64, rule models.FilterRule) (models.FilterRule, error) {
+	f, err := compileFilterRule(rule)
+	if err != nil {
+		return models.FilterRule{}, err
+	}
+	rule = f.rule
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO filter_rules(user_id, name, field, match_type, pattern, action, tag, enabled)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		userID, rule.Name, rule.Field, rule.MatchType, rule.Pattern, rule.Action, rule.Tag, boolToInt(rule.Enabled)).Scan(&rule.ID)
+	if err != nil {
+		return models.FilterRule{}, err
+	}
+	rule.UserID = userID
+	return rule, nil
+}
+
+func (s *FeedService) UpdateFilterRule(ctx context.Context, userID int64, rule models.FilterRule) error {
+	f, err := compileFilterRule(rule)
+	if err != nil {
+		return err
+	}
+	rule = f.rule
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE filter_rules SET name=?, field=?, match_type=?, pattern=?, action=?, tag=?, enabled=?
+		WHERE id=? AND user_id=?`,
+		rule.Name, rule.Field, rule.MatchType, rule.Pattern, rule.Action, rule.Tag, boolToInt(rule.Enabled),
+		rule.ID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("filter rule not found")
+	}
+	return nil
+}
+
+func (s *FeedService) DeleteFilterRule(ctx context.Context, userID, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM filter_rules WHERE id=? AND user_id=?`, id, userID)
+	return err
+}
+
+// loadFilters compiles the user's enabled rules. Rules that no longer
+// compile are skipped rather than blocking ingest.
+func (s *FeedService) loadFilters(ctx context.Context, q dbtx, userID int64) ([]compiledFilter, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, name, field, match_type, pattern, action, tag
+		FROM filter_rules WHERE user_id=? AND enabled=1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var filters []compiledFilter
+	for rows.Next() {
+		rule := models.FilterRule{UserID: userID, Enabled: true}
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Field, &rule.MatchType, &rule.Pattern, &rule.Action, &rule.Tag); err != nil {
+			return nil, err
+		}
+		if f, err := compileFilterRule(rule); err == nil {
+			filters = append(filters, f)
+		}
+	}
+	return filters, rows.Err()
+}
+
+// applyFilters runs the actions of every rule matching a newly stored item.
+func (s *FeedService) applyFilters(ctx context.Context, q dbtx, userID, itemID int64, filters []compiledFilter, subj filterSubject) error {
+	for _, f := range filters {
+		if !f.matches(subj) {
+			continue
+		}
+		var err error
+		switch f.rule.Action {
+		case "mark_read":
+			_, err = q.ExecContext(ctx, `INSERT INTO item_state(item_id, user_id, is_read) VALUES(?, ?, 1)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET is_read=1`, itemID, userID)
+		case "bookmark":
+			_, err = q.ExecContext(ctx, `INSERT INTO item_state(item_id, user_id, is_bookmarked, bookmarked_at) VALUES(?, ?, 1, CURRENT_TIMESTAMP)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET is_bookmarked=1, bookmarked_at=CURRENT_TIMESTAMP`, itemID, userID)
+		case "hide":
+			_, err = q.ExecContext(ctx, `INSERT INTO item_state(item_id, user_id, is_hidden) VALUES(?, ?, 1)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET is_hidden=1`, itemID, userID)
+		case "highlight":
+			_, err = q.ExecContext(ctx, `INSERT INTO item_state(item_id, user_id, is_highlighted) VALUES(?, ?, 1)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET is_highlighted=1`, itemID, userID)
+		case "tag":
+			_, err = q.ExecContext(ctx, `INSERT INTO item_tags(item_id, user_id, tag) VALUES(?, ?, ?) ON CONFLICT DO NOTHING`, itemID, userID, f.rule.Tag)
+		}
+		if err != nil {
+			return fmt.Errorf("filter %d: %w", f.rule.ID, err)
+		}
+	}
+	return nil
+}
+
+// DryRunFilterRule reports which of the user's stored items the rule would
+// act on, without changing anything. It returns up to limit matches, newest
+// first, and the total number of matches.
+func (s *FeedService) DryRunFilterRule(ctx context.Context, userID int64, rule models.FilterRule, limit int) ([]models.Item, int, error) {
+	f, err := compileFilterRule(rule)
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT items.id, COALESCE(items.title,''), COALESCE(items.author,''), COALESCE(items.link,''),
+			   COALESCE(items.summary_text,''), COALESCE(items.content_html,''), COALESCE(items.media_json,''),
+			   COALESCE(feeds.title, sources.title, ''), folders.name
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.id = feeds.source_id
+		JOIN folders ON folders.id = feeds.folder_id
+		WHERE items.user_id=?
+		ORDER BY COALESCE(items.published_at, items.created_at) DESC, items.id DESC`, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var ids []int64
+	total := 0
+	for rows.Next() {
+		var (
+			id                                                             int64
+			title, author, link, summary, content, mediaJSON, feed, folder string
+		)
+		if err := rows.Scan(&id, &title, &author, &link, &summary, &content, &mediaJSON, &feed, &folder); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		if !f.matches(newFilterSubject(title, author, link, summary, content, mediaJSON, feed, folder, f.needsContent())) {
+			continue
+		}
+		total++
+		if len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]models.Item, 0, len(ids))
+	for _, id := range ids {
+		it, err := s.GetItem(ctx, userID, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, it)
+	}
+	return items, total, nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+	"rss-feed-manager/backend/internal/models"
+)
+
+func TestCompileFilterRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.FilterRule
+		wantErr bool
+	}{
+		{"keyword defaults", models.FilterRule{Name: "ads", Pattern: "sponsored", Action: "hide"}, false},
+		{"missing name", models.FilterRule{Pattern: "x", Action: "hide"}, true},
+		{"missing pattern", models.FilterRule{Name: "x", Pattern: " , ", Action: "hide"}, true},
+		{"unknown field", models.FilterRule{Name: "x", Field: "body", Pattern: "x", Action: "hide"}, true},
+		{"unknown action", models.FilterRule{Name: "x", Pattern: "x", Action: "delete"}, true},
+		{"bad regex", models.FilterRule{Name: "x", MatchType: "regex", Pattern: "(", Action: "hide"}, true},
+		{"tag without tag", models.FilterRule{Name: "x", Pattern: "x", Action: "tag"}, true},
+		{"tag", models.FilterRule{Name: "x", Pattern: "x", Action: "tag", Tag: "  go   lang "}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := compileFilterRule(tc.rule)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("compileFilterRule() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && tc.rule.Action == "tag" && f.rule.Tag != "go lang" {
+				t.Errorf("tag = %q, expected %q", f.rule.Tag, "go lang")
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	subj := filterSubject{
+		Title:      "Weekly Podcast: Go 1.22",
+		Author:     "Jane Doe",
+		Content:    "This episode is sponsored by Acme.",
+		Link:       "https://example.com/podcast/42",
+		Feed:       "Example Radio",
+		Folder:     "Audio",
+		MediaTypes: []string{"audio/mpeg"},
+	}
+	tests := []struct {
+		name string
+		rule models.FilterRule
+		want bool
+	}{
+		{"keyword any", models.FilterRule{Pattern: "SPONSORED"}, true},
+		{"keyword list", models.FilterRule{Field: "title", Pattern: "rust, go 1.22"}, true},
+		{"keyword wrong field", models.FilterRule{Field: "title", Pattern: "sponsored"}, false},
+		{"feed", models.FilterRule{Field: "feed", Pattern: "example radio"}, true},
+		{"folder", models.FilterRule{Field: "folder", Pattern: "video"}, false},
+		{"regex", models.FilterRule{Field: "link", MatchType: "regex", Pattern: `/podcast/\d+$`}, true},
+		{"regex case sensitive", models.FilterRule{Field: "author", MatchType: "regex", Pattern: "jane"}, false},
+		{"media wildcard", models.FilterRule{MatchType: "media_type", Pattern: "video/*, audio/*"}, true},
+		{"media exact", models.FilterRule{MatchType: "media_type", Pattern: "audio/ogg"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.Name, tc.rule.Action = tc.name, "hide"
+			f, err := compileFilterRule(tc.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.matches(subj); got != tc.want {
+				t.Errorf("matches() = %v, expected %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestFilterNewItems stores entries through the ingest path and checks that
+// each action lands on the matching item only, that disabled rules do
+// nothing, and that one user's rules leave another subscriber alone.
+func TestFilterNewItems(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, feedID := subscribe(t, d, svc, "first@example.com", sourceID)
+		otherID, otherFeed := subscribe(t, d, svc, "second@example.com", sourceID)
+
+		for _, rule := range []models.FilterRule{
+			{Name: "read", Field: "title", Pattern: "changelog", Action: "mark_read", Enabled: true},
+			{Name: "save", Field: "title", Pattern: "tutorial", Action: "bookmark", Enabled: true},
+			{Name: "ads", Field: "title", Pattern: "sponsored", Action: "hide", Enabled: true},
+			{Name: "lang", Field: "title", Pattern: "golang", Action: "tag", Tag: "Go", Enabled: true},
+			{Name: "me", Field: "author", Pattern: "jane", Action: "highlight", Enabled: true},
+			{Name: "off", Field: "title", Pattern: "plain", Action: "hide", Enabled: false},
+		} {
+			if _, err := svc.CreateFilterRule(ctx, userID, rule); err != nil {
+				t.Fatalf("create %s: %v", rule.Name, err)
+			}
+		}
+
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		added, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", []*gofeed.Item{
+			{GUID: "read", Title: "Changelog 1.2"},
+			{GUID: "bookmark", Title: "A tutorial"},
+			{GUID: "hide", Title: "Sponsored post"},
+			{GUID: "tag", Title: "Golang news"},
+			{GUID: "highlight", Title: "Notes", Author: &gofeed.Person{Name: "Jane Doe"}},
+			{GUID: "plain", Title: "A plain post"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sub := range []struct{ userID, feedID int64 }{{userID, feedID}, {otherID, otherFeed}} {
+			if err := svc.filterNewItems(ctx, tx, sub.userID, sub.feedID, added); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		// state returns the item's flags and tags as e.g. "read,tag:Go".
+		state := func(userID int64, guid string) string {
+			t.Helper()
+			var read, bookmarked, hidden, highlighted bool
+			err := d.QueryRow(`
+				SELECT COALESCE(MAX(s.is_read), 0), COALESCE(MAX(s.is_bookmarked), 0),
+				       COALESCE(MAX(s.is_hidden), 0), COALESCE(MAX(s.is_highlighted), 0)
+				FROM entries LEFT JOIN item_state s ON s.item_id = entries.id AND s.user_id = ?
+				WHERE entries.guid = ?`, userID, guid).Scan(&read, &bookmarked, &hidden, &highlighted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for name, set := range map[string]bool{"read": read, "bookmark": bookmarked, "hide": hidden, "highlight": highlighted} {
+				if set {
+					got = append(got, name)
+				}
+			}
+			rows, err := d.Query(`SELECT tag FROM item_tags JOIN entries ON entries.id = item_tags.item_id
+				WHERE item_tags.user_id = ? AND entries.guid = ?`, userID, guid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var tag string
+				if err := rows.Scan(&tag); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, "tag:"+tag)
+			}
+			return strings.Join(got, ",")
+		}
+		for guid, want := range map[string]string{
+			"read":      "read",
+			"bookmark":  "bookmark",
+			"hide":      "hide",
+			"tag":       "tag:Go",
+			"highlight": "highlight",
+			"plain":     "",
+		} {
+			if got := state(userID, guid); got != want {
+				t.Errorf("%s: state = %q, want %q", guid, got, want)
+			}
+			if got := state(otherID, guid); got != "" {
+				t.Errorf("%s: other subscriber state = %q, want none", guid, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,701 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// Google Reader stream and tag IDs. Clients may also send "user/<id>/..."
+// for the user's own streams; normalizeStreamID rewrites those to "user/-/".
+const (
+	GReaderReadingList = "user/-/state/com.google/reading-list"
+	GReaderRead        = "user/-/state/com.google/read"
+	GReaderStarred     = "user/-/state/com.google/starred"
+	GReaderKeptUnread  = "user/-/state/com.google/kept-unread"
+	GReaderLabelPrefix = "user/-/label/"
+	GReaderFeedPrefix  = "feed/"
+
+	greaderItemIDPrefix = "tag:google.com,2005:reader/item/"
+	// greaderMaxItems caps one page of item contents; ID listings may be
+	// longer since clients sync unread and starred IDs in one request.
+	greaderMaxItems   = 1000
+	greaderMaxItemIDs = 10000
+)
+
+var ErrInvalidGReaderRequest = errors.New("invalid google reader request")
+
+// GReaderService maps the Google Reader API onto folders, feeds, items and
+// item state: folders and item tags are labels, bookmarks are starred items.
+type GReaderService struct {
+	db          *db.DB
+	feedService *FeedService
+}
+
+func NewGReaderService(db *db.DB, feedService *FeedService) *GReaderService {
+	return &GReaderService{db: db, feedService: feedService}
+}
+
+// GReaderQuery selects items from a stream. Exclude and Include are stream
+// IDs (xt and it); Since and Until bound the item timestamp (ot and nt).
+type GReaderQuery struct {
+	Stream      string
+	Exclude     []string
+	Include     []string
+	Since       *time.Time
+	Until       *time.Time
+	OldestFirst bool
+	Limit       int
+	Cursor      *ItemCursor
+}
+
+// GReaderItemID is the long form of an item ID used in item contents.
+func GReaderItemID(id int64) string {
+	return fmt.Sprintf("%s%016x", greaderItemIDPrefix, uint64(id))
+}
+
+// ParseGReaderItemID accepts an item ID in long (hex) or short (decimal)
+// form.
+func ParseGReaderItemID(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if hex, ok := strings.CutPrefix(raw, greaderItemIDPrefix); ok {
+		id, err := strconv.ParseUint(hex, 16, 64)
+		return int64(id), err
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+func normalizeStreamID(stream string) string {
+	if rest, ok := strings.CutPrefix(stream, "user/"); ok {
+		if i := strings.Index(rest, "/"); i >= 0 {
+			return "user/-" + rest[i:]
+		}
+	}
+	return stream
+}
+
+func greaderFeedStream(feedID int64) string {
+	return GReaderFeedPrefix + strconv.FormatInt(feedID, 10)
+}
+
+// Subscriptions lists the user's feeds with their folder as the category.
+func (s *GReaderService) Subscriptions(ctx context.Context, userID int64) ([]models.GReaderSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT feeds.id, COALESCE(feeds.title, sources.title, ''), sources.url, COALESCE(sources.site_url, ''), folders.name
+		FROM feeds
+		JOIN sources ON sources.id = feeds.source_id
+		JOIN folders ON folders.id = feeds.folder_id
+		WHERE feeds.user_id=?
+		ORDER BY COALESCE(feeds.title, sources.title)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subs := []models.GReaderSubscription{}
+	for rows.Next() {
+		var (
+			id     int64
+			sub    models.GReaderSubscription
+			folder string
+		)
+		if err := rows.Scan(&id, &sub.Title, &sub.URL, &sub.HTMLURL, &folder); err != nil {
+			return nil, err
+		}
+		sub.ID = greaderFeedStream(id)
+		sub.Categories = []models.GReaderCategory{{ID: GReaderLabelPrefix + folder, Label: folder}}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Tags lists the starred state, folders and item tags.
+func (s *GReaderService) Tags(ctx context.Context, userID int64) ([]models.GReaderTag, error) {
+	tags := []models.GReaderTag{{ID: GReaderStarred}}
+	folders, err := s.queryStrings(ctx, `SELECT name FROM folders WHERE user_id=? ORDER BY name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, name := range folders {
+		seen[name] = true
+		tags = append(tags, models.GReaderTag{ID: GReaderLabelPrefix + name, Type: "folder"})
+	}
+	itemTags, err := s.queryStrings(ctx, `SELECT DISTINCT tag FROM item_tags WHERE user_id=? ORDER BY tag`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range itemTags {
+		if !seen[name] {
+			tags = append(tags, models.GReaderTag{ID: GReaderLabelPrefix + name, Type: "tag"})
+		}
+	}
+	return tags, nil
+}
+
+// UnreadCounts returns unread counts per feed, per folder and for the
+// reading list, with the newest unread item's timestamp.
+func (s *GReaderService) UnreadCounts(ctx context.Context, userID int64) ([]models.GReaderUnreadCount, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT feeds.id, folders.name, COUNT(items.id), MAX(%s)
+		FROM items
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN folders ON folders.id = feeds.folder_id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		WHERE items.user_id=? AND COALESCE(item_state.is_read,0)=0 AND COALESCE(item_state.is_hidden,0)=0
+		GROUP BY feeds.id, folders.name`, itemTimeExpr(s.db.Dialect())), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		counts      []models.GReaderUnreadCount
+		folderOrder []string
+		folders     = map[string]*models.GReaderUnreadCount{}
+		all         = models.GReaderUnreadCount{ID: GReaderReadingList}
+		allNewest   int64
+		newest      = map[string]int64{}
+	)
+	for rows.Next() {
+		var (
+			feedID       int64
+			folder       string
+			count        int
+			feedNewestTS int64
+		)
+		if err := rows.Scan(&feedID, &folder, &count, &feedNewestTS); err != nil {
+			return nil, err
+		}
+		counts = append(counts, models.GReaderUnreadCount{
+			ID: greaderFeedStream(feedID), Count: count, NewestItemTimestampUsec: usec(feedNewestTS),
+		})
+		label := GReaderLabelPrefix + folder
+		if folders[label] == nil {
+			folders[label] = &models.GReaderUnreadCount{ID: label}
+			folderOrder = append(folderOrder, label)
+		}
+		folders[label].Count += count
+		newest[label] = max(newest[label], feedNewestTS)
+		all.Count += count
+		allNewest = max(allNewest, feedNewestTS)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, label := range folderOrder {
+		c := *folders[label]
+		c.NewestItemTimestampUsec = usec(newest[label])
+		counts = append(counts, c)
+	}
+	all.NewestItemTimestampUsec = usec(allNewest)
+	return append(counts, all), nil
+}
+
+// StreamItemIDs returns references to the items in a stream and the cursor
+// for the next page, if any.
+func (s *GReaderService) StreamItemIDs(ctx context.Context, userID int64, q GReaderQuery) ([]models.GReaderItemRef, *ItemCursor, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	q.Limit = min(q.Limit, greaderMaxItemIDs)
+	ids, stamps, next, err := s.streamIDs(ctx, userID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	refs := make([]models.GReaderItemRef, len(ids))
+	for i, id := range ids {
+		refs[i] = models.GReaderItemRef{
+			ID:              strconv.FormatInt(id, 10),
+			DirectStreamIDs: []string{},
+			TimestampUsec:   usec(stamps[i]),
+		}
+	}
+	return refs, next, nil
+}
+
+// StreamItems returns a page of a stream's items and the cursor for the
+// next page, if any.
+func (s *GReaderService) StreamItems(ctx context.Context, userID int64, q GReaderQuery) ([]models.GReaderItem, *ItemCursor, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	q.Limit = min(q.Limit, greaderMaxItems)
+	ids, _, next, err := s.streamIDs(ctx, userID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := s.Items(ctx, userID, ids)
+	return items, next, err
+}
+
+func (s *GReaderService) streamIDs(ctx context.Context, userID int64, q GReaderQuery) ([]int64, []int64, *ItemCursor, error) {
+	clauses := []string{"items.user_id=?", "COALESCE(item_state.is_hidden,0)=0"}
+	args := []interface{}{userID}
+	add := func(stream string, exclude bool) error {
+		clause, clauseArgs, err := s.streamFilter(ctx, userID, stream)
+		if err != nil {
+			return err
+		}
+		if clause == "" {
+			return nil
+		}
+		if exclude {
+			clause = "NOT (" + clause + ")"
+		}
+		clauses = append(clauses, clause)
+		args = append(args, clauseArgs...)
+		return nil
+	}
+	if err := add(q.Stream, false); err != nil {
+		return nil, nil, nil, err
+	}
+	for _, stream := range q.Include {
+		if err := add(stream, false); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	for _, stream := range q.Exclude {
+		if err := add(stream, true); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	orderExpr := itemTimeExpr(s.db.Dialect())
+	if q.Since != nil {
+		clauses = append(clauses, orderExpr+" >= ?")
+		args = append(args, q.Since.Unix())
+	}
+	if q.Until != nil {
+		clauses = append(clauses, orderExpr+" <= ?")
+		args = append(args, q.Until.Unix())
+	}
+	orderDir, cursorOp := "DESC", "<"
+	if q.OldestFirst {
+		orderDir, cursorOp = "ASC", ">"
+	}
+	if q.Cursor != nil {
+		clauses = append(clauses, fmt.Sprintf("(%s %s ? OR (%s = ? AND items.id %s ?))", orderExpr, cursorOp, orderExpr, cursorOp))
+		args = append(args, q.Cursor.Timestamp, q.Cursor.Timestamp, q.Cursor.ID)
+	}
+	args = append(args, q.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT items.id, %s
+		FROM items
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		JOIN feeds ON feeds.id = items.feed_id
+		WHERE %s
+		ORDER BY %s %s, items.id %s
+		LIMIT ?`, orderExpr, strings.Join(clauses, " AND "), orderExpr, orderDir, orderDir), args...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+	var ids, stamps []int64
+	for rows.Next() {
+		var id, ts int64
+		if err := rows.Scan(&id, &ts); err != nil {
+			return nil, nil, nil, err
+		}
+		ids = append(ids, id)
+		stamps = append(stamps, ts)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+	var next *ItemCursor
+	if len(ids) > q.Limit {
+		ids, stamps = ids[:q.Limit], stamps[:q.Limit]
+		next = &ItemCursor{Timestamp: stamps[q.Limit-1], ID: ids[q.Limit-1]}
+	}
+	return ids, stamps, next, nil
+}
+
+// streamFilter returns the SQL condition selecting a stream's items over
+// items, item_state and feeds. The reading list selects everything.
+func (s *GReaderService) streamFilter(ctx context.Context, userID int64, stream string) (string, []interface{}, error) {
+	stream = normalizeStreamID(stream)
+	switch {
+	case stream == "" || stream == GReaderReadingList:
+		return "", nil, nil
+	case stream == GReaderRead:
+		return "COALESCE(item_state.is_read,0)=1", nil, nil
+	case stream == GReaderKeptUnread:
+		return "COALESCE(item_state.is_read,0)=0", nil, nil
+	case stream == GReaderStarred:
+		return "COALESCE(item_state.is_bookmarked,0)=1", nil, nil
+	case strings.HasPrefix(stream, GReaderFeedPrefix):
+		feedID, err := s.feedForStream(ctx, userID, stream)
+		if err != nil {
+			return "", nil, err
+		}
+		return "items.feed_id=?", []interface{}{feedID}, nil
+	case strings.HasPrefix(stream, GReaderLabelPrefix):
+		label := strings.TrimPrefix(stream, GReaderLabelPrefix)
+		folderID, err := s.folderByName(ctx, userID, label)
+		if err != nil {
+			return "", nil, err
+		}
+		if folderID != 0 {
+			return "feeds.folder_id=?", []interface{}{folderID}, nil
+		}
+		return "items.id IN (SELECT item_id FROM item_tags WHERE user_id=? AND tag=?)", []interface{}{userID, label}, nil
+	}
+	return "", nil, fmt.Errorf("%w: unknown stream %q", ErrInvalidGReaderRequest, stream)
+}
+
+// feedForStream resolves "feed/<id>" or "feed/<url>" to one of the user's
+// feeds.
+func (s *GReaderService) feedForStream(ctx context.Context, userID int64, stream string) (int64, error) {
+	ref := strings.TrimPrefix(stream, GReaderFeedPrefix)
+	var feedID int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT feeds.id FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=? AND (CAST(feeds.id AS TEXT)=? OR sources.url=?)`, userID, ref, ref).Scan(&feedID)
+	return feedID, err
+}
+
+// folderByName returns the ID of the user's folder with the name, or zero.
+func (s *GReaderService) folderByName(ctx context.Context, userID int64, name string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM folders WHERE user_id=? AND name=? ORDER BY id LIMIT 1`, userID, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// Items returns the user's items with the given IDs in that order, skipping
+// IDs that are not theirs.
+func (s *GReaderService) Items(ctx context.Context, userID int64, ids []int64) ([]models.GReaderItem, error) {
+	items := []models.GReaderItem{}
+	if len(ids) == 0 {
+		return items, nil
+	}
+	args := []interface{}{userID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT items.id, items.feed_id, COALESCE(items.title, ''), COALESCE(items.author, ''), COALESCE(items.link, ''),
+			COALESCE(NULLIF(items.content_html, ''), items.summary_text, ''), items.published_at, items.created_at,
+			COALESCE(item_state.is_read,0), COALESCE(item_state.is_bookmarked,0),
+			COALESCE(feeds.title, sources.title, ''), COALESCE(sources.site_url, ''), folders.name
+		FROM items
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		JOIN feeds ON feeds.id = items.feed_id
+		JOIN sources ON sources.id = feeds.source_id
+		JOIN folders ON folders.id = feeds.folder_id
+		WHERE items.user_id=? AND items.id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]models.GReaderItem{}
+	var page []models.Item // for attachTags
+	for rows.Next() {
+		var (
+			it                 models.GReaderItem
+			id, feedID         int64
+			link, content      string
+			published          sql.NullTime
+			created            time.Time
+			isRead, isStarred  bool
+			feedTitle, siteURL string
+			folder             string
+		)
+		if err := rows.Scan(&id, &feedID, &it.Title, &it.Author, &link, &content, &published, &created,
+			&isRead, &isStarred, &feedTitle, &siteURL, &folder); err != nil {
+			return nil, err
+		}
+		ts := created
+		if published.Valid {
+			ts = published.Time
+		}
+		it.ID = GReaderItemID(id)
+		it.CrawlTimeMsec = strconv.FormatInt(created.UnixMilli(), 10)
+		it.TimestampUsec = usec(ts.Unix())
+		it.Published = ts.Unix()
+		it.Updated = ts.Unix()
+		it.Canonical = []models.GReaderLink{{Href: link}}
+		it.Alternate = []models.GReaderLink{{Href: link, Type: "text/html"}}
+		it.Summary = models.GReaderContent{Direction: "ltr", Content: content}
+		it.Origin = models.GReaderOrigin{StreamID: greaderFeedStream(feedID), Title: feedTitle, HTMLURL: siteURL}
+		it.Categories = []string{GReaderReadingList, GReaderLabelPrefix + folder}
+		if isRead {
+			it.Categories = append(it.Categories, GReaderRead)
+		}
+		if isStarred {
+			it.Categories = append(it.Categories, GReaderStarred)
+		}
+		byID[id] = it
+		page = append(page, models.Item{ID: id})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.feedService.attachTags(ctx, userID, page); err != nil {
+		return nil, err
+	}
+	for _, tagged := range page {
+		it := byID[tagged.ID]
+		for _, tag := range tagged.Tags {
+			it.Categories = append(it.Categories, GReaderLabelPrefix+tag)
+		}
+		byID[tagged.ID] = it
+	}
+	for _, id := range ids {
+		if it, ok := byID[id]; ok {
+			items = append(items, it)
+		}
+	}
+	return items, nil
+}
+
+// EditTags adds and removes tags on items: the read and starred states, and
+// labels, which become item tags. All changes are made in one transaction
+// and announced with a single event.
+func (s *GReaderService) EditTags(ctx context.Context, userID int64, ids []int64, add, remove []string) error {
+	var (
+		read, starred           *bool
+		addLabels, removeLabels []string
+	)
+	collect := func(tags []string, on bool) error {
+		for _, tag := range tags {
+			switch tag = normalizeStreamID(tag); {
+			case tag == GReaderRead:
+				v := on
+				read = &v
+			case tag == GReaderKeptUnread:
+				v := !on
+				read = &v
+			case tag == GReaderStarred:
+				v := on
+				starred = &v
+			case strings.HasPrefix(tag, GReaderLabelPrefix):
+				label, err := normalizeTag(strings.TrimPrefix(tag, GReaderLabelPrefix))
+				if err != nil {
+					return err
+				}
+				if on {
+					addLabels = append(addLabels, label)
+				} else {
+					removeLabels = append(removeLabels, label)
+				}
+			}
+		}
+		return nil
+	}
+	if err := collect(add, true); err != nil {
+		return err
+	}
+	if err := collect(remove, false); err != nil {
+		return err
+	}
+	if len(ids) == 0 || (read == nil && starred == nil && len(addLabels) == 0 && len(removeLabels) == 0) {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	owned, err := s.ownedItems(ctx, tx, userID, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range owned {
+		if read != nil {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO item_state(item_id, user_id, is_read) VALUES(?, ?, ?)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET is_read=excluded.is_read`, id, userID, boolToInt(*read)); err != nil {
+				return err
+			}
+		}
+		if starred != nil {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO item_state(item_id, user_id, is_bookmarked, bookmarked_at) VALUES(?, ?, ?, CASE WHEN ?=1 THEN CURRENT_TIMESTAMP ELSE NULL END)
+				ON CONFLICT(user_id, item_id) DO UPDATE SET is_bookmarked=excluded.is_bookmarked,
+				bookmarked_at=CASE WHEN excluded.is_bookmarked=1 THEN CURRENT_TIMESTAMP ELSE NULL END`,
+				id, userID, boolToInt(*starred), boolToInt(*starred)); err != nil {
+				return err
+			}
+		}
+		for _, label := range addLabels {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO item_tags(item_id, user_id, tag) VALUES(?, ?, ?)
+				ON CONFLICT(user_id, item_id, tag) DO NOTHING`, id, userID, label); err != nil {
+				return err
+			}
+		}
+		for _, label := range removeLabels {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM item_tags WHERE item_id=? AND user_id=? AND tag=?`, id, userID, label); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(owned) > 0 && (read != nil || starred != nil) {
+		data := map[string]interface{}{"itemIds": owned}
+		if read != nil {
+			data["isRead"] = *read
+		}
+		if starred != nil {
+			data["isBookmarked"] = *starred
+		}
+		s.feedService.events.Publish(userID, Event{Type: EventItemState, Data: data})
+	}
+	return nil
+}
+
+// ownedItems returns the ids that are items of the user.
+func (s *GReaderService) ownedItems(ctx context.Context, q dbtx, userID int64, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := []interface{}{userID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return s.feedService.queryIDsTx(ctx, q, `SELECT id FROM items WHERE user_id=? AND id IN (`+
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+`)`, args...)
+}
+
+// MarkAllRead marks a feed, label or the reading list read up to the cutoff.
+func (s *GReaderService) MarkAllRead(ctx context.Context, userID int64, stream string, before *time.Time) error {
+	scope := MarkReadScope{Before: before}
+	switch stream = normalizeStreamID(stream); {
+	case stream == GReaderReadingList:
+	case strings.HasPrefix(stream, GReaderFeedPrefix):
+		feedID, err := s.feedForStream(ctx, userID, stream)
+		if err != nil {
+			return err
+		}
+		scope.FeedID = &feedID
+	case strings.HasPrefix(stream, GReaderLabelPrefix):
+		label := strings.TrimPrefix(stream, GReaderLabelPrefix)
+		folderID, err := s.folderByName(ctx, userID, label)
+		if err != nil {
+			return err
+		}
+		if folderID != 0 {
+			scope.FolderID = &folderID
+		} else {
+			scope.Tag = label
+		}
+	default:
+		return fmt.Errorf("%w: cannot mark %q as read", ErrInvalidGReaderRequest, stream)
+	}
+	_, err := s.feedService.MarkAllRead(ctx, userID, scope)
+	return err
+}
+
+// Subscribe adds a feed to the folder named by label, creating the folder if
+// needed, or to the user's first folder. A title renames the feed.
+func (s *GReaderService) Subscribe(ctx context.Context, userID int64, feedURL, title, label string) (models.Feed, error) {
+	folderID, err := s.folderFor(ctx, userID, label)
+	if err != nil {
+		return models.Feed{}, err
+	}
+	feed, err := s.feedService.AddFeed(ctx, userID, folderID, feedURL)
+	if err != nil {
+		return models.Feed{}, err
+	}
+	if title != "" {
+		if _, err := s.db.ExecContext(ctx, `UPDATE feeds SET title=? WHERE id=? AND user_id=?`, title, feed.ID, userID); err != nil {
+			return models.Feed{}, err
+		}
+		feed.Title = title
+	}
+	return feed, nil
+}
+
+// Unsubscribe removes the feed named by stream.
+func (s *GReaderService) Unsubscribe(ctx context.Context, userID int64, stream string) error {
+	feedID, err := s.feedForStream(ctx, userID, stream)
+	if err != nil {
+		return err
+	}
+	return s.feedService.DeleteFeed(ctx, userID, feedID)
+}
+
+// EditSubscription renames a feed and moves it to the folder named by label.
+// Removing a label without adding one leaves the feed where it is, since
+// every feed lives in a folder.
+func (s *GReaderService) EditSubscription(ctx context.Context, userID int64, stream, title, label string) error {
+	feedID, err := s.feedForStream(ctx, userID, stream)
+	if err != nil {
+		return err
+	}
+	if title != "" {
+		if _, err := s.db.ExecContext(ctx, `UPDATE feeds SET title=? WHERE id=? AND user_id=?`, title, feedID, userID); err != nil {
+			return err
+		}
+	}
+	if label != "" {
+		folderID, err := s.folderFor(ctx, userID, label)
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE feeds SET folder_id=? WHERE id=? AND user_id=?`, folderID, feedID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// folderFor returns the folder a label names, creating it if needed. An
+// empty label means the user's first folder.
+func (s *GReaderService) folderFor(ctx context.Context, userID int64, label string) (int64, error) {
+	name := strings.TrimPrefix(normalizeStreamID(label), GReaderLabelPrefix)
+	if name == "" {
+		first, err := s.feedService.GetFirstFolder(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		if first != nil {
+			return first.ID, nil
+		}
+		name = "Subscriptions"
+	}
+	folderID, err := s.folderByName(ctx, userID, name)
+	if err != nil || folderID != 0 {
+		return folderID, err
+	}
+	folder, err := s.feedService.CreateFolder(ctx, userID, name)
+	return folder.ID, err
+}
+
+func (s *GReaderService) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// usec formats Unix seconds as the microsecond strings the API uses.
+func usec(unix int64) string {
+	return strconv.FormatInt(unix*1_000_000, 10)
+}
@@ -0,0 +1,34 @@
+package services
+
+import "testing"
+
+func TestGReaderItemID(t *testing.T) {
+	long := GReaderItemID(255)
+	if long != "tag:google.com,2005:reader/item/00000000000000ff" {
+		t.Errorf("GReaderItemID(255) = %q", long)
+	}
+	for _, raw := range []string{long, "255", " 255 "} {
+		id, err := ParseGReaderItemID(raw)
+		if err != nil || id != 255 {
+			t.Errorf("ParseGReaderItemID(%q) = %d, %v, expected 255", raw, id, err)
+		}
+	}
+	if _, err := ParseGReaderItemID("tag:google.com,2005:reader/item/xyz"); err == nil {
+		t.Error("expected error for bad hex id")
+	}
+}
+
+func TestNormalizeStreamID(t *testing.T) {
+	tests := map[string]string{
+		"user/-/state/com.google/read":    GReaderRead,
+		"user/1234/state/com.google/read": GReaderRead,
+		"user/1234/label/Tech":            GReaderLabelPrefix + "Tech",
+		"feed/12":                         "feed/12",
+		"user/":                           "user/",
+	}
+	for input, want := range tests {
+		if got := normalizeStreamID(input); got != want {
+			t.Errorf("normalizeStreamID(%q) = %q, expected %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// publishItemLimit is how many items a published feed carries.
+const publishItemLimit = 50
+
+// PublishService renders a user's folders, feeds, saved searches, tags,
+// bookmarks and top news as JSON Feed or Atom for other readers. Published feeds are
+// authenticated by a per-user token embedded in the URL, since feed readers
+// cannot log in.
+type PublishService struct {
+	db             *db.DB
+	feedService    *FeedService
+	topNewsService *TopNewsService
+}
+
+func NewPublishService(db *db.DB, feedService *FeedService, topNewsService *TopNewsService) *PublishService {
+	return &PublishService{db: db, feedService: feedService, topNewsService: topNewsService}
+}
+
+// Stream is a list of items with the metadata needed to publish it.
+type Stream struct {
+	ID      string // stable, token-free identifier for the Atom <id>
+	Title   string
+	HomeURL string
+	Items   []models.Item
+}
+
+// Token returns the user's publish token, or "" if they have not created
+// one yet.
+func (s *PublishService) Token(ctx context.Context, userID int64) (string, error) {
+	var token string
+	err := s.db.QueryRowContext(ctx, `SELECT token FROM publish_tokens WHERE user_id=?`, userID).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return token, err
+}
+
+// RotateToken creates the user's publish token or replaces it, breaking
+// every URL that embedded the old one.
+func (s *PublishService) RotateToken(ctx context.Context, userID int64) (string, error) {
+	token, err := generateToken(24)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO publish_tokens(user_id, token, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET token=excluded.token, created_at=excluded.created_at`,
+		userID, token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// UserForToken resolves a publish token to its owner.
+func (s *PublishService) UserForToken(ctx context.Context, token string) (int64, error) {
+	if token == "" {
+		return 0, ErrInvalidToken
+	}
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `SELECT user_id FROM publish_tokens WHERE token=?`, token).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	return userID, err
+}
+
+func (s *PublishService) FolderStream(ctx context.Context, userID, folderID int64) (Stream, error) {
+	var name string
+	if err := s.db.QueryRowContext(ctx, `SELECT name FROM folders WHERE id=? AND user_id=?`, folderID, userID).Scan(&name); err != nil {
+		return Stream{}, err
+	}
+	items, _, err := s.feedService.ListItems(ctx, userID, &folderID, nil, "", false, publishItemLimit, nil, "")
+	if err != nil {
+		return Stream{}, err
+	}
+	return Stream{ID: fmt.Sprintf("urn:rss-feed-manager:folder:%d", folderID), Title: name, Items: items}, nil
+}
+
+func (s *PublishService) FeedStream(ctx context.Context, userID, feedID int64) (Stream, error) {
+	var title, siteURL sql.NullString
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(feeds.title, sources.title), sources.site_url
+		FROM feeds JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.id=? AND feeds.user_id=?`, feedID, userID).
+		Scan(&title, &siteURL); err != nil {
+		return Stream{}, err
+	}
+	items, _, err := s.feedService.ListItems(ctx, userID, nil, &feedID, "", false, publishItemLimit, nil, "")
+	if err != nil {
+		return Stream{}, err
+	}
+	return Stream{
+		ID:      fmt.Sprintf("urn:rss-feed-manager:feed:%d", feedID),
+		Title:   title.String,
+		HomeURL: siteURL.String,
+		Items:   items,
+	}, nil
+}
+
+func (s *PublishService) SavedSearchStream(ctx context.Context, userID, id int64) (Stream, error) {
+	ss, err := s.feedService.GetSavedSearch(ctx, userID, id)
+	if err != nil {
+		return Stream{}, err
+	}
+	items, _, err := s.feedService.ListSavedSearchItems(ctx, userID, id, publishItemLimit, nil, "")
+	if err != nil {
+		return Stream{}, err
+	}
+	return Stream{ID: fmt.Sprintf("urn:rss-feed-manager:saved-search:%d", id), Title: ss.Name, Items: items}, nil
+}
+
+func (s *PublishService) TagStream(ctx context.Context, userID int64, tag string) (Stream, error) {
+	items, _, err := s.feedService.ListItems(ctx, userID, nil, nil, tag, false, publishItemLimit, nil, "")
+	if err != nil {
+		return Stream{}, err
+	}
+	if len(items) == 0 {
+		return Stream{}, sql.ErrNoRows
+	}
+	return Stream{ID: fmt.Sprintf("urn:rss-feed-manager:user:%d:tag:%s", userID, url.PathEscape(tag)), Title: tag, Items: items}, nil
+}
+
+func (s *PublishService) BookmarksStream(ctx context.Context, userID int64) (Stream, error) {
+	items, _, err := s.feedService.ListBookmarks(ctx, userID, "", publishItemLimit, nil, "")
+	if err != nil {
+		return Stream{}, err
+	}
+	return Stream{ID: fmt.Sprintf("urn:rss-feed-manager:user:%d:bookmarks", userID), Title: "Bookmarks", Items: items}, nil
+}
+
+func (s *PublishService) TopNewsStream(ctx context.Context, userID int64) (Stream, error) {
+	items, _, _, _, err := s.topNewsService.GetTopNews(ctx, userID, publishItemLimit)
+	if err != nil {
+		return Stream{}, err
+	}
+	if err := s.feedService.attachTags(ctx, userID, items); err != nil {
+		return Stream{}, err
+	}
+	return Stream{ID: fmt.Sprintf("urn:rss-feed-manager:user:%d:top-news", userID), Title: "Top News", Items: items}, nil
+}
+
+// RenderJSONFeed renders the stream as JSON Feed 1.1; selfURL is the URL the
+// document is served from.
+func RenderJSONFeed(stream Stream, selfURL string) ([]byte, error) {
+	doc := models.JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       stream.Title,
+		HomePageURL: stream.HomeURL,
+		FeedURL:     selfURL,
+		Items:       make([]models.JSONFeedItem, 0, len(stream.Items)),
+	}
+	for _, it := range stream.Items {
+		entry := models.JSONFeedItem{
+			ID:          publishedItemID(it),
+			URL:         it.Link,
+			Title:       it.Title,
+			ContentHTML: it.ContentHTML,
+			Summary:     it.SummaryText,
+			Tags:        it.Tags,
+		}
+		if entry.ContentHTML == "" {
+			// An item must carry content_html or content_text.
+			entry.ContentText = firstNonEmpty(it.SummaryText, it.Title)
+		}
+		if published := publishedAt(it); !published.IsZero() {
+			entry.DatePublished = published.Format(time.RFC3339)
+		}
+		if it.Author != "" {
+			entry.Authors = []models.JSONFeedAuthor{{Name: it.Author}}
+		}
+		for _, m := range itemMedia(it) {
+			if entry.Image == "" && strings.HasPrefix(m.Type, "image/") {
+				entry.Image = m.URL
+				continue
+			}
+			if m.Type == "" {
+				continue
+			}
+			size, _ := strconv.ParseInt(m.Length, 10, 64)
+			entry.Attachments = append(entry.Attachments, models.JSONFeedAttachment{URL: m.URL, MimeType: m.Type, SizeInBytes: size})
+		}
+		doc.Items = append(doc.Items, entry)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// RenderAtom renders the stream as an Atom 1.0 document.
+func RenderAtom(stream Stream, selfURL string) ([]byte, error) {
+	doc := models.AtomFeed{
+		ID:    stream.ID,
+		Title: stream.Title,
+		Links: []models.AtomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}},
+	}
+	if stream.HomeURL != "" {
+		doc.Links = append(doc.Links, models.AtomLink{Href: stream.HomeURL, Rel: "alternate", Type: "text/html"})
+	}
+	var latest time.Time
+	for _, it := range stream.Items {
+		updated := publishedAt(it)
+		if updated.After(latest) {
+			latest = updated
+		}
+		entry := models.AtomEntry{
+			ID:      publishedItemID(it),
+			Title:   it.Title,
+			Updated: updated.UTC().Format(time.RFC3339),
+		}
+		if it.PublishedAt != nil {
+			entry.Published = it.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		if it.Link != "" {
+			entry.Links = append(entry.Links, models.AtomLink{Href: it.Link, Rel: "alternate"})
+		}
+		for _, m := range itemMedia(it) {
+			if m.Type != "" {
+				entry.Links = append(entry.Links, models.AtomLink{Href: m.URL, Rel: "enclosure", Type: m.Type, Length: m.Length})
+			}
+		}
+		if it.Author != "" {
+			entry.Author = &models.AtomPerson{Name: it.Author}
+		}
+		if it.SummaryText != "" {
+			entry.Summary = &models.AtomText{Type: "text", Body: it.SummaryText}
+		}
+		if it.ContentHTML != "" {
+			entry.Content = &models.AtomText{Type: "html", Body: it.ContentHTML}
+		}
+		for _, tag := range it.Tags {
+			entry.Categories = append(entry.Categories, models.AtomCategory{Term: tag})
+		}
+		if it.Source != nil && (it.Source.Title != "" || it.Source.SiteURL != "") {
+			entry.Source = &models.AtomSource{Title: it.Source.Title}
+			if it.Source.SiteURL != "" {
+				entry.Source.Links = []models.AtomLink{{Href: it.Source.SiteURL, Rel: "alternate"}}
+			}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	doc.Updated = latest.UTC().Format(time.RFC3339)
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// publishedItemID keeps the source GUID when it is already a URI, as Atom
+// requires, and otherwise mints a URN from the item's ID.
+func publishedItemID(it models.Item) string {
+	if u, err := url.Parse(it.GUID); err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "") {
+		return it.GUID
+	}
+	return fmt.Sprintf("urn:rss-feed-manager:item:%d", it.ID)
+}
+
+func publishedAt(it models.Item) time.Time {
+	if it.PublishedAt != nil {
+		return *it.PublishedAt
+	}
+	return it.CreatedAt
+}
+
+func itemMedia(it models.Item) []models.Media {
+	if it.MediaJSON == "" {
+		return nil
+	}
+	var media []models.Media
+	if err := json.Unmarshal([]byte(it.MediaJSON), &media); err != nil {
+		return nil
+	}
+	return media
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
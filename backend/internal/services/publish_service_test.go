@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+func testStream() Stream {
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return Stream{
+		ID:      "urn:rss-feed-manager:folder:1",
+		Title:   "Tech",
+		HomeURL: "https://example.com",
+		Items: []models.Item{
+			{
+				ID: 7, GUID: "https://example.com/posts/1", Link: "https://example.com/posts/1",
+				Title: "First", Author: "Ann", PublishedAt: &published, ContentHTML: "<p>hi</p>", Tags: []string{"go", "News"},
+				MediaJSON: `[{"url":"https://example.com/a.mp3","length":"123","type":"audio/mpeg"},{"url":"https://example.com/a.jpg","type":"image/jpeg"}]`,
+			},
+			{ID: 8, GUID: "not a uri", Title: "Second", SummaryText: "plain", CreatedAt: published.Add(time.Hour)},
+		},
+	}
+}
+
+func TestRenderJSONFeed(t *testing.T) {
+	data, err := RenderJSONFeed(testStream(), "https://reader.test/feed.json")
+	if err != nil {
+		t.Fatalf("RenderJSONFeed: %v", err)
+	}
+	var doc models.JSONFeed
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.Version != "https://jsonfeed.org/version/1.1" || doc.FeedURL != "https://reader.test/feed.json" {
+		t.Errorf("unexpected header: %+v", doc)
+	}
+	if len(doc.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(doc.Items))
+	}
+	first, second := doc.Items[0], doc.Items[1]
+	if first.ID != "https://example.com/posts/1" || first.Image != "https://example.com/a.jpg" {
+		t.Errorf("first item = %+v", first)
+	}
+	if len(first.Attachments) != 1 || first.Attachments[0].SizeInBytes != 123 {
+		t.Errorf("attachments = %+v", first.Attachments)
+	}
+	if len(first.Tags) != 2 || first.Tags[1] != "News" || second.Tags != nil {
+		t.Errorf("tags = %v / %v", first.Tags, second.Tags)
+	}
+	if second.ID != "urn:rss-feed-manager:item:8" || second.ContentText != "plain" {
+		t.Errorf("second item = %+v", second)
+	}
+}
+
+func TestRenderAtom(t *testing.T) {
+	data, err := RenderAtom(testStream(), "https://reader.test/feed.atom")
+	if err != nil {
+		t.Fatalf("RenderAtom: %v", err)
+	}
+	var doc models.AtomFeed
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.ID != "urn:rss-feed-manager:folder:1" || doc.Updated != "2024-03-01T13:00:00Z" {
+		t.Errorf("unexpected header: id=%q updated=%q", doc.ID, doc.Updated)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(doc.Entries))
+	}
+	if links := doc.Entries[0].Links; len(links) != 3 || links[1].Rel != "enclosure" {
+		t.Errorf("first entry links = %+v", links)
+	}
+	if cats := doc.Entries[0].Categories; len(cats) != 2 || cats[0].Term != "go" || len(doc.Entries[1].Categories) != 0 {
+		t.Errorf("categories = %+v / %+v", cats, doc.Entries[1].Categories)
+	}
+	if doc.Entries[1].Published != "" || doc.Entries[1].Updated != "2024-03-01T13:00:00Z" {
+		t.Errorf("second entry dates = %q / %q", doc.Entries[1].Published, doc.Entries[1].Updated)
+	}
+}
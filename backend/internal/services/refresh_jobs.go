@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+const (
+	// refreshJobRetention is how long finished jobs can still be polled.
+	refreshJobRetention = time.Hour
+	// refreshJobTimeout bounds a whole background refresh run.
+	refreshJobTimeout = 30 * time.Minute
+)
+
+// refreshJobs tracks background refresh runs in memory. At most one
+// refresh-all job runs per user; starting another returns the running one.
+type refreshJobs struct {
+	mu     sync.Mutex
+	jobs   map[string]*refreshJob
+	active map[int64]string // user ID -> running refresh-all job
+}
+
+type refreshJob struct {
+	userID int64
+	job    models.RefreshJob
+	index  map[int64]int // feed ID -> position in job.Feeds
+}
+
+func newRefreshJobs() *refreshJobs {
+	return &refreshJobs{jobs: make(map[string]*refreshJob), active: make(map[int64]string)}
+}
+
+// StartRefreshAll starts refreshing every unpaused feed of the user in the
+// background and returns the job. If one is already running it is returned
+// instead, with started false.
+func (s *FeedService) StartRefreshAll(ctx context.Context, userID int64) (models.RefreshJob, bool, error) {
+	s.jobs.mu.Lock()
+	if id, ok := s.jobs.active[userID]; ok {
+		job := s.jobs.jobs[id].snapshot()
+		s.jobs.mu.Unlock()
+		return job, false, nil
+	}
+	s.jobs.mu.Unlock()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT feeds.id, COALESCE(feeds.title, sources.title) FROM feeds
+		JOIN sources ON sources.id = feeds.source_id
+		WHERE feeds.user_id=? AND feeds.paused=0 ORDER BY feeds.id`, userID)
+	if err != nil {
+		return models.RefreshJob{}, false, err
+	}
+	var (
+		feedIDs []int64
+		entries []models.RefreshJobFeed
+	)
+	for rows.Next() {
+		var (
+			id    int64
+			title sql.NullString
+		)
+		if err := rows.Scan(&id, &title); err != nil {
+			rows.Close()
+			return models.RefreshJob{}, false, err
+		}
+		feedIDs = append(feedIDs, id)
+		entries = append(entries, models.RefreshJobFeed{FeedID: id, Title: title.String, Status: "pending"})
+	}
+	if err := rows.Close(); err != nil {
+		return models.RefreshJob{}, false, err
+	}
+
+	id, err := generateToken(8)
+	if err != nil {
+		return models.RefreshJob{}, false, err
+	}
+	rj := &refreshJob{
+		userID: userID,
+		job: models.RefreshJob{
+			ID:        id,
+			Status:    "running",
+			Total:     len(feedIDs),
+			Feeds:     entries,
+			StartedAt: time.Now(),
+		},
+		index: make(map[int64]int, len(entries)),
+	}
+	for i, e := range entries {
+		rj.index[e.FeedID] = i
+	}
+
+	s.jobs.mu.Lock()
+	// Another request may have started a job while feeds were loading.
+	if running, ok := s.jobs.active[userID]; ok {
+		job := s.jobs.jobs[running].snapshot()
+		s.jobs.mu.Unlock()
+		return job, false, nil
+	}
+	s.jobs.pruneLocked(time.Now())
+	s.jobs.jobs[id] = rj
+	s.jobs.active[userID] = id
+	job := rj.snapshot()
+	s.jobs.mu.Unlock()
+
+	go s.runRefreshJob(rj, feedIDs)
+	return job, true, nil
+}
+
+func (s *FeedService) runRefreshJob(rj *refreshJob, feedIDs []int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshJobTimeout)
+	defer cancel()
+
+	s.refreshWithEvents(ctx, rj.userID, nil, rj.job.ID, feedIDs, func(p RefreshProgress) {
+		s.jobs.mu.Lock()
+		defer s.jobs.mu.Unlock()
+		rj.job.Completed = p.Completed
+		rj.job.Failed = p.Failed
+		rj.job.NewItems += p.Last.NewItems
+		if i, ok := rj.index[p.Last.FeedID]; ok {
+			entry := &rj.job.Feeds[i]
+			entry.Status = "done"
+			entry.Fetched = p.Last.ItemsFetched
+			entry.NewItems = p.Last.NewItems
+			if p.Last.Err != nil {
+				entry.Status = "failed"
+				entry.Error = p.Last.Err.Error()
+			}
+		}
+	})
+
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	now := time.Now()
+	rj.job.Status = "finished"
+	rj.job.FinishedAt = &now
+	delete(s.jobs.active, rj.userID)
+}
+
+// RefreshJob returns one of the user's refresh jobs.
+func (s *FeedService) RefreshJob(userID int64, id string) (models.RefreshJob, bool) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	rj, ok := s.jobs.jobs[id]
+	if !ok || rj.userID != userID {
+		return models.RefreshJob{}, false
+	}
+	return rj.snapshot(), true
+}
+
+// pruneLocked drops jobs that finished more than refreshJobRetention ago.
+func (j *refreshJobs) pruneLocked(now time.Time) {
+	for id, rj := range j.jobs {
+		if rj.job.FinishedAt != nil && now.Sub(*rj.job.FinishedAt) > refreshJobRetention {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// snapshot copies the job so callers can use it without holding the lock.
+func (rj *refreshJob) snapshot() models.RefreshJob {
+	job := rj.job
+	job.Feeds = append([]models.RefreshJobFeed(nil), rj.job.Feeds...)
+	if rj.job.FinishedAt != nil {
+		finished := *rj.job.FinishedAt
+		job.FinishedAt = &finished
+	}
+	return job
+}
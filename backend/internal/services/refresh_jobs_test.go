@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+	"rss-feed-manager/backend/internal/feeds"
+	"rss-feed-manager/backend/internal/models"
+)
+
+func TestRefreshJobsPrune(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-refreshJobRetention - time.Minute)
+	recent := now.Add(-time.Minute)
+	jobs := newRefreshJobs()
+	jobs.jobs["running"] = &refreshJob{job: models.RefreshJob{ID: "running"}}
+	jobs.jobs["old"] = &refreshJob{job: models.RefreshJob{ID: "old", FinishedAt: &old}}
+	jobs.jobs["recent"] = &refreshJob{job: models.RefreshJob{ID: "recent", FinishedAt: &recent}}
+
+	jobs.pruneLocked(now)
+	for _, id := range []string{"running", "recent"} {
+		if _, ok := jobs.jobs[id]; !ok {
+			t.Errorf("job %q pruned", id)
+		}
+	}
+	if _, ok := jobs.jobs["old"]; ok {
+		t.Error("old job kept")
+	}
+}
+
+func TestRefreshJobSnapshot(t *testing.T) {
+	started := time.Now()
+	finished := started
+	rj := &refreshJob{job: models.RefreshJob{
+		ID:         "a",
+		Feeds:      []models.RefreshJobFeed{{FeedID: 1, Status: "pending"}},
+		FinishedAt: &finished,
+	}}
+	snap := rj.snapshot()
+	rj.job.Feeds[0].Status = "done"
+	*rj.job.FinishedAt = started.Add(time.Hour)
+	if snap.Feeds[0].Status != "pending" {
+		t.Errorf("snapshot shares feeds: status %q", snap.Feeds[0].Status)
+	}
+	if !snap.FinishedAt.Equal(started) {
+		t.Error("snapshot shares finishedAt")
+	}
+}
+
+// TestStartRefreshAllConcurrent starts refresh-all many times at once: each
+// user gets one job, every caller sees it, and a new job can start once it
+// finishes. The feed fetch is held open so the jobs stay running.
+func TestStartRefreshAllConcurrent(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title>` +
+				`<item><guid>a</guid><title>First</title></item></channel></rss>`))
+		}))
+		defer srv.Close()
+
+		svc := NewFeedService(d, feeds.NewFetcher("test"))
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`, srv.URL, "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, _ := subscribe(t, d, svc, "reader@example.com", sourceID)
+		otherID, _ := subscribe(t, d, svc, "other@example.com", sourceID)
+
+		type result struct {
+			userID  int64
+			job     models.RefreshJob
+			started bool
+			err     error
+		}
+		// Holding the only connection stops every caller at the feed query,
+		// after it has found no running job, so they all race to register one.
+		d.SetMaxOpenConns(1)
+		hold, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waits := d.Stats().WaitCount
+		const callers = 8
+		results := make(chan result, 2*callers)
+		var wg sync.WaitGroup
+		for i := 0; i < 2*callers; i++ {
+			uid := userID
+			if i%2 == 1 {
+				uid = otherID
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				job, started, err := svc.StartRefreshAll(ctx, uid)
+				results <- result{uid, job, started, err}
+			}()
+		}
+		for d.Stats().WaitCount < waits+2*callers {
+			time.Sleep(time.Millisecond)
+		}
+		hold.Rollback()
+		wg.Wait()
+		close(results)
+
+		jobs := map[int64]string{}
+		startedCount := map[int64]int{}
+		for r := range results {
+			if r.err != nil {
+				t.Fatalf("StartRefreshAll: %v", r.err)
+			}
+			if id, ok := jobs[r.userID]; ok && id != r.job.ID {
+				t.Errorf("user %d got jobs %s and %s", r.userID, id, r.job.ID)
+			}
+			jobs[r.userID] = r.job.ID
+			if r.started {
+				startedCount[r.userID]++
+			}
+		}
+		for _, uid := range []int64{userID, otherID} {
+			if startedCount[uid] != 1 {
+				t.Errorf("user %d: %d jobs started, want 1", uid, startedCount[uid])
+			}
+		}
+		if jobs[userID] == jobs[otherID] {
+			t.Error("both users share one job")
+		}
+
+		close(release)
+		waitFinished := func(uid int64, id string) {
+			t.Helper()
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				job, ok := svc.RefreshJob(uid, id)
+				if !ok {
+					t.Fatalf("job %s not found", id)
+				}
+				if job.Status == "finished" {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("job %s still %s", id, job.Status)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		waitFinished(userID, jobs[userID])
+		waitFinished(otherID, jobs[otherID])
+		if _, ok := svc.RefreshJob(otherID, jobs[userID]); ok {
+			t.Error("a user can read another user's job")
+		}
+
+		job, started, err := svc.StartRefreshAll(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !started || job.ID == jobs[userID] {
+			t.Errorf("refresh after the job finished: started=%v id=%s, want a new job", started, job.ID)
+		}
+		waitFinished(userID, job.ID)
+	})
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshConcurrency = 8
+	// feedRefreshTimeout bounds a single feed refresh, including any wait for
+	// a per-host slot, so one slow host cannot stall a whole run.
+	feedRefreshTimeout = 30 * time.Second
+)
+
+// RefreshTarget identifies one feed belonging to one user.
+type RefreshTarget struct {
+	UserID int64
+	FeedID int64
+}
+
+// RefreshResult is the outcome of refreshing a single feed.
+type RefreshResult struct {
+	RefreshTarget
+	ItemsFetched int
+	NewItems     int
+	Err          error
+}
+
+// RefreshProgress is reported after each feed finishes.
+type RefreshProgress struct {
+	Total     int
+	Completed int // includes failures
+	Failed    int
+	Last      RefreshResult
+}
+
+// RefreshSummary describes a finished (or cancelled) refresh run.
+type RefreshSummary struct {
+	Total     int
+	Completed int
+	Failed    int
+	Skipped   int // not started because the context was cancelled
+}
+
+// SetRefreshConcurrency sets how many feeds are refreshed in parallel.
+func (s *FeedService) SetRefreshConcurrency(n int) {
+	if n <= 0 {
+		n = defaultRefreshConcurrency
+	}
+	s.refreshConcurrency = n
+}
+
+// RefreshMany refreshes targets on a bounded worker pool, in the order given
+// as far as concurrency allows. Per-host politeness is enforced by the
+// fetcher. Once ctx is cancelled no new feeds are started and the remainder
+// is counted as skipped. onProgress, if set, is called after every feed and
+// never concurrently with itself.
+func (s *FeedService) RefreshMany(ctx context.Context, targets []RefreshTarget, onProgress func(RefreshProgress)) RefreshSummary {
+	workers := s.refreshConcurrency
+	if workers <= 0 {
+		workers = defaultRefreshConcurrency
+	}
+	if workers > len(targets) {
+		workers = len(targets)
+	}
+
+	jobs := make(chan RefreshTarget)
+	var (
+		mu       sync.Mutex
+		progress = RefreshProgress{Total: len(targets)}
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				feedCtx, cancel := context.WithTimeout(ctx, feedRefreshTimeout)
+				count, added, err := s.refreshFeed(feedCtx, target.UserID, target.FeedID)
+				cancel()
+
+				mu.Lock()
+				progress.Completed++
+				if err != nil {
+					progress.Failed++
+				}
+				progress.Last = RefreshResult{RefreshTarget: target, ItemsFetched: count, NewItems: added, Err: err}
+				if onProgress != nil {
+					onProgress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for _, target := range targets {
+		select {
+		case jobs <- target:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return RefreshSummary{
+		Total:     progress.Total,
+		Completed: progress.Completed,
+		Failed:    progress.Failed,
+		Skipped:   len(targets) - dispatched,
+	}
+}
+
+func refreshTargets(userID int64, feedIDs []int64) []RefreshTarget {
+	targets := make([]RefreshTarget, 0, len(feedIDs))
+	for _, feedID := range feedIDs {
+		targets = append(targets, RefreshTarget{UserID: userID, FeedID: feedID})
+	}
+	return targets
+}
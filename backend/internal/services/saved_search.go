@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+// ListSavedSearches returns the user's saved searches. Unread counts cost a
+// full-text query each, so they are left to CountSavedSearches.
+func (s *FeedService) ListSavedSearches(ctx context.Context, userID int64) ([]models.SavedSearch, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, query, folder_id, feed_id, unread_only, within_days, digest, created_at
+		FROM saved_searches WHERE user_id=? ORDER BY lower(name), id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	list := []models.SavedSearch{}
+	for rows.Next() {
+		ss, err := scanSavedSearch(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ss.UserID = userID
+		list = append(list, ss)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CountSavedSearches fills in the unread count of each saved search. Counts
+// stay unset when search is unavailable or a query no longer parses.
+func (s *FeedService) CountSavedSearches(ctx context.Context, userID int64, list []models.SavedSearch) {
+	if !s.hasSearchIndex(ctx, s.db) {
+		return
+	}
+	now := time.Now()
+	for i := range list {
+		sq := savedSearchQuery(list[i], now)
+		sq.UnreadOnly = true
+		n, err := s.CountSearch(ctx, userID, sq)
+		if err != nil {
+			log.Printf("count saved search %d: %v", list[i].ID, err)
+			continue
+		}
+		list[i].UnreadCount = &n
+	}
+}
+
+func (s *FeedService) GetSavedSearch(ctx context.Context, userID, id int64) (models.SavedSearch, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, name, query, folder_id, feed_id, unread_only, within_days, digest, created_at
+		FROM saved_searches WHERE id=? AND user_id=?`, id, userID)
+	ss, err := scanSavedSearch(row)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	ss.UserID = userID
+	return ss, nil
+}
+
+func (s *FeedService) CreateSavedSearch(ctx context.Context, userID int64, ss models.SavedSearch) (models.SavedSearch, error) {
+	if err := s.validateSavedSearch(ctx, userID, &ss); err != nil {
+		return models.SavedSearch{}, err
+	}
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO saved_searches(user_id, name, query, folder_id, feed_id, unread_only, within_days, digest)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		userID, ss.Name, ss.Query, ss.FolderID, ss.FeedID, boolToInt(ss.UnreadOnly), ss.WithinDays, boolToInt(ss.Digest)).Scan(&ss.ID)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	ss.UserID = userID
+	ss.CreatedAt = time.Now()
+	return ss, nil
+}
+
+func (s *FeedService) UpdateSavedSearch(ctx context.Context, userID int64, ss models.SavedSearch) error {
+	if err := s.validateSavedSearch(ctx, userID, &ss); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE saved_searches SET name=?, query=?, folder_id=?, feed_id=?, unread_only=?, within_days=?, digest=?
+		WHERE id=? AND user_id=?`,
+		ss.Name, ss.Query, ss.FolderID, ss.FeedID, boolToInt(ss.UnreadOnly), ss.WithinDays, boolToInt(ss.Digest),
+		ss.ID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("saved search not found")
+	}
+	return nil
+}
+
+func (s *FeedService) DeleteSavedSearch(ctx context.Context, userID, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE id=? AND user_id=?`, id, userID)
+	return err
+}
+
+// ListSavedSearchItems returns the items matching a saved search, newest
+// first unless sort is "oldest". Results are ordered by item time, so it
+// pages with the same ItemCursor as ListItems.
+func (s *FeedService) ListSavedSearchItems(ctx context.Context, userID, id int64, limit int, cursor *ItemCursor, sort string) ([]models.Item, *ItemCursor, error) {
+	ss, err := s.GetSavedSearch(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	sq := savedSearchQuery(ss, time.Now())
+	sq.Limit = limit
+	if cursor != nil {
+		sq.Cursor = &SearchCursor{Key: float64(cursor.Timestamp), ID: cursor.ID}
+	}
+	sq.Sort = "latest"
+	if sort == string(SortOldest) {
+		sq.Sort = "oldest"
+	}
+	hits, next, err := s.SearchItems(ctx, userID, sq)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := make([]models.Item, len(hits))
+	for i, hit := range hits {
+		items[i] = hit.Item
+	}
+	if err := s.attachTags(ctx, userID, items); err != nil {
+		return nil, nil, err
+	}
+	if next == nil {
+		return items, nil, nil
+	}
+	return items, &ItemCursor{Timestamp: int64(next.Key), ID: next.ID}, nil
+}
+
+func (s *FeedService) validateSavedSearch(ctx context.Context, userID int64, ss *models.SavedSearch) error {
+	ss.Name = strings.TrimSpace(ss.Name)
+	ss.Query = strings.TrimSpace(ss.Query)
+	if ss.Name == "" {
+		return errors.New("name required")
+	}
+	if _, err := buildMatchQuery(ss.Query); err != nil {
+		return err
+	}
+	if ss.WithinDays < 0 {
+		return errors.New("withinDays must not be negative")
+	}
+	if ss.FolderID != nil {
+		var n int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM folders WHERE id=? AND user_id=?`, *ss.FolderID, userID).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("folder not found")
+		}
+	}
+	if ss.FeedID != nil {
+		var n int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM feeds WHERE id=? AND user_id=?`, *ss.FeedID, userID).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("feed not found")
+		}
+	}
+	return nil
+}
+
+// savedSearchQuery expands a saved search into a query relative to now.
+func savedSearchQuery(ss models.SavedSearch, now time.Time) SearchQuery {
+	sq := SearchQuery{
+		Query:      ss.Query,
+		FolderID:   ss.FolderID,
+		FeedID:     ss.FeedID,
+		UnreadOnly: ss.UnreadOnly,
+	}
+	if ss.WithinDays > 0 {
+		since := now.AddDate(0, 0, -ss.WithinDays)
+		sq.Since = &since
+	}
+	return sq
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSavedSearch(row rowScanner) (models.SavedSearch, error) {
+	var (
+		ss               models.SavedSearch
+		folderID, feedID sql.NullInt64
+	)
+	if err := row.Scan(&ss.ID, &ss.Name, &ss.Query, &folderID, &feedID, &ss.UnreadOnly, &ss.WithinDays, &ss.Digest, &ss.CreatedAt); err != nil {
+		return models.SavedSearch{}, err
+	}
+	if folderID.Valid {
+		ss.FolderID = &folderID.Int64
+	}
+	if feedID.Valid {
+		ss.FeedID = &feedID.Int64
+	}
+	return ss, nil
+}
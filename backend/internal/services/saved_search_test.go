@@ -0,0 +1,24 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+func TestSavedSearchQuery(t *testing.T) {
+	now := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+	folderID := int64(3)
+	sq := savedSearchQuery(models.SavedSearch{Query: "k8s", FolderID: &folderID, UnreadOnly: true, WithinDays: 7}, now)
+	if sq.Query != "k8s" || sq.FolderID != &folderID || !sq.UnreadOnly {
+		t.Errorf("unexpected query: %+v", sq)
+	}
+	if sq.Since == nil || !sq.Since.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Since = %v, expected a week before now", sq.Since)
+	}
+
+	if sq := savedSearchQuery(models.SavedSearch{Query: "k8s"}, now); sq.Since != nil {
+		t.Errorf("Since = %v, expected nil without WithinDays", sq.Since)
+	}
+}
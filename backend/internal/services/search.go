@@ -0,0 +1,561 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// ErrSearchUnavailable is returned when SQLite was built without FTS5
+// (build with -tags sqlite_fts5) and the search index does not exist.
+var ErrSearchUnavailable = errors.New("search is not available: sqlite was built without FTS5")
+
+// Snippet highlight markers; control characters cannot occur in indexed
+// text, so they survive HTML escaping and are swapped for <mark> afterwards.
+const (
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+// SearchQuery describes a full-text search over the user's items.
+type SearchQuery struct {
+	Query      string
+	FolderID   *int64
+	FeedID     *int64
+	Since      *time.Time
+	Until      *time.Time
+	AddedSince *time.Time // stored after this time, regardless of publish date
+	UnreadOnly bool
+	Sort       string // relevance (default), latest or oldest
+	Limit      int
+	Cursor     *SearchCursor
+}
+
+// SearchCursor is a position in search results: the sort key of the last
+// hit on a page (its rank for relevance, its item time for latest and
+// oldest) and its id, so the next page starts right after it however the
+// index changes in between.
+type SearchCursor struct {
+	Key float64
+	ID  int64
+}
+
+func (c SearchCursor) Encode() string {
+	return strconv.FormatFloat(c.Key, 'g', -1, 64) + ":" + strconv.FormatInt(c.ID, 10)
+}
+
+// hasSearchIndex reports whether the items_fts table exists.
+func (s *FeedService) hasSearchIndex(ctx context.Context, q dbtx) bool {
+	var n int
+	err := q.QueryRowContext(ctx, s.db.Dialect().TableExistsQuery(), "items_fts").Scan(&n)
+	return err == nil && n > 0
+}
+
+// matchQuery compiles the user's query for the dialect's search index:
+// an FTS5 MATCH expression on SQLite, a tsquery on Postgres.
+func matchQuery(d db.Dialect, raw string) (string, error) {
+	if d == db.Postgres {
+		return buildTSQuery(raw)
+	}
+	return buildMatchQuery(raw)
+}
+
+// matchClause filters items_fts by the compiled query.
+func matchClause(d db.Dialect) string {
+	if d == db.Postgres {
+		return "items_fts.document @@ to_tsquery('english', ?)"
+	}
+	return "items_fts MATCH ?"
+}
+
+// indexItem (re)writes the search index row for an entry. Deletions are
+// handled by a trigger on entries.
+func (s *FeedService) indexItem(ctx context.Context, q dbtx, entryID int64) error {
+	var title, author, summary, content sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT title, author, summary_text, content_html FROM entries WHERE id=?`,
+		entryID).Scan(&title, &author, &summary, &content)
+	if err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM items_fts WHERE rowid=?`, entryID); err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO items_fts(rowid, title, author, summary, content) VALUES(?, ?, ?, ?, ?)`,
+		entryID, title.String, author.String, plainText(summary.String), plainText(content.String))
+	return err
+}
+
+// IndexMissingItems adds entries that predate the search index, in batches.
+// It returns the number of entries indexed.
+func (s *FeedService) IndexMissingItems(ctx context.Context) (int, error) {
+	if !s.hasSearchIndex(ctx, s.db) {
+		return 0, nil
+	}
+	total := 0
+	for {
+		batch, err := s.queryIDs(ctx, `
+			SELECT id FROM entries
+			WHERE id NOT IN (SELECT rowid FROM items_fts)
+			LIMIT 500`)
+		if err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return total, err
+		}
+		for _, id := range batch {
+			if err := s.indexItem(ctx, tx, id); err != nil {
+				tx.Rollback()
+				return total, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return total, err
+		}
+		total += len(batch)
+	}
+}
+
+// SearchItems runs a full-text query over the user's items. Each hit carries
+// an HTML-escaped snippet with matches wrapped in <mark>. The second return
+// value is the cursor for the next page, or nil when there is none.
+func (s *FeedService) SearchItems(ctx context.Context, userID int64, sq SearchQuery) ([]models.SearchHit, *SearchCursor, error) {
+	dialect := s.db.Dialect()
+	match, err := matchQuery(dialect, sq.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.hasSearchIndex(ctx, s.db) {
+		return nil, nil, ErrSearchUnavailable
+	}
+	limit := sq.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	clauses, args := sq.filters(dialect, match, userID)
+	snippetExpr := "snippet(items_fts, -1, ?, ?, '…', 16)"
+	snippetArgs := []interface{}{snippetOpen, snippetClose}
+	if dialect == db.Postgres {
+		snippetExpr = "ts_headline('english', concat_ws(' ', items_fts.summary, items_fts.content), to_tsquery('english', ?), ?)"
+		snippetArgs = []interface{}{match, fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=16, MinWords=8, MaxFragments=1, FragmentDelimiter="…"`, snippetOpen, snippetClose)}
+	}
+	// Hits are ordered by a sort key and then id; keyDir and idDir are the
+	// directions, and the cursor continues past the last hit in them.
+	var sortKey, keyDir, idDir string
+	var sortArgs []interface{}
+	switch {
+	case sq.Sort == "latest":
+		sortKey, keyDir, idDir = itemTimeExpr(dialect), "DESC", "DESC"
+	case sq.Sort == "oldest":
+		sortKey, keyDir, idDir = itemTimeExpr(dialect), "ASC", "ASC"
+	case dialect == db.Postgres:
+		sortKey, keyDir, idDir = "ts_rank(items_fts.document, to_tsquery('english', ?))", "DESC", "DESC"
+		sortArgs = append(sortArgs, match)
+	default:
+		// bm25 is lower for better matches.
+		sortKey, keyDir, idDir = "bm25(items_fts, 10.0, 2.0, 1.0, 1.0)", "ASC", "DESC"
+	}
+	if dialect == db.Postgres {
+		// ts_rank is a real and item times are bigints; compare both as
+		// float8 so the key read back into the cursor matches exactly.
+		sortKey = "CAST(" + sortKey + " AS DOUBLE PRECISION)"
+	}
+	args = append(append(snippetArgs, sortArgs...), args...)
+	cmp := map[string]string{"ASC": ">", "DESC": "<"}
+
+	// FTS5 only allows its auxiliary functions in the result and ORDER BY,
+	// so the cursor filters the ranked hits from outside.
+	after := "1=1"
+	if sq.Cursor != nil {
+		after = fmt.Sprintf("(sort_key %s ? OR (sort_key = ? AND id %s ?))", cmp[keyDir], cmp[idDir])
+		args = append(args, sq.Cursor.Key, sq.Cursor.Key, sq.Cursor.ID)
+	}
+	query := fmt.Sprintf(`
+		SELECT * FROM (
+			SELECT items.id AS id, items.feed_id, items.guid, items.link, items.title, items.author, items.published_at,
+				   items.summary_text, items.content_html, items.media_json, items.created_at,
+				   COALESCE(item_state.is_read,0) AS is_read, COALESCE(item_state.is_bookmarked,0) AS is_bookmarked,
+				   item_state.bookmarked_at, COALESCE(feeds.title, sources.title) AS feed_title, sources.site_url,
+				   %s AS snippet, %s AS sort_key
+			FROM items_fts
+			JOIN items ON items.id = items_fts.rowid
+			LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+			JOIN feeds ON feeds.id = items.feed_id
+			JOIN sources ON sources.id = feeds.source_id
+			WHERE %s
+		) hits
+		WHERE %s
+		ORDER BY sort_key %s, id %s
+		LIMIT ?`, snippetExpr, sortKey, strings.Join(clauses, " AND "), after, keyDir, idDir)
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "fts5: syntax error") || strings.Contains(err.Error(), "syntax error in tsquery") {
+			return nil, nil, fmt.Errorf("invalid search query: %w", err)
+		}
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	hits := []models.SearchHit{}
+	var keys []float64
+	for rows.Next() {
+		var (
+			hit                models.SearchHit
+			it                 = &hit.Item
+			published          sql.NullTime
+			bookmarkedAt       sql.NullTime
+			stateRead, stateBm bool
+			sourceTitle        sql.NullString
+			sourceSite         sql.NullString
+			snippet            string
+			key                float64
+		)
+		if err := rows.Scan(&it.ID, &it.FeedID, &it.GUID, &it.Link, &it.Title, &it.Author, &published,
+			&it.SummaryText, &it.ContentHTML, &it.MediaJSON, &it.CreatedAt,
+			&stateRead, &stateBm, &bookmarkedAt,
+			&sourceTitle, &sourceSite, &snippet, &key); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		it.UserID = userID
+		if published.Valid {
+			it.PublishedAt = &published.Time
+		}
+		it.State = models.ItemState{ItemID: it.ID, UserID: userID, IsRead: stateRead, IsBookmarked: stateBm}
+		if bookmarkedAt.Valid {
+			it.State.BookmarkedAt = &bookmarkedAt.Time
+		}
+		if sourceTitle.Valid || sourceSite.Valid {
+			it.Source = &models.Feed{ID: it.FeedID, Title: sourceTitle.String, SiteURL: sourceSite.String}
+		}
+		hit.Snippet = highlightSnippet(snippet)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var next *SearchCursor
+	if len(hits) > limit {
+		hits = hits[:limit]
+		next = &SearchCursor{Key: keys[limit-1], ID: hits[limit-1].ID}
+	}
+	return hits, next, nil
+}
+
+// CountSearch returns how many of the user's items match the query.
+func (s *FeedService) CountSearch(ctx context.Context, userID int64, sq SearchQuery) (int, error) {
+	match, err := matchQuery(s.db.Dialect(), sq.Query)
+	if err != nil {
+		return 0, err
+	}
+	if !s.hasSearchIndex(ctx, s.db) {
+		return 0, ErrSearchUnavailable
+	}
+	clauses, args := sq.filters(s.db.Dialect(), match, userID)
+	var n int
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(1)
+		FROM items_fts
+		JOIN items ON items.id = items_fts.rowid
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
+		JOIN feeds ON feeds.id = items.feed_id
+		WHERE %s`, strings.Join(clauses, " AND ")), args...).Scan(&n)
+	return n, err
+}
+
+// filters returns the WHERE clauses and arguments shared by search queries.
+func (sq SearchQuery) filters(d db.Dialect, match string, userID int64) ([]string, []interface{}) {
+	args := []interface{}{match, userID}
+	clauses := []string{matchClause(d), "items.user_id=?", "COALESCE(item_state.is_hidden,0)=0"}
+	if sq.FolderID != nil {
+		clauses = append(clauses, "feeds.folder_id=?")
+		args = append(args, *sq.FolderID)
+	}
+	if sq.FeedID != nil {
+		clauses = append(clauses, "items.feed_id=?")
+		args = append(args, *sq.FeedID)
+	}
+	if sq.Since != nil {
+		clauses = append(clauses, "COALESCE(items.published_at, items.created_at) >= ?")
+		args = append(args, *sq.Since)
+	}
+	if sq.Until != nil {
+		clauses = append(clauses, "COALESCE(items.published_at, items.created_at) < ?")
+		args = append(args, *sq.Until)
+	}
+	if sq.AddedSince != nil {
+		clauses = append(clauses, "items.created_at > ?")
+		args = append(args, *sq.AddedSince)
+	}
+	if sq.UnreadOnly {
+		clauses = append(clauses, "COALESCE(item_state.is_read,0)=0")
+	}
+	return clauses, args
+}
+
+// buildMatchQuery turns user input into an FTS5 MATCH expression.
+func buildMatchQuery(raw string) (string, error) {
+	out, excluded, err := parseSearchQuery(raw)
+	if err != nil {
+		return "", err
+	}
+	match := strings.Join(out, " ")
+	if len(excluded) > 0 {
+		match = "(" + match + ")"
+		for _, tok := range excluded {
+			match += " NOT " + tok
+		}
+	}
+	return match, nil
+}
+
+// buildTSQuery turns user input into a Postgres tsquery with the same
+// meaning as buildMatchQuery: adjacent terms are ANDed, phrases match
+// consecutive words and title:/author: select the A and B weights.
+func buildTSQuery(raw string) (string, error) {
+	out, excluded, err := parseSearchQuery(raw)
+	if err != nil {
+		return "", err
+	}
+	isOperand := func(tok string) bool {
+		return tok != "" && tok != "AND" && tok != "OR" && tok != "NOT" && tok != "(" && tok != ")"
+	}
+	var parts []string
+	prev := ""
+	for _, tok := range out {
+		// FTS5 ANDs adjacent operands implicitly; tsquery needs the &.
+		if (isOperand(prev) || prev == ")") && (isOperand(tok) || tok == "(") {
+			parts = append(parts, "&")
+		}
+		switch tok {
+		case "AND":
+			parts = append(parts, "&")
+		case "OR":
+			parts = append(parts, "|")
+		case "NOT":
+			parts = append(parts, "& !")
+		case "(", ")":
+			parts = append(parts, tok)
+		default:
+			parts = append(parts, tsTerm(tok))
+		}
+		prev = tok
+	}
+	query := strings.Join(parts, " ")
+	if len(excluded) > 0 {
+		query = "(" + query + ")"
+		for _, tok := range excluded {
+			query += " & !" + tsTerm(tok)
+		}
+	}
+	return query, nil
+}
+
+// tsTerm converts a term produced by parseSearchQuery (optional column,
+// quoted text, optional *) into tsquery lexemes.
+func tsTerm(tok string) string {
+	weight := ""
+	if column, rest, ok := strings.Cut(tok, ":"); ok && !strings.HasPrefix(tok, `"`) {
+		tok = rest
+		if column == "title" {
+			weight = "A"
+		} else {
+			weight = "B"
+		}
+	}
+	prefix := strings.HasSuffix(tok, "*")
+	tok = strings.TrimSuffix(tok, "*")
+	text := strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(tok, `"`), `"`), `""`, `"`)
+	words := strings.Fields(text)
+	lexemes := make([]string, len(words))
+	for i, word := range words {
+		label := weight
+		if prefix && i == len(words)-1 {
+			label = "*" + label
+		}
+		word = strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(word)
+		lexemes[i] = "'" + word + "'"
+		if label != "" {
+			lexemes[i] += ":" + label
+		}
+	}
+	if len(lexemes) == 1 {
+		return lexemes[0]
+	}
+	return "(" + strings.Join(lexemes, " <-> ") + ")"
+}
+
+// parseSearchQuery splits user input into balanced FTS5 tokens and the terms
+// to exclude. It accepts "quoted phrases", prefix*, AND/OR/NOT, parentheses,
+// -excluded terms and title:/author: column filters; every term is quoted so
+// punctuation cannot produce syntax errors.
+func parseSearchQuery(raw string) (out, excluded []string, err error) {
+	var (
+		depth int
+		// pendingNot is set by a NOT with nothing on its left; it applies
+		// to the next term, which is excluded instead.
+		pendingNot bool
+	)
+	isOperator := func(tok string) bool { return tok == "AND" || tok == "OR" || tok == "NOT" }
+	last := func() string {
+		if len(out) == 0 {
+			return ""
+		}
+		return out[len(out)-1]
+	}
+	push := func(tok string) {
+		switch {
+		case isOperator(tok):
+			// Operators need an operand on the left.
+			if l := last(); l == "" || l == "(" || isOperator(l) {
+				pendingNot = tok == "NOT"
+				return
+			}
+		case tok == ")":
+			if depth == 0 {
+				return
+			}
+			for isOperator(last()) {
+				out = out[:len(out)-1]
+			}
+			depth--
+			if last() == "(" {
+				out = out[:len(out)-1]
+				return
+			}
+		case tok == "(":
+			pendingNot = false
+			depth++
+		default:
+			if pendingNot {
+				pendingNot = false
+				excluded = append(excluded, tok)
+				return
+			}
+		}
+		out = append(out, tok)
+	}
+
+	runes := []rune(raw)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			push(string(r))
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			phrase := strings.TrimSpace(string(runes[i+1 : min(end, len(runes))]))
+			i = end + 1
+			if phrase == "" {
+				continue
+			}
+			tok := quoteTerm(phrase)
+			if i < len(runes) && runes[i] == '*' {
+				tok += "*"
+				i++
+			}
+			push(tok)
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			word := string(runes[i:end])
+			i = end
+			if isOperator(word) {
+				push(word)
+				continue
+			}
+			negate := strings.HasPrefix(word, "-")
+			word = strings.TrimLeft(word, "-")
+			column := ""
+			if c, rest, ok := strings.Cut(word, ":"); ok && (c == "title" || c == "author") && rest != "" {
+				column, word = c+":", rest
+			}
+			prefix := strings.HasSuffix(word, "*")
+			word = strings.TrimRight(word, "*")
+			if word == "" {
+				continue
+			}
+			tok := column + quoteTerm(word)
+			if prefix {
+				tok += "*"
+			}
+			if negate {
+				pendingNot = true
+			}
+			push(tok)
+		}
+	}
+	for l := last(); isOperator(l) || l == "("; l = last() {
+		if l == "(" {
+			depth--
+		}
+		out = out[:len(out)-1]
+	}
+	for ; depth > 0; depth-- {
+		out = append(out, ")")
+	}
+	if len(out) == 0 {
+		return nil, nil, errors.New("search query needs at least one term")
+	}
+	return out, excluded, nil
+}
+
+func quoteTerm(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
+
+// highlightSnippet escapes the snippet and turns the markers into <mark>.
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetOpen, "<mark>")
+	return strings.ReplaceAll(escaped, snippetClose, "</mark>")
+}
+
+// plainText extracts the visible text from an HTML fragment for indexing.
+func plainText(fragment string) string {
+	text := fragment
+	if strings.ContainsAny(fragment, "<&") {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment)); err == nil {
+			doc.Find("script, style").Remove()
+			text = doc.Text()
+		} else {
+			text = stripHTML(fragment)
+		}
+	}
+	text = strings.Map(func(r rune) rune {
+		if r == rune(snippetOpen[0]) || r == rune(snippetClose[0]) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,99 @@
+package services
+
+import "testing"
+
+func TestBuildMatchQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"golang", `"golang"`},
+		{"go channels", `"go" "channels"`},
+		{`"memory safety" rust`, `"memory safety" "rust"`},
+		{"gorout*", `"gorout"*`},
+		{"rust OR go", `"rust" OR "go"`},
+		{"go -rust", `("go") NOT "rust"`},
+		{"NOT rust go", `("go") NOT "rust"`},
+		{"(rust OR go) AND safety", `( "rust" OR "go" ) AND "safety"`},
+		{"(go", `( "go" )`},
+		{"go) OR", `"go"`},
+		{"title:pasta author:ann", `title:"pasta" author:"ann"`},
+		{"url:x", `"url:x"`},
+		{"c++", `"c++"`},
+		{`say "hi`, `"say" "hi"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := buildMatchQuery(tc.input)
+			if err != nil {
+				t.Fatalf("buildMatchQuery(%q) error: %v", tc.input, err)
+			}
+			if result != tc.expected {
+				t.Errorf("buildMatchQuery(%q) = %s, expected %s", tc.input, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBuildMatchQuery_NoTerms(t *testing.T) {
+	for _, input := range []string{"", "   ", "AND OR", "()", "-rust"} {
+		if result, err := buildMatchQuery(input); err == nil {
+			t.Errorf("buildMatchQuery(%q) = %s, expected error", input, result)
+		}
+	}
+}
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"golang", `'golang'`},
+		{"go channels", `'go' & 'channels'`},
+		{`"memory safety" rust`, `('memory' <-> 'safety') & 'rust'`},
+		{"gorout*", `'gorout':*`},
+		{"rust OR go", `'rust' | 'go'`},
+		{"go -rust", `('go') & !'rust'`},
+		{"go NOT rust", `'go' & ! 'rust'`},
+		{"(rust OR go) safety", `( 'rust' | 'go' ) & 'safety'`},
+		{"title:pasta* author:ann", `'pasta':*A & 'ann':B`},
+		{"url:x", `'url:x'`},
+		{`it's`, `'it''s'`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := buildTSQuery(tc.input)
+			if err != nil {
+				t.Fatalf("buildTSQuery(%q) error: %v", tc.input, err)
+			}
+			if result != tc.expected {
+				t.Errorf("buildTSQuery(%q) = %s, expected %s", tc.input, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	result := highlightSnippet("a <b> & " + snippetOpen + "go" + snippetClose + "…")
+	expected := "a &lt;b&gt; &amp; <mark>go</mark>…"
+	if result != expected {
+		t.Errorf("highlightSnippet() = %q, expected %q", result, expected)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{"<p>Hello <b>world</b></p><script>x()</script>", "Hello world"},
+		{"fish &amp; chips", "fish & chips"},
+		{"  spaced \n\t out  ", "spaced out"},
+	}
+	for _, tc := range tests {
+		if result := plainText(tc.input); result != tc.expected {
+			t.Errorf("plainText(%q) = %q, expected %q", tc.input, result, tc.expected)
+		}
+	}
+}
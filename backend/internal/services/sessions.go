@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+// sessionTouchInterval limits how often a session's last activity and
+// expiry are written.
+const sessionTouchInterval = time.Minute
+
+// Session kinds. Web sessions come from signing in to the app; app sessions
+// come from Google Reader ClientLogin with the app password and only
+// authenticate the Google Reader API.
+const (
+	SessionKindWeb = "web"
+	SessionKindApp = "app"
+)
+
+// maxUserAgentLen bounds the user agent stored with a session.
+const maxUserAgentLen = 512
+
+type clientInfoKey struct{}
+
+// ClientInfo describes where a request came from, for the session list.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// WithClientInfo attaches the request's client to ctx so sessions created
+// or used under it record it.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func clientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	if len(info.UserAgent) > maxUserAgentLen {
+		info.UserAgent = info.UserAgent[:maxUserAgentLen]
+	}
+	return info
+}
+
+// createSession starts a session of the given kind for the user and returns
+// its token.
+func (s *AuthService) createSession(ctx context.Context, userID int64, kind string) (string, error) {
+	token, err := generateToken(32)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	client := clientInfoFrom(ctx)
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO sessions (user_id, token, kind, created_at, expires_at, last_seen_at, ip, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, token, kind, now, now.Add(s.sessionExpiry), now, client.IP, client.UserAgent)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// touchSession records activity on a session and slides its expiry forward.
+func (s *AuthService) touchSession(ctx context.Context, sessionID int64) {
+	now := time.Now()
+	client := clientInfoFrom(ctx)
+	_, _ = s.db.ExecContext(ctx, `
+		UPDATE sessions SET last_seen_at = ?, expires_at = ?,
+			ip = CASE WHEN ? = '' THEN ip ELSE ? END,
+			user_agent = CASE WHEN ? = '' THEN user_agent ELSE ? END
+		WHERE id = ?
+	`, now, now.Add(s.sessionExpiry), client.IP, client.IP, client.UserAgent, client.UserAgent, sessionID)
+}
+
+// ListSessions returns the user's unexpired sessions, most recently active
+// first, marking the one whose token is current.
+func (s *AuthService) ListSessions(ctx context.Context, userID int64, current string) ([]models.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, kind, token = ?, created_at, last_seen_at, expires_at, COALESCE(ip, ''), COALESCE(user_agent, '')
+		FROM sessions
+		WHERE user_id = ? AND expires_at > ?
+		ORDER BY COALESCE(last_seen_at, created_at) DESC, id DESC
+	`, current, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []models.Session{}
+	for rows.Next() {
+		var sess models.Session
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&sess.ID, &sess.Kind, &sess.Current, &sess.CreatedAt, &lastSeen, &sess.ExpiresAt, &sess.IP, &sess.UserAgent); err != nil {
+			return nil, err
+		}
+		if lastSeen.Valid {
+			sess.LastSeenAt = &lastSeen.Time
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession signs out one of the user's sessions.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ? AND user_id = ?`, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the user except the
+// current one and returns how many were revoked.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID int64, current string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ? AND token <> ?`, userID, current)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+)
+
+func TestClientInfoFrom(t *testing.T) {
+	if got := clientInfoFrom(context.Background()); got != (ClientInfo{}) {
+		t.Errorf("empty context gave %+v", got)
+	}
+	ctx := WithClientInfo(context.Background(), ClientInfo{IP: "203.0.113.9", UserAgent: strings.Repeat("a", maxUserAgentLen+10)})
+	got := clientInfoFrom(ctx)
+	if got.IP != "203.0.113.9" {
+		t.Errorf("IP = %q", got.IP)
+	}
+	if len(got.UserAgent) != maxUserAgentLen {
+		t.Errorf("user agent length %d, expected %d", len(got.UserAgent), maxUserAgentLen)
+	}
+}
+
+// TestSessionSlidingExpiry checks that using a session pushes its expiry
+// back, at most once per sessionTouchInterval, and that an expired session
+// is removed.
+func TestSessionSlidingExpiry(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		svc := NewAuthService(d, nil)
+		var userID int64
+		if err := d.QueryRow(`INSERT INTO users(email) VALUES('reader@example.com') RETURNING id`).Scan(&userID); err != nil {
+			t.Fatal(err)
+		}
+		token, err := svc.createSession(WithClientInfo(context.Background(), ClientInfo{IP: "203.0.113.1"}), userID, SessionKindWeb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Requests from here on come from a new address.
+		ctx := WithClientInfo(context.Background(), ClientInfo{IP: "203.0.113.2", UserAgent: "Reader/2"})
+
+		set := func(lastSeen, expires time.Time) {
+			t.Helper()
+			if _, err := d.Exec(`UPDATE sessions SET last_seen_at=?, expires_at=? WHERE token=?`, lastSeen, expires, token); err != nil {
+				t.Fatal(err)
+			}
+		}
+		check := func(wantExpires time.Time, wantIP string) {
+			t.Helper()
+			if _, err := svc.ValidateSession(ctx, token); err != nil {
+				t.Fatalf("ValidateSession: %v", err)
+			}
+			var expires time.Time
+			var ip string
+			if err := d.QueryRow(`SELECT expires_at, ip FROM sessions WHERE token=?`, token).Scan(&expires, &ip); err != nil {
+				t.Fatal(err)
+			}
+			if diff := expires.Sub(wantExpires); diff < -time.Minute || diff > time.Minute {
+				t.Errorf("expires at %v, want about %v", expires, wantExpires)
+			}
+			if ip != wantIP {
+				t.Errorf("ip = %q, want %q", ip, wantIP)
+			}
+		}
+
+		// Used within the interval: nothing is written.
+		soon := time.Now().Add(time.Hour)
+		set(time.Now().Add(-sessionTouchInterval/2), soon)
+		check(soon, "203.0.113.1")
+
+		// Used after the interval: the expiry slides and the client updates.
+		set(time.Now().Add(-2*sessionTouchInterval), soon)
+		check(time.Now().Add(svc.sessionExpiry), "203.0.113.2")
+
+		set(time.Now().Add(-time.Hour), time.Now().Add(-time.Second))
+		if _, err := svc.ValidateSession(ctx, token); !errors.Is(err, ErrSessionExpired) {
+			t.Fatalf("expired session: err = %v, want ErrSessionExpired", err)
+		}
+		var n int
+		if err := d.QueryRow(`SELECT COUNT(*) FROM sessions WHERE token=?`, token).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Error("expired session was kept")
+		}
+	})
+}
+
+// TestRevokeOtherSessions checks that signing out everywhere else keeps the
+// current session and leaves other users alone.
+func TestRevokeOtherSessions(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewAuthService(d, nil)
+		var userID, otherID int64
+		if err := d.QueryRow(`INSERT INTO users(email) VALUES('reader@example.com') RETURNING id`).Scan(&userID); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.QueryRow(`INSERT INTO users(email) VALUES('other@example.com') RETURNING id`).Scan(&otherID); err != nil {
+			t.Fatal(err)
+		}
+		var tokens []string
+		for i := 0; i < 3; i++ {
+			token, err := svc.createSession(ctx, userID, SessionKindWeb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens = append(tokens, token)
+		}
+		otherToken, err := svc.createSession(ctx, otherID, SessionKindWeb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		revoked, err := svc.RevokeOtherSessions(ctx, userID, tokens[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != 2 {
+			t.Errorf("revoked %d sessions, want 2", revoked)
+		}
+		sessions, err := svc.ListSessions(ctx, userID, tokens[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 1 || !sessions[0].Current {
+			t.Errorf("sessions after revoking = %+v, want only the current one", sessions)
+		}
+		for _, token := range []string{tokens[0], tokens[2]} {
+			if _, err := svc.ValidateSession(ctx, token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("revoked session: err = %v, want ErrInvalidToken", err)
+			}
+		}
+		if _, err := svc.ValidateSession(ctx, otherToken); err != nil {
+			t.Errorf("other user's session: %v", err)
+		}
+	})
+}
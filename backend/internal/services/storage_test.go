@@ -0,0 +1,697 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"rss-feed-manager/backend/internal/db"
+	"rss-feed-manager/backend/internal/db/dbtest"
+	"rss-feed-manager/backend/internal/models"
+)
+
+// TestItemStorage runs the item lifecycle against every backend: storing
+// entries, listing, read and bookmark state, tags and counts, and keeping
+// that state apart for two users subscribed to the same source.
+func TestItemStorage(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, feedID := subscribe(t, d, svc, "reader@example.com", sourceID)
+		otherID, otherFeedID := subscribe(t, d, svc, "other@example.com", sourceID)
+
+		published := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Second)
+		entries := []*gofeed.Item{
+			{GUID: "a", Title: "First", Link: "https://example.com/a", Description: "one", PublishedParsed: &published},
+			{GUID: "b", Title: "Second", Link: "https://example.com/b", Description: "two"},
+		}
+		save := func() int {
+			tx, err := d.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			added, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", entries)
+			if err != nil {
+				t.Fatalf("saveEntries: %v", err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			return len(added)
+		}
+		if added := save(); added != 2 {
+			t.Fatalf("first save added %d items, expected 2", added)
+		}
+		if added := save(); added != 0 {
+			t.Fatalf("second save added %d items, expected 0", added)
+		}
+
+		items, _, err := svc.ListItems(ctx, userID, nil, nil, "", false, 10, nil, "")
+		if err != nil {
+			t.Fatalf("ListItems: %v", err)
+		}
+		if len(items) != 2 {
+			t.Fatalf("ListItems returned %d items, expected 2", len(items))
+		}
+
+		first := items[0].ID
+		if err := svc.MarkRead(ctx, userID, first, true); err != nil {
+			t.Fatalf("MarkRead: %v", err)
+		}
+		if err := svc.Bookmark(ctx, userID, first, true); err != nil {
+			t.Fatalf("Bookmark: %v", err)
+		}
+		if _, err := svc.SetItemTags(ctx, userID, first, []string{"go", "News"}); err != nil {
+			t.Fatalf("SetItemTags: %v", err)
+		}
+
+		counts, err := svc.Counts(ctx, userID)
+		if err != nil {
+			t.Fatalf("Counts: %v", err)
+		}
+		if counts.Account != (models.ItemCounts{Unread: 1, Total: 2}) {
+			t.Errorf("account counts = %+v, expected 1 unread of 2", counts.Account)
+		}
+		tags, err := svc.ListTags(ctx, userID)
+		if err != nil {
+			t.Fatalf("ListTags: %v", err)
+		}
+		if len(tags) != 2 || tags[0].Name != "go" || tags[1].Name != "News" {
+			t.Errorf("ListTags = %+v, expected go and News", tags)
+		}
+		bookmarks, _, err := svc.ListBookmarks(ctx, userID, "", 10, nil, "")
+		if err != nil {
+			t.Fatalf("ListBookmarks: %v", err)
+		}
+		if len(bookmarks) != 1 || bookmarks[0].ID != first {
+			t.Errorf("ListBookmarks returned %d items, expected item %d", len(bookmarks), first)
+		}
+
+		switch n, err := svc.CountSearch(ctx, userID, SearchQuery{Query: "second"}); {
+		case errors.Is(err, ErrSearchUnavailable):
+		case err != nil:
+			t.Errorf("CountSearch: %v", err)
+		case n != 1:
+			t.Errorf("CountSearch = %d, expected 1", n)
+		}
+
+		n, err := svc.MarkAllRead(ctx, userID, MarkReadScope{FeedID: &feedID})
+		if err != nil {
+			t.Fatalf("MarkAllRead: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("MarkAllRead changed %d items, expected 1", n)
+		}
+
+		// The other subscriber sees the same entries with their own state.
+		counts, err = svc.Counts(ctx, otherID)
+		if err != nil {
+			t.Fatalf("Counts: %v", err)
+		}
+		if counts.Feeds[otherFeedID] != (models.ItemCounts{Unread: 2, Total: 2}) {
+			t.Errorf("other feed counts = %+v, expected 2 unread of 2", counts.Feeds[otherFeedID])
+		}
+		if tags, err := svc.ListTags(ctx, otherID); err != nil || len(tags) != 0 {
+			t.Errorf("other user sees tags %+v, %v", tags, err)
+		}
+
+		// Unsubscribing drops only that user's state; the last one out
+		// removes the source.
+		if err := svc.DeleteFeed(ctx, userID, feedID); err != nil {
+			t.Fatalf("DeleteFeed: %v", err)
+		}
+		var entriesLeft, stateLeft int
+		if err := d.QueryRow(`SELECT COUNT(*) FROM entries WHERE source_id=?`, sourceID).Scan(&entriesLeft); err != nil || entriesLeft != 2 {
+			t.Errorf("%d entries left after one unsubscribed, expected 2 (%v)", entriesLeft, err)
+		}
+		if err := d.QueryRow(`SELECT COUNT(*) FROM item_state WHERE user_id=?`, userID).Scan(&stateLeft); err != nil || stateLeft != 0 {
+			t.Errorf("%d state rows left for the unsubscribed user (%v)", stateLeft, err)
+		}
+		if err := svc.DeleteFeed(ctx, otherID, otherFeedID); err != nil {
+			t.Fatalf("DeleteFeed: %v", err)
+		}
+		if err := d.QueryRow(`SELECT COUNT(*) FROM entries WHERE source_id=?`, sourceID).Scan(&entriesLeft); err != nil || entriesLeft != 0 {
+			t.Errorf("%d entries left without subscribers (%v)", entriesLeft, err)
+		}
+	})
+}
+
+// TestPerUserRetention checks that each subscriber of a source sees entries
+// for their own retention period, while bookmarked and tagged entries stay
+// visible to whoever kept them, and that read state on entries hidden by
+// retention survives unsubscribing from other feeds.
+func TestPerUserRetention(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		shortID, shortFeed := subscribe(t, d, svc, "short@example.com", sourceID)
+		longID, _ := subscribe(t, d, svc, "long@example.com", sourceID)
+		if err := svc.SetRetentionDays(ctx, shortID, 2); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.SetRetentionDays(ctx, longID, 90); err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		fresh, week, kept := now.Add(-time.Hour), now.AddDate(0, 0, -7), now.AddDate(0, 0, -10)
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", []*gofeed.Item{
+			{GUID: "fresh", Title: "Fresh", PublishedParsed: &fresh},
+			{GUID: "week", Title: "A week old", PublishedParsed: &week},
+			{GUID: "kept", Title: "Kept", PublishedParsed: &kept},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		// Entries are stored for the longest retention among subscribers.
+		if err := svc.pruneEntries(ctx, tx, sourceID, 90); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		guids := func(userID int64) map[string]int64 {
+			t.Helper()
+			items, _, err := svc.ListItems(ctx, userID, nil, nil, "", false, 10, nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := map[string]int64{}
+			for _, it := range items {
+				out[it.GUID] = it.ID
+			}
+			return out
+		}
+		long := guids(longID)
+		if len(long) != 3 {
+			t.Fatalf("90-day subscriber sees %v, want all three", long)
+		}
+		if err := svc.Bookmark(ctx, shortID, long["kept"], true); err != nil {
+			t.Fatal(err)
+		}
+
+		short := guids(shortID)
+		if _, ok := short["week"]; ok || len(short) != 2 {
+			t.Errorf("2-day subscriber sees %v, want fresh and the bookmarked one", short)
+		}
+		counts, err := svc.Counts(ctx, shortID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts.Account.Total != 2 {
+			t.Errorf("2-day subscriber counts %+v, want 2 in total", counts.Account)
+		}
+		if _, err := svc.GetItem(ctx, shortID, long["week"]); err == nil {
+			t.Errorf("2-day subscriber can open an entry past their retention")
+		}
+
+		// Read while visible, then hidden by a shorter retention: dropping
+		// an unrelated subscription must not clear that state.
+		setRetention := func(days int) {
+			t.Helper()
+			if err := svc.SetRetentionDays(ctx, shortID, days); err != nil {
+				t.Fatal(err)
+			}
+		}
+		setRetention(90)
+		if err := svc.MarkRead(ctx, shortID, long["week"], true); err != nil {
+			t.Fatal(err)
+		}
+		setRetention(2)
+		var otherSource, otherFeed int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.org/feed", "Other").Scan(&otherSource); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.QueryRow(`INSERT INTO feeds(user_id, folder_id, source_id)
+			SELECT user_id, folder_id, ? FROM feeds WHERE id=? RETURNING id`, otherSource, shortFeed).Scan(&otherFeed); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.DeleteFeed(ctx, shortID, otherFeed); err != nil {
+			t.Fatal(err)
+		}
+		setRetention(90)
+		if item, err := svc.GetItem(ctx, shortID, long["week"]); err != nil || !item.State.IsRead {
+			t.Errorf("read state after unsubscribing elsewhere: read=%v, err=%v; want read", item.State.IsRead, err)
+		}
+
+		// Unsubscribing from the source itself clears the state.
+		if err := svc.DeleteFeed(ctx, shortID, shortFeed); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := d.QueryRow(`SELECT COUNT(*) FROM item_state WHERE user_id=?`, shortID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%d item_state rows left after unsubscribing from the source", n)
+		}
+	})
+}
+
+// TestPerSubscriptionPause checks that a dead source pauses every
+// subscription, that resuming only unpauses the user who resumed, and that
+// the source is polled through an unpaused subscription only.
+func TestPerSubscriptionPause(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		firstID, firstFeed := subscribe(t, d, svc, "first@example.com", sourceID)
+		secondID, secondFeed := subscribe(t, d, svc, "second@example.com", sourceID)
+
+		if _, err := d.Exec(`UPDATE sources SET consecutive_failures=? WHERE id=?`, maxConsecutiveFailures-1, sourceID); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.recordFetchFailure(ctx, sourceID, errors.New("connection refused")); err != nil {
+			t.Fatal(err)
+		}
+		paused := func(feedID int64) bool {
+			t.Helper()
+			var p bool
+			if err := d.QueryRow(`SELECT paused FROM feeds WHERE id=?`, feedID).Scan(&p); err != nil {
+				t.Fatal(err)
+			}
+			return p
+		}
+		if !paused(firstFeed) || !paused(secondFeed) {
+			t.Fatal("dead source did not pause its subscriptions")
+		}
+		due := func(userID int64) []int64 {
+			t.Helper()
+			ids, err := svc.ListDueFeedIDs(ctx, userID, time.Now().Add(365*24*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ids
+		}
+		if len(due(firstID))+len(due(secondID)) != 0 {
+			t.Error("a source with every subscription paused is still polled")
+		}
+
+		// The second, newer subscriber resumes; the source is polled through
+		// their subscription while the first stays paused.
+		if err := svc.ResumeFeed(ctx, secondID, secondFeed); err != nil {
+			t.Fatal(err)
+		}
+		if !paused(firstFeed) || paused(secondFeed) {
+			t.Errorf("after resume: first paused=%v, second paused=%v", paused(firstFeed), paused(secondFeed))
+		}
+		if ids := due(secondID); len(ids) != 1 || ids[0] != secondFeed {
+			t.Errorf("second subscriber due feeds = %v, want %d", ids, secondFeed)
+		}
+		if ids := due(firstID); len(ids) != 0 {
+			t.Errorf("paused first subscriber due feeds = %v", ids)
+		}
+
+		// Another failure does not pause the resumed subscription at once.
+		if err := svc.recordFetchFailure(ctx, sourceID, errors.New("connection refused")); err != nil {
+			t.Fatal(err)
+		}
+		if paused(secondFeed) {
+			t.Error("resumed subscription paused again after one failure")
+		}
+	})
+}
+
+// TestSearchPaging pages through search results one hit at a time in every
+// sort order, with ties in both rank and time, and expects every match
+// exactly once and in order.
+func TestSearchPaging(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, _ := subscribe(t, d, svc, "reader@example.com", sourceID)
+
+		day := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+		earlier := day.Add(-time.Hour)
+		entries := []*gofeed.Item{
+			{GUID: "a", Title: "Gopher news", Description: "gopher", PublishedParsed: &day},
+			{GUID: "b", Title: "Gopher news", Description: "gopher", PublishedParsed: &day},
+			{GUID: "c", Title: "Gopher", Description: "gopher gopher gopher", PublishedParsed: &earlier},
+			{GUID: "d", Title: "Weekly", Description: "a gopher appears"},
+			{GUID: "e", Title: "Weekly", Description: "a gopher appears"},
+			{GUID: "f", Title: "Unrelated", Description: "nothing to see"},
+		}
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", entries); err != nil {
+			t.Fatalf("saveEntries: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, sort := range []string{"relevance", "latest", "oldest"} {
+			sq := SearchQuery{Query: "gopher", Sort: sort, Limit: 5}
+			all, next, err := svc.SearchItems(ctx, userID, sq)
+			if errors.Is(err, ErrSearchUnavailable) {
+				t.Skip("search index not available")
+			}
+			if err != nil {
+				t.Fatalf("%s: SearchItems: %v", sort, err)
+			}
+			if len(all) != 5 || next != nil {
+				t.Fatalf("%s: got %d hits (next %v), expected all 5 in one page", sort, len(all), next)
+			}
+
+			sq.Limit = 1
+			var paged []int64
+			for page := 0; page < 10; page++ {
+				hits, next, err := svc.SearchItems(ctx, userID, sq)
+				if err != nil {
+					t.Fatalf("%s: SearchItems page %d: %v", sort, page, err)
+				}
+				for _, hit := range hits {
+					paged = append(paged, hit.ID)
+				}
+				if next == nil {
+					break
+				}
+				sq.Cursor = next
+			}
+			if len(paged) != len(all) {
+				t.Fatalf("%s: paging returned %v, expected %d hits", sort, paged, len(all))
+			}
+			for i, hit := range all {
+				if paged[i] != hit.ID {
+					t.Errorf("%s: paged order %v differs from a single page at %d", sort, paged, i)
+					break
+				}
+			}
+		}
+	})
+}
+
+// TestMarkAllReadScope checks that a cursor bound follows the listing's
+// sort and that folder and feed scopes leave other items alone.
+func TestMarkAllReadScope(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		source := func(url string) int64 {
+			var id int64
+			if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`, url, url).Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			return id
+		}
+		newsID, otherID := source("https://example.com/news"), source("https://example.com/other")
+		userID, newsFeed := subscribe(t, d, svc, "reader@example.com", newsID)
+		folder, err := svc.CreateFolder(ctx, userID, "Other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var otherFeed int64
+		if err := d.QueryRow(`INSERT INTO feeds(user_id, folder_id, source_id) VALUES(?, ?, ?) RETURNING id`,
+			userID, folder.ID, otherID).Scan(&otherFeed); err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		at := func(hours int) *time.Time { t := now.Add(-time.Duration(hours) * time.Hour); return &t }
+		save := func(sourceID int64, entries ...*gofeed.Item) {
+			tx, err := d.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", entries); err != nil {
+				t.Fatalf("saveEntries: %v", err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		save(newsID,
+			&gofeed.Item{GUID: "old", Title: "Old", PublishedParsed: at(3)},
+			&gofeed.Item{GUID: "mid", Title: "Mid", PublishedParsed: at(2)},
+			&gofeed.Item{GUID: "new", Title: "New", PublishedParsed: at(1)})
+		save(otherID, &gofeed.Item{GUID: "elsewhere", Title: "Elsewhere", PublishedParsed: at(2)})
+
+		// unread lists the GUIDs still unread, oldest first, and resets
+		// every item to unread for the next case.
+		unread := func() []string {
+			t.Helper()
+			items, _, err := svc.ListItems(ctx, userID, nil, nil, "", true, 10, nil, string(SortOldest))
+			if err != nil {
+				t.Fatalf("ListItems: %v", err)
+			}
+			guids := []string{}
+			for _, it := range items {
+				guids = append(guids, it.GUID)
+			}
+			if _, err := d.Exec(`DELETE FROM item_state WHERE user_id=?`, userID); err != nil {
+				t.Fatal(err)
+			}
+			return guids
+		}
+		// cursorAt is the listing cursor of the item with this GUID.
+		cursorAt := func(guid string) *ItemCursor {
+			t.Helper()
+			var id int64
+			if err := d.QueryRow(`SELECT id FROM items WHERE user_id=? AND guid=?`, userID, guid).Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			return &ItemCursor{Timestamp: at(2).Unix(), ID: id}
+		}
+
+		for _, tc := range []struct {
+			name   string
+			scope  MarkReadScope
+			marked int64
+			unread []string
+		}{
+			{"newest first from cursor", MarkReadScope{FeedID: &newsFeed, Cursor: cursorAt("mid"), Sort: string(SortLatest)},
+				2, []string{"elsewhere", "new"}},
+			{"oldest first from cursor", MarkReadScope{FeedID: &newsFeed, Cursor: cursorAt("mid"), Sort: string(SortOldest)},
+				2, []string{"old", "elsewhere"}},
+			{"default sort is newest first", MarkReadScope{FeedID: &newsFeed, Cursor: cursorAt("mid")},
+				2, []string{"elsewhere", "new"}},
+			{"before", MarkReadScope{FeedID: &newsFeed, Before: at(1), Sort: string(SortOldest)},
+				2, []string{"elsewhere", "new"}},
+			{"folder", MarkReadScope{FolderID: &folder.ID},
+				1, []string{"old", "mid", "new"}},
+			{"feed", MarkReadScope{FeedID: &newsFeed},
+				3, []string{"elsewhere"}},
+			{"folder from cursor", MarkReadScope{FolderID: &folder.ID, Cursor: cursorAt("mid"), Sort: string(SortOldest)},
+				1, []string{"old", "mid", "new"}},
+		} {
+			n, err := svc.MarkAllRead(ctx, userID, tc.scope)
+			if err != nil {
+				t.Fatalf("%s: MarkAllRead: %v", tc.name, err)
+			}
+			got := unread()
+			if n != tc.marked || strings.Join(got, ",") != strings.Join(tc.unread, ",") {
+				t.Errorf("%s: marked %d, unread %v; expected %d, %v", tc.name, n, got, tc.marked, tc.unread)
+			}
+		}
+	})
+}
+
+// TestPruneKeepsKeptEntries checks that pruning a source drops entries past
+// retention unless a subscriber has bookmarked or tagged them.
+func TestPruneKeepsKeptEntries(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, _ := subscribe(t, d, svc, "reader@example.com", sourceID)
+
+		old := time.Now().UTC().AddDate(0, 0, -100).Truncate(time.Second)
+		fresh := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", []*gofeed.Item{
+			{GUID: "tagged", Title: "Tagged", PublishedParsed: &old},
+			{GUID: "bookmarked", Title: "Bookmarked", PublishedParsed: &old},
+			{GUID: "stale", Title: "Stale", PublishedParsed: &old},
+			{GUID: "fresh", Title: "Fresh", PublishedParsed: &fresh},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		entryID := func(guid string) int64 {
+			t.Helper()
+			var id int64
+			if err := d.QueryRow(`SELECT id FROM entries WHERE source_id=? AND guid=?`, sourceID, guid).Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			return id
+		}
+		// Tag and bookmark while the entries are within the user's
+		// retention, then let them age out of it.
+		if err := svc.SetRetentionDays(ctx, userID, 365); err != nil {
+			t.Fatal(err)
+		}
+		tagged := entryID("tagged")
+		if _, err := svc.SetItemTags(ctx, userID, tagged, []string{"keep"}); err != nil {
+			t.Fatalf("SetItemTags: %v", err)
+		}
+		if err := svc.Bookmark(ctx, userID, entryID("bookmarked"), true); err != nil {
+			t.Fatalf("Bookmark: %v", err)
+		}
+		if err := svc.SetRetentionDays(ctx, userID, 30); err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err = d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.pruneEntries(ctx, tx, sourceID, 30); err != nil {
+			t.Fatalf("pruneEntries: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := d.Query(`SELECT guid FROM entries WHERE source_id=? ORDER BY guid`, sourceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var left []string
+		for rows.Next() {
+			var guid string
+			if err := rows.Scan(&guid); err != nil {
+				t.Fatal(err)
+			}
+			left = append(left, guid)
+		}
+		if strings.Join(left, ",") != "bookmarked,fresh,tagged" {
+			t.Errorf("entries left after pruning = %v, expected bookmarked, fresh and tagged", left)
+		}
+		items, _, err := svc.ListItems(ctx, userID, nil, nil, "keep", false, 10, nil, "")
+		if err != nil {
+			t.Fatalf("ListItems: %v", err)
+		}
+		if len(items) != 1 || items[0].ID != tagged {
+			t.Errorf("tag listing returned %d items, expected the tagged entry", len(items))
+		}
+	})
+}
+
+// TestSavedSearchCountsAndDigest checks that listing saved searches does not
+// count them, and that counts and the digest degrade without a search index
+// instead of failing.
+func TestSavedSearchCountsAndDigest(t *testing.T) {
+	dbtest.EachMigrated(t, func(t *testing.T, d *db.DB) {
+		ctx := context.Background()
+		svc := NewFeedService(d, nil)
+		digest := NewDigestService(d, nil, svc)
+
+		var sourceID int64
+		if err := d.QueryRow(`INSERT INTO sources(url, title) VALUES(?, ?) RETURNING id`,
+			"https://example.com/feed", "Example").Scan(&sourceID); err != nil {
+			t.Fatal(err)
+		}
+		userID, _ := subscribe(t, d, svc, "reader@example.com", sourceID)
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.saveEntries(ctx, tx, sourceID, "https://example.com", []*gofeed.Item{
+			{GUID: "a", Title: "Gopher weekly", Link: "https://example.com/a"},
+			{GUID: "b", Title: "Cooking", Link: "https://example.com/b"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.CreateSavedSearch(ctx, userID, models.SavedSearch{Name: "Go", Query: "gopher", Digest: true}); err != nil {
+			t.Fatalf("CreateSavedSearch: %v", err)
+		}
+
+		searches, err := svc.ListSavedSearches(ctx, userID)
+		if err != nil {
+			t.Fatalf("ListSavedSearches: %v", err)
+		}
+		if len(searches) != 1 || searches[0].UnreadCount != nil {
+			t.Fatalf("ListSavedSearches = %+v, expected one search without a count", searches)
+		}
+		svc.CountSavedSearches(ctx, userID, searches)
+
+		entries, err := digest.digestEntries(ctx, userID, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("digestEntries: %v", err)
+		}
+		if svc.hasSearchIndex(ctx, d) {
+			if c := searches[0].UnreadCount; c == nil || *c != 1 {
+				t.Errorf("unread count = %v, expected 1", c)
+			}
+			if len(entries) != 1 || entries[0].title != "Gopher weekly" {
+				t.Errorf("digest = %+v, expected only the saved search match", entries)
+			}
+		} else {
+			if searches[0].UnreadCount != nil {
+				t.Errorf("unread count = %d without a search index", *searches[0].UnreadCount)
+			}
+			if len(entries) != 2 {
+				t.Errorf("digest has %d entries without a search index, expected every new item", len(entries))
+			}
+		}
+	})
+}
+
+// subscribe creates a user with a folder subscribed to the source.
+func subscribe(t *testing.T, d *db.DB, svc *FeedService, email string, sourceID int64) (userID, feedID int64) {
+	t.Helper()
+	if err := d.QueryRow(`INSERT INTO users(email) VALUES(?) RETURNING id`, email).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	folder, err := svc.CreateFolder(context.Background(), userID, "News")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.QueryRow(`INSERT INTO feeds(user_id, folder_id, source_id) VALUES(?, ?, ?) RETURNING id`,
+		userID, folder.ID, sourceID).Scan(&feedID); err != nil {
+		t.Fatal(err)
+	}
+	return userID, feedID
+}
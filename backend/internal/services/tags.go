@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"rss-feed-manager/backend/internal/models"
+)
+
+const maxTagLen = 64
+
+// normalizeTag trims a tag and collapses inner whitespace.
+func normalizeTag(raw string) (string, error) {
+	tag := strings.Join(strings.Fields(raw), " ")
+	if tag == "" {
+		return "", errors.New("tag required")
+	}
+	if len(tag) > maxTagLen {
+		return "", fmt.Errorf("tag longer than %d characters", maxTagLen)
+	}
+	return tag, nil
+}
+
+// ListTags returns the user's tags with item counts, alphabetically.
+func (s *FeedService) ListTags(ctx context.Context, userID int64) ([]models.Tag, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT item_tags.tag, COUNT(1), SUM(CASE WHEN COALESCE(item_state.is_read,0)=0 THEN 1 ELSE 0 END)
+		FROM item_tags
+		LEFT JOIN item_state ON item_state.item_id = item_tags.item_id AND item_state.user_id = item_tags.user_id
+		WHERE item_tags.user_id=?
+		GROUP BY item_tags.tag
+		ORDER BY lower(item_tags.tag)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Name, &t.Count, &t.UnreadCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// SetItemTags replaces the tags on an item and returns the stored set.
+func (s *FeedService) SetItemTags(ctx context.Context, userID, itemID int64, tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	clean := []string{}
+	for _, raw := range tags {
+		tag, err := normalizeTag(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			clean = append(clean, tag)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(1) FROM items WHERE id=? AND user_id=?`, itemID, userID).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.New("item not found")
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM item_tags WHERE item_id=? AND user_id=?`, itemID, userID); err != nil {
+		return nil, err
+	}
+	for _, tag := range clean {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO item_tags(item_id, user_id, tag) VALUES(?, ?, ?)`, itemID, userID, tag); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return clean, nil
+}
+
+// DeleteTag removes a tag from every item of the user.
+func (s *FeedService) DeleteTag(ctx context.Context, userID int64, tag string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM item_tags WHERE user_id=? AND tag=?`, userID, tag)
+	return err
+}
+
+// attachTags fills in Tags for a page of items with a single query.
+func (s *FeedService) attachTags(ctx context.Context, userID int64, items []models.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(items))
+	args := []interface{}{userID}
+	for i, it := range items {
+		index[it.ID] = i
+		args = append(args, it.ID)
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT item_id, tag FROM item_tags
+		WHERE user_id=? AND item_id IN (%s)
+		ORDER BY lower(tag)`, strings.TrimSuffix(strings.Repeat("?,", len(items)), ",")), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			itemID int64
+			tag    string
+		)
+		if err := rows.Scan(&itemID, &tag); err != nil {
+			return err
+		}
+		i := index[itemID]
+		items[i].Tags = append(items[i].Tags, tag)
+	}
+	return rows.Err()
+}
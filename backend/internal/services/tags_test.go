@@ -0,0 +1,29 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"go", "go", false},
+		{"  read \t later  ", "read later", false},
+		{"   ", "", true},
+		{strings.Repeat("x", maxTagLen+1), "", true},
+	}
+	for _, tc := range tests {
+		got, err := normalizeTag(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("normalizeTag(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("normalizeTag(%q) = %q, expected %q", tc.input, got, tc.want)
+		}
+	}
+}
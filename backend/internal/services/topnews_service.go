@@ -17,11 +17,12 @@ import (
 	"sync"
 	"time"
 
+	"rss-feed-manager/backend/internal/db"
 	"rss-feed-manager/backend/internal/models"
 )
 
 type TopNewsService struct {
-	db              *sql.DB
+	db              *db.DB
 	apiKey          string
 	model           string
 	client          *http.Client
@@ -45,7 +46,7 @@ const (
 	defaultGeminiMaxTokens   = 512
 )
 
-func NewTopNewsService(db *sql.DB) *TopNewsService {
+func NewTopNewsService(db *db.DB) *TopNewsService {
 	model := os.Getenv("GEMINI_MODEL")
 	if model == "" {
 		model = "gemini-3-flash-preview"
@@ -167,12 +168,13 @@ func (s *TopNewsService) fetchRecentItems(ctx context.Context, userID int64, lim
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT items.id, items.feed_id, items.guid, items.link, items.title, items.author, items.published_at, items.summary_text,
 			   items.content_html, items.media_json, items.created_at,
-			   IFNULL(item_state.is_read,0), IFNULL(item_state.is_bookmarked,0), item_state.bookmarked_at,
-			   feeds.title, feeds.site_url
+			   COALESCE(item_state.is_read,0), COALESCE(item_state.is_bookmarked,0), item_state.bookmarked_at,
+			   COALESCE(feeds.title, sources.title), sources.site_url
 		FROM items
-		LEFT JOIN item_state ON item_state.item_id = items.id
+		LEFT JOIN item_state ON item_state.item_id = items.id AND item_state.user_id = items.user_id
 		JOIN feeds ON feeds.id = items.feed_id
-		WHERE items.user_id=?
+		JOIN sources ON sources.id = feeds.source_id
+		WHERE items.user_id=? AND COALESCE(item_state.is_hidden,0)=0
 		ORDER BY items.created_at DESC
 		LIMIT ?`, userID, limit)
 	if err != nil {
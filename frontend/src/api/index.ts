@@ -1,5 +1,6 @@
+import { isAxiosError } from "axios";
 import api from "./client";
-import type { Folder, Feed, Item, ReaderResult, AiSummaryResult, User, AuthResponse } from "./types";
+import type { Folder, Feed, Item, ReaderResult, AiSummaryResult, User, AuthResponse, FeedCandidate, SavedSearch, FilterRule, Tag, Counts, RefreshJob, APIToken, Session } from "./types";
 
 // Auth functions
 export async function sendOTP(email: string): Promise<{ message: string }> {
@@ -31,11 +32,46 @@ export async function fetchMe(): Promise<User> {
   return res.data;
 }
 
+export async function fetchSessions(): Promise<Session[]> {
+  const res = await api.get<Session[]>("/api/auth/sessions");
+  return res.data;
+}
+
+export async function revokeSession(id: number) {
+  await api.delete(`/api/auth/sessions/${id}`);
+}
+
+// Signs out every other session; returns how many were revoked.
+export async function revokeOtherSessions(): Promise<number> {
+  const res = await api.delete<{ revoked: number }>("/api/auth/sessions");
+  return res.data.revoked;
+}
+
+// Personal API tokens; the token itself is only returned on creation.
+export async function fetchAPITokens(): Promise<APIToken[]> {
+  const res = await api.get<APIToken[]>("/api/tokens");
+  return res.data;
+}
+
+export async function createAPIToken(name: string, scope: APIToken["scope"]): Promise<APIToken> {
+  const res = await api.post<APIToken>("/api/tokens", { name, scope });
+  return res.data;
+}
+
+export async function revokeAPIToken(id: number) {
+  await api.delete(`/api/tokens/${id}`);
+}
+
 export async function fetchFolders(): Promise<Folder[]> {
   const res = await api.get<{ folders: Folder[] }>("/api/folders");
   return res.data.folders;
 }
 
+export async function fetchCounts(): Promise<Counts> {
+  const res = await api.get<Counts>("/api/counts");
+  return res.data;
+}
+
 export async function createFolder(name: string): Promise<Folder> {
   const res = await api.post<Folder>("/api/folders", { name });
   return res.data;
@@ -49,18 +85,85 @@ export async function deleteFolder(id: number): Promise<void> {
   await api.delete(`/api/folders/${id}`);
 }
 
+// AmbiguousFeedError is thrown by addFeed when the URL is a page that links
+// to several feeds; the caller should let the user pick one of candidates.
+export class AmbiguousFeedError extends Error {
+  candidates: FeedCandidate[];
+
+  constructor(message: string, candidates: FeedCandidate[]) {
+    super(message);
+    this.name = "AmbiguousFeedError";
+    this.candidates = candidates;
+  }
+}
+
 export async function addFeed(folderId: number, url: string): Promise<Feed> {
-  const res = await api.post<Feed>("/api/feeds", { folderId, url });
-  return res.data;
+  try {
+    const res = await api.post<Feed>("/api/feeds", { folderId, url });
+    return res.data;
+  } catch (err) {
+    const data = isAxiosError<{ error?: string; candidates?: FeedCandidate[] }>(err) ? err.response?.data : undefined;
+    if (isAxiosError(err) && err.response?.status === 409 && data?.candidates?.length) {
+      throw new AmbiguousFeedError(data.error ?? "Choose a feed", data.candidates);
+    }
+    throw err;
+  }
 }
 
 export async function deleteFeed(id: number): Promise<void> {
   await api.delete(`/api/feeds/${id}`);
 }
 
+export type SavedSearchInput = Pick<SavedSearch, "name" | "query" | "folderId" | "feedId" | "unreadOnly" | "withinDays" | "digest">;
+
+export async function fetchSavedSearches(): Promise<SavedSearch[]> {
+  const res = await api.get<{ savedSearches: SavedSearch[] }>("/api/saved-searches");
+  return res.data.savedSearches;
+}
+
+export async function createSavedSearch(input: SavedSearchInput): Promise<SavedSearch> {
+  const res = await api.post<SavedSearch>("/api/saved-searches", input);
+  return res.data;
+}
+
+export async function updateSavedSearch(id: number, input: SavedSearchInput): Promise<void> {
+  await api.put(`/api/saved-searches/${id}`, input);
+}
+
+export async function deleteSavedSearch(id: number): Promise<void> {
+  await api.delete(`/api/saved-searches/${id}`);
+}
+
+export type FilterRuleInput = Pick<FilterRule, "name" | "field" | "matchType" | "pattern" | "action" | "tag" | "enabled">;
+
+export async function fetchFilterRules(): Promise<FilterRule[]> {
+  const res = await api.get<{ filters: FilterRule[] }>("/api/filters");
+  return res.data.filters;
+}
+
+export async function createFilterRule(input: FilterRuleInput): Promise<FilterRule> {
+  const res = await api.post<FilterRule>("/api/filters", input);
+  return res.data;
+}
+
+export async function updateFilterRule(id: number, input: FilterRuleInput): Promise<void> {
+  await api.put(`/api/filters/${id}`, input);
+}
+
+export async function deleteFilterRule(id: number): Promise<void> {
+  await api.delete(`/api/filters/${id}`);
+}
+
+export async function dryRunFilterRule(input: FilterRuleInput, limit?: number) {
+  const res = await api.post("/api/filters/dry-run", input, { params: { limit } });
+  return res.data as { items: Item[]; total: number };
+}
+
 export type ItemListParams = {
   folderId?: number;
   feedId?: number;
+  savedSearchId?: number;
+  tag?: string;
   unread?: boolean;
   limit?: number;
   cursor?: string;
@@ -72,11 +175,33 @@ export async function fetchItems(params: ItemListParams): Promise<{ items: Item[
   return res.data;
 }
 
-export async function fetchBookmarks(params: { limit?: number; cursor?: string; sort?: "popular_latest" | "latest" | "oldest" }) {
+export async function fetchBookmarks(params: { tag?: string; limit?: number; cursor?: string; sort?: "popular_latest" | "latest" | "oldest" }) {
   const res = await api.get("/api/bookmarks", { params });
   return res.data as { items: Item[]; nextCursor?: string };
 }
 
+export async function fetchHighlights(params: { limit?: number; cursor?: string; sort?: "popular_latest" | "latest" | "oldest" }) {
+  const res = await api.get("/api/highlights", { params });
+  return res.data as { items: Item[]; nextCursor?: string };
+}
+
+export type SearchParams = {
+  q: string;
+  folderId?: number;
+  feedId?: number;
+  since?: string;
+  until?: string;
+  unread?: boolean;
+  sort?: "relevance" | "latest" | "oldest";
+  limit?: number;
+  cursor?: string;
+};
+
+export async function searchItems(params: SearchParams) {
+  const res = await api.get("/api/search", { params });
+  return res.data as { items: (Item & { snippet: string })[]; nextCursor?: string };
+}
+
 export async function fetchTopNews(limit = 18) {
   const res = await api.get("/api/top-news", { params: { limit } });
   return res.data as { items: Item[]; source?: "ai" | "fallback"; reason?: string; detail?: string };
@@ -86,10 +211,41 @@ export async function markRead(id: number, read: boolean) {
   await api.post(`/api/items/${id}/${read ? "read" : "unread"}`);
 }
 
+export type MarkAllReadParams = {
+  all?: boolean;
+  folderId?: number;
+  feedId?: number;
+  tag?: string;
+  q?: string;
+  savedSearchId?: number;
+  before?: string;
+  cursor?: string;
+  sort?: "popular_latest" | "latest" | "oldest";
+};
+
+export async function markAllRead(params: MarkAllReadParams): Promise<number> {
+  const res = await api.post<{ updated: number }>("/api/items/mark-read", params);
+  return res.data.updated;
+}
+
 export async function bookmark(id: number, set: boolean) {
   await api.post(`/api/items/${id}/${set ? "bookmark" : "unbookmark"}`);
 }
 
+export async function setItemTags(id: number, tags: string[]): Promise<string[]> {
+  const res = await api.put<{ tags: string[] }>(`/api/items/${id}/tags`, { tags });
+  return res.data.tags;
+}
+
+export async function fetchTags(): Promise<Tag[]> {
+  const res = await api.get<{ tags: Tag[] }>("/api/tags");
+  return res.data.tags;
+}
+
+export async function deleteTag(name: string): Promise<void> {
+  await api.delete(`/api/tags/${encodeURIComponent(name)}`);
+}
+
 export async function readerView(url: string): Promise<ReaderResult> {
   const res = await api.get<ReaderResult>("/api/reader", { params: { url } });
   return res.data;
@@ -105,8 +261,23 @@ export async function refreshFeed(id: number) {
   await api.post(`/api/feeds/${id}/refresh`);
 }
 
-export async function refreshAll() {
-  await api.post("/api/refresh/all");
+// Starts (or joins) a background refresh of every feed.
+export async function refreshAll(): Promise<RefreshJob> {
+  const res = await api.post<{ jobId: string; job: RefreshJob }>("/api/refresh/all");
+  return res.data.job;
+}
+
+export async function fetchJob(id: string): Promise<RefreshJob> {
+  const res = await api.get<RefreshJob>(`/api/jobs/${id}`);
+  return res.data;
+}
+
+export async function waitForJob(id: string, intervalMs = 1000): Promise<RefreshJob> {
+  for (;;) {
+    const job = await fetchJob(id);
+    if (job.status === "finished") return job;
+    await new Promise((resolve) => setTimeout(resolve, intervalMs));
+  }
 }
 
 export async function refreshFolder(id: number) {
@@ -128,6 +299,89 @@ export async function updateSettings(settings: Partial<UserSettings>): Promise<U
   return res.data;
 }
 
+// Token for published JSON Feed / Atom URLs (/api/publish/{token}/...);
+// null until rotatePublishToken creates one.
+export async function fetchPublishToken(): Promise<string | null> {
+  const res = await api.get<{ token: string }>("/api/settings/publish-token");
+  return res.data.token || null;
+}
+
+export async function rotatePublishToken(): Promise<string> {
+  const res = await api.post<{ token: string }>("/api/settings/publish-token");
+  return res.data.token;
+}
+
+// Fever API access for mobile clients (/api/fever/)
+export async function fetchFeverEnabled(): Promise<boolean> {
+  const res = await api.get<{ enabled: boolean }>("/api/settings/fever");
+  return res.data.enabled;
+}
+
+export async function setFeverPassword(password: string) {
+  await api.put("/api/settings/fever", { password });
+}
+
+export async function disableFever() {
+  await api.delete("/api/settings/fever");
+}
+
+export type ServerEvent =
+  | { type: "items.new"; data: { feedId: number; count: number } }
+  | { type: "item.state"; data: { itemId?: number; itemIds?: number[]; isRead?: boolean; isBookmarked?: boolean } }
+  | { type: "items.read"; data: { count: number } }
+  | { type: "refresh.started"; data: { folderId?: number; total: number } }
+  | { type: "refresh.progress"; data: { folderId?: number; total: number; completed: number; failed: number; feedId: number; error?: string } }
+  | { type: "refresh.finished"; data: { folderId?: number; total: number; completed: number; failed: number; skipped: number } };
+
+// Subscribes to /api/events. EventSource cannot send headers, so each
+// connection opens with a single-use ticket rather than the token, and
+// reconnects fetch a fresh one. Returns a function that closes the stream.
+export function subscribeEvents(onEvent: (event: ServerEvent) => void): () => void {
+  const base = api.defaults.baseURL ?? "";
+  const types: ServerEvent["type"][] = [
+    "items.new",
+    "item.state",
+    "items.read",
+    "refresh.started",
+    "refresh.progress",
+    "refresh.finished",
+  ];
+  let source: EventSource | null = null;
+  let retry: ReturnType<typeof setTimeout> | undefined;
+  let closed = false;
+
+  const reconnect = () => {
+    if (!closed) retry = setTimeout(connect, 5000);
+  };
+  const connect = async () => {
+    try {
+      const res = await api.post<{ ticket: string }>("/api/events/ticket");
+      if (closed) return;
+      source = new EventSource(`${base}/api/events?ticket=${encodeURIComponent(res.data.ticket)}`);
+      for (const type of types) {
+        source.addEventListener(type, (e) => {
+          onEvent({ type, data: JSON.parse((e as MessageEvent).data) } as ServerEvent);
+        });
+      }
+      // The ticket is spent, so EventSource's own retry would be refused.
+      source.onerror = () => {
+        source?.close();
+        source = null;
+        reconnect();
+      };
+    } catch {
+      reconnect();
+    }
+  };
+
+  connect();
+  return () => {
+    closed = true;
+    clearTimeout(retry);
+    source?.close();
+  };
+}
+
 export async function fetchDiscover() {
   const res = await api.get("/api/discover");
   return res.data as {
@@ -139,7 +393,9 @@ export async function fetchDiscover() {
 
 export async function resolveDiscover(url: string) {
   const res = await api.post("/api/discover/resolve", { url });
-  return res.data as { feeds: { title: string; url: string }[] };
+  return res.data as {
+    feeds: { title: string; url: string; format: string; itemCount: number; lastUpdated?: string }[];
+  };
 }
 export const importOPML = async (file: File): Promise<{ message: string; importedCount: number }> => {
   const formData = new FormData();
@@ -156,3 +412,27 @@ export const downloadOPML = async (): Promise<Blob> => {
   const res = await api.get<Blob>("/api/opml/export", { responseType: "blob" });
   return res.data;
 };
+
+export type AccountImportResult = {
+  folders: number;
+  feeds: number;
+  items: number;
+  savedSearches: number;
+  filterRules: number;
+};
+
+export const downloadAccountArchive = async (): Promise<Blob> => {
+  const res = await api.get<Blob>("/api/account/export", { params: { format: "zip" }, responseType: "blob" });
+  return res.data;
+};
+
+export const importAccountArchive = async (file: File): Promise<{ message: string; result: AccountImportResult }> => {
+  const formData = new FormData();
+  formData.append("file", file);
+  const res = await api.post("/api/account/import", formData, {
+    headers: {
+      "Content-Type": "multipart/form-data",
+    },
+  });
+  return res.data;
+};
@@ -4,6 +4,35 @@ export type Folder = {
   name: string;
   createdAt: string;
   feeds: Feed[];
+  unreadCount: number;
+  totalCount: number;
+};
+
+export type SavedSearch = {
+  id: number;
+  userId: number;
+  name: string;
+  query: string;
+  folderId?: number;
+  feedId?: number;
+  unreadOnly: boolean;
+  withinDays?: number;
+  digest: boolean;
+  unreadCount?: number;
+  createdAt: string;
+};
+
+export type FilterRule = {
+  id: number;
+  userId: number;
+  name: string;
+  field: "any" | "title" | "author" | "content" | "link" | "feed" | "folder";
+  matchType: "keyword" | "regex" | "media_type";
+  pattern: string;
+  action: "mark_read" | "bookmark" | "hide" | "tag" | "highlight";
+  tag?: string;
+  enabled: boolean;
+  createdAt: string;
 };
 
 export type Feed = {
@@ -14,6 +43,27 @@ export type Feed = {
   title: string;
   siteUrl: string;
   lastCheckedAt?: string;
+  nextCheckAt?: string;
+  lastSuccessAt?: string;
+  lastError?: string;
+  lastErrorAt?: string;
+  lastHttpStatus?: number;
+  consecutiveFailures: number;
+  paused: boolean;
+  unreadCount: number;
+  totalCount: number;
+};
+
+export type ItemCounts = {
+  unread: number;
+  total: number;
+};
+
+export type Counts = {
+  account: ItemCounts;
+  starred: number;
+  folders: Record<string, ItemCounts>;
+  feeds: Record<string, ItemCounts>;
 };
 
 export type ItemState = {
@@ -39,6 +89,13 @@ export type Item = {
   createdAt: string;
   state: ItemState;
   source?: Feed;
+  tags?: string[];
+};
+
+export type Tag = {
+  name: string;
+  count: number;
+  unreadCount: number;
 };
 
 export type ReaderResult = {
@@ -61,6 +118,27 @@ export type User = {
   createdAt: string;
 };
 
+export type Session = {
+  id: number;
+  kind: "web" | "app";
+  createdAt: string;
+  lastSeenAt?: string;
+  expiresAt: string;
+  ip?: string;
+  userAgent?: string;
+  current: boolean;
+};
+
+export type APIToken = {
+  id: number;
+  name: string;
+  scope: "read" | "read_write";
+  prefix: string;
+  token?: string;
+  createdAt: string;
+  lastUsedAt?: string;
+};
+
 export type AuthResponse = {
   user: User;
   token: string;
@@ -71,3 +149,34 @@ export type AiSummaryResult = {
   source?: "ai" | "fallback";
   reason?: string;
 };
+
+export type RefreshJobFeed = {
+  feedId: number;
+  title: string;
+  status: "pending" | "done" | "failed";
+  fetched: number;
+  newItems: number;
+  error?: string;
+};
+
+export type RefreshJob = {
+  id: string;
+  status: "running" | "finished";
+  total: number;
+  completed: number;
+  failed: number;
+  newItems: number;
+  feeds: RefreshJobFeed[];
+  startedAt: string;
+  finishedAt?: string;
+};
+
+// A feed advertised by a page, as returned with a 409 from POST /api/feeds
+// when the page offers more than one. Candidates come best first.
+export type FeedCandidate = {
+  url: string;
+  title?: string;
+  type?: "rss" | "atom" | "json";
+  comments?: boolean;
+  sameOrigin: boolean;
+};
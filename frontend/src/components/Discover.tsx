@@ -1,11 +1,12 @@
 import { useEffect, useMemo, useState } from "react";
 import { useQuery } from "@tanstack/react-query";
-import { fetchDiscover, resolveDiscover } from "../api";
-import type { Folder } from "../api/types";
+import { AmbiguousFeedError, fetchDiscover, resolveDiscover } from "../api";
+import type { FeedCandidate, Folder } from "../api/types";
 import { BaseModal } from "../modals/BaseModal";
 import { useLog } from "../hooks/useLog";
 import { extractErrorMessage } from "../services/LogService";
 import { Button, Input, Select, Label, FormGroup } from "./ui";
+import { FeedCandidateList } from "./FeedCandidateList";
 
 type Props = {
   folders: Folder[];
@@ -1106,6 +1107,8 @@ export function Discover({ folders, onAddFeed, onCreateFolder }: Props) {
   const [folderChoice, setFolderChoice] = useState<string>("");
   const [newFolderName, setNewFolderName] = useState("");
   const [addError, setAddError] = useState("");
+  const [candidates, setCandidates] = useState<FeedCandidate[]>([]);
+  const [candidateUrl, setCandidateUrl] = useState<string | null>(null);
   const [adding, setAdding] = useState(false);
   const [activeCategory, setActiveCategory] = useState<string | null>(null);
   const [categorySearch, setCategorySearch] = useState("");
@@ -1220,6 +1223,11 @@ export function Discover({ folders, onAddFeed, onCreateFolder }: Props) {
     setAddError("");
   }, [addTarget, folders]);
 
+  useEffect(() => {
+    setCandidates([]);
+    setCandidateUrl(null);
+  }, [addTarget]);
+
   const handleAdd = async () => {
     if (!addTarget) return;
     setAddError("");
@@ -1247,10 +1255,14 @@ export function Discover({ folders, onAddFeed, onCreateFolder }: Props) {
         folderId = selected.id;
         folderName = selected.name;
       }
-      await onAddFeed(folderId, addTarget.url);
+      await onAddFeed(folderId, candidateUrl ?? addTarget.url);
       success("feed", "Feed added", `Added "${addTarget.title}" to ${folderName}`);
       setAddTarget(null);
     } catch (err) {
+      if (err instanceof AmbiguousFeedError) {
+        setCandidates(err.candidates);
+        return;
+      }
       setAddError("Unable to add feed. Try again.");
       logError("feed", "Failed to add feed", extractErrorMessage(err));
     } finally {
@@ -1458,6 +1470,17 @@ export function Discover({ folders, onAddFeed, onCreateFolder }: Props) {
               />
             </FormGroup>
           )}
+          {candidates.length > 0 && (
+            <FeedCandidateList
+              candidates={candidates}
+              disabled={adding}
+              onChoose={(candidate) => {
+                setCandidateUrl(candidate.url);
+                setCandidates([]);
+              }}
+            />
+          )}
+          {candidateUrl && <p className="text-xs text-muted">Feed: {candidateUrl}</p>}
           {addError && <p className="text-xs text-red-600">{addError}</p>}
         </div>
         <div className="mt-5 flex justify-end gap-2">
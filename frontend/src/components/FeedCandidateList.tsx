@@ -0,0 +1,41 @@
+import type { FeedCandidate } from "../api/types";
+import { Badge } from "./ui";
+
+type Props = {
+  candidates: FeedCandidate[];
+  onChoose: (candidate: FeedCandidate) => void;
+  disabled?: boolean;
+};
+
+const typeLabels: Record<string, string> = { rss: "RSS", atom: "Atom", json: "JSON Feed" };
+
+// FeedCandidateList lets the user pick one of the feeds a page links to.
+export function FeedCandidateList({ candidates, onChoose, disabled }: Props) {
+  return (
+    <div className="feed-candidates space-y-1">
+      <p className="text-xs text-gray-500 dark:text-gray-400">This page has several feeds. Choose one:</p>
+      <ul className="space-y-1">
+        {candidates.map((candidate) => (
+          <li key={candidate.url}>
+            <button
+              type="button"
+              disabled={disabled}
+              className="w-full rounded-md border border-gray-200 px-3 py-2 text-left text-sm hover:bg-gray-100 disabled:opacity-50 dark:border-gray-800 dark:hover:bg-gray-800"
+              onClick={() => onChoose(candidate)}
+            >
+              <span className="flex items-center gap-2">
+                <span className="truncate font-medium">{candidate.title || candidate.url}</span>
+                {candidate.type && <Badge>{typeLabels[candidate.type] ?? candidate.type}</Badge>}
+                {candidate.comments && <Badge variant="warning">Comments</Badge>}
+                {!candidate.sameOrigin && <Badge variant="default">Other site</Badge>}
+              </span>
+              {candidate.title && (
+                <span className="block truncate text-xs text-gray-500 dark:text-gray-400">{candidate.url}</span>
+              )}
+            </button>
+          </li>
+        ))}
+      </ul>
+    </div>
+  );
+}
@@ -1,9 +1,11 @@
 import { useEffect, useState } from "react";
-import type { Folder, Feed } from "../api/types";
+import type { Folder, Feed, FeedCandidate } from "../api/types";
+import { AmbiguousFeedError } from "../api";
 import { clsx } from "clsx";
 import { BaseModal } from "../modals/BaseModal";
 import { createPortal } from "react-dom";
 import { Button, Input, FormGroup } from "./ui";
+import { FeedCandidateList } from "./FeedCandidateList";
 
 type Props = {
   folders: Folder[];
@@ -70,6 +72,8 @@ export function FeedTree({
   const [renameValue, setRenameValue] = useState("");
   const [addingFeedFor, setAddingFeedFor] = useState<number | null>(null);
   const [feedUrl, setFeedUrl] = useState("");
+  const [candidates, setCandidates] = useState<FeedCandidate[]>([]);
+  const [addingFeed, setAddingFeed] = useState(false);
   const [menuFolderId, setMenuFolderId] = useState<number | null>(null);
   const [menuFeedId, setMenuFeedId] = useState<number | null>(null);
   const [createFolderOpen, setCreateFolderOpen] = useState(false);
@@ -137,14 +141,22 @@ export function FeedTree({
     setRenameValue("");
   };
 
-  const handleAddFeed = async (folderId: number) => {
-    if (!feedUrl.trim()) return;
+  const handleAddFeed = async (folderId: number, url = feedUrl.trim()) => {
+    if (!url) return;
+    setAddingFeed(true);
     try {
-      await onAddFeed(folderId, feedUrl.trim());
+      await onAddFeed(folderId, url);
       setFeedUrl("");
+      setCandidates([]);
       setAddingFeedFor(null);
     } catch (err) {
+      if (err instanceof AmbiguousFeedError) {
+        setCandidates(err.candidates);
+        return;
+      }
       alert("Failed to add feed. Please check the URL and try again.");
+    } finally {
+      setAddingFeed(false);
     }
   };
 
@@ -264,6 +276,7 @@ export function FeedTree({
                     onClick={(e) => {
                       e.stopPropagation();
                       setAddingFeedFor(folder.id);
+                      setCandidates([]);
                       setMenuFolderId(null);
                     }}
                     title="Add feed"
@@ -332,16 +345,28 @@ export function FeedTree({
               )}
 
               {addingFeedFor === folder.id && (
-                <div className="feed-tree-add ml-6 flex gap-2">
-                  <Input
-                    size="sm"
-                    placeholder="https://feed.url"
-                    value={feedUrl}
-                    onChange={(e) => setFeedUrl(e.target.value)}
-                  />
-                  <Button size="sm" onClick={() => handleAddFeed(folder.id)}>
-                    Add
-                  </Button>
+                <div className="feed-tree-add ml-6 space-y-2">
+                  <div className="flex gap-2">
+                    <Input
+                      size="sm"
+                      placeholder="https://feed.url"
+                      value={feedUrl}
+                      onChange={(e) => {
+                        setFeedUrl(e.target.value);
+                        setCandidates([]);
+                      }}
+                    />
+                    <Button size="sm" loading={addingFeed} onClick={() => handleAddFeed(folder.id)}>
+                      Add
+                    </Button>
+                  </div>
+                  {candidates.length > 0 && (
+                    <FeedCandidateList
+                      candidates={candidates}
+                      disabled={addingFeed}
+                      onChoose={(candidate) => handleAddFeed(folder.id, candidate.url)}
+                    />
+                  )}
                 </div>
               )}
 
@@ -1,5 +1,6 @@
 import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
 import {
+    AmbiguousFeedError,
     addFeed,
     createFolder,
     deleteFeed,
@@ -76,6 +77,8 @@ export function useFolders() {
             success("feed", "Feed added", `Added "${feed.title || feed.url}" to ${folderName}`);
         },
         onError: (err, { url }) => {
+            // The caller asks the user to pick one of the page's feeds.
+            if (err instanceof AmbiguousFeedError) return;
             logError("feed", "Failed to add feed", `${url}: ${extractErrorMessage(err)}`);
         },
     });
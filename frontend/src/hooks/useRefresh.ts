@@ -1,6 +1,6 @@
 import { useEffect } from "react";
 import { useMutation, useQueryClient } from "@tanstack/react-query";
-import { refreshAll, refreshFeed, refreshFolder } from "../api";
+import { refreshAll, refreshFeed, refreshFolder, waitForJob } from "../api";
 import { useLog } from "./useLog";
 import { extractErrorMessage } from "../services/LogService";
 
@@ -14,15 +14,19 @@ export function useRefresh({ getFolderName, getFeedTitle }: RefreshOptions) {
     const { success, info, error: logError } = useLog();
 
     const all = useMutation({
-        mutationFn: refreshAll,
+        mutationFn: async () => {
+            const job = await refreshAll();
+            return job.status === "finished" ? job : waitForJob(job.id);
+        },
         onMutate: () => {
             info("refresh", "Refreshing all feeds", "This may take a moment...");
         },
-        onSuccess: () => {
+        onSuccess: (job) => {
             queryClient.invalidateQueries({ queryKey: ["items"] });
             queryClient.invalidateQueries({ queryKey: ["bookmarks"] });
             queryClient.invalidateQueries({ queryKey: ["folders"] });
-            success("refresh", "All feeds refreshed", "Your feeds are now up to date");
+            const failed = job.failed > 0 ? `, ${job.failed} failed` : "";
+            success("refresh", "All feeds refreshed", `${job.newItems} new items${failed}`);
         },
         onError: (err) => {
             logError("refresh", "Failed to refresh feeds", extractErrorMessage(err));
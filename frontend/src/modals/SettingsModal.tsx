@@ -2,10 +2,20 @@ import { useEffect, useState } from "react";
 import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
 import { ACCENTS, AccentKey, THEME_PRESETS, ThemePreset, useTheme } from "../hooks/useTheme";
 import { BaseModal } from "./BaseModal";
-import { fetchSettings, updateSettings, importOPML, downloadOPML } from "../api";
+import {
+  fetchSettings,
+  updateSettings,
+  importOPML,
+  downloadOPML,
+  importAccountArchive,
+  downloadAccountArchive,
+  fetchFeverEnabled,
+  setFeverPassword,
+  disableFever,
+} from "../api";
 import { useLog } from "../hooks/useLog";
 import { extractErrorMessage } from "../services/LogService";
-import { Button, Select, Radio, FormGroup } from "../components/ui";
+import { Button, Input, Select, Radio, FormGroup } from "../components/ui";
 
 type Props = {
   open: boolean;
@@ -35,7 +45,7 @@ const FONT_OPTIONS = [
 export function SettingsModal({ open, onClose }: Props) {
   const { theme, setTheme, fontFamily, setFontFamily, fontSize, setFontSize, accent, setAccent } = useTheme();
   const { success, error: logError } = useLog();
-  const [section, setSection] = useState<"general" | "appearance" | "reading" | "storage" | "data">("general");
+  const [section, setSection] = useState<"general" | "appearance" | "reading" | "storage" | "apps" | "data">("general");
   const [startPage, setStartPage] = useState<StartPage>("today");
   const [sortPref, setSortPref] = useState<SortPref>("popular_latest");
   const [hideRead, setHideRead] = useState(false);
@@ -44,6 +54,13 @@ export function SettingsModal({ open, onClose }: Props) {
   const [importing, setImporting] = useState(false);
   const [exporting, setExporting] = useState(false);
 
+  // Account archive states
+  const [importingArchive, setImportingArchive] = useState(false);
+  const [exportingArchive, setExportingArchive] = useState(false);
+
+  // Fever password for mobile apps
+  const [feverPassword, setFeverPasswordInput] = useState("");
+
   const queryClient = useQueryClient();
   const settingsQuery = useQuery({
     queryKey: ["settings"],
@@ -61,6 +78,33 @@ export function SettingsModal({ open, onClose }: Props) {
     },
   });
 
+  const feverQuery = useQuery({
+    queryKey: ["settings", "fever"],
+    queryFn: fetchFeverEnabled,
+    enabled: open && section === "apps",
+  });
+  const feverPasswordMutation = useMutation({
+    mutationFn: setFeverPassword,
+    onSuccess: () => {
+      setFeverPasswordInput("");
+      queryClient.invalidateQueries({ queryKey: ["settings", "fever"] });
+      success("settings", "App password saved", "Sign in to your app with your email and this password");
+    },
+    onError: (err) => {
+      logError("settings", "Failed to save app password", extractErrorMessage(err));
+    },
+  });
+  const disableFeverMutation = useMutation({
+    mutationFn: disableFever,
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ["settings", "fever"] });
+      success("settings", "App access disabled", "Apps signed in with the old password can no longer sync");
+    },
+    onError: (err) => {
+      logError("settings", "Failed to disable app access", extractErrorMessage(err));
+    },
+  });
+
   useEffect(() => {
     const storedStart = (localStorage.getItem("pref:startPage") as StartPage) || "today";
     const storedSort = (localStorage.getItem("pref:sort") as SortPref) || "popular_latest";
@@ -133,6 +177,43 @@ export function SettingsModal({ open, onClose }: Props) {
     }
   };
 
+  const handleImportArchive = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    if (!e.target.files || !e.target.files[0]) return;
+    const file = e.target.files[0];
+
+    setImportingArchive(true);
+    try {
+      const res = await importAccountArchive(file);
+      success("settings", "Import Successful", res.message);
+      queryClient.invalidateQueries();
+      e.target.value = "";
+    } catch (err: any) {
+      logError("settings", "Import Failed", extractErrorMessage(err));
+    } finally {
+      setImportingArchive(false);
+    }
+  };
+
+  const handleExportArchive = async () => {
+    setExportingArchive(true);
+    try {
+      const blob = await downloadAccountArchive();
+      const url = window.URL.createObjectURL(blob);
+      const a = document.createElement("a");
+      a.href = url;
+      a.download = `rss-account-${new Date().toISOString().split("T")[0]}.zip`;
+      document.body.appendChild(a);
+      a.click();
+      window.URL.revokeObjectURL(url);
+      document.body.removeChild(a);
+      success("settings", "Export started", "Your account archive is downloading");
+    } catch (err: any) {
+      logError("settings", "Export Failed", extractErrorMessage(err));
+    } finally {
+      setExportingArchive(false);
+    }
+  };
+
   const panelToneClass = theme === "aurora" ? "sm:bg-emerald-50/80" : "sm:bg-gray-50";
   const selectedFont = FONT_OPTIONS.some((opt) => opt.value === fontFamily) ? fontFamily : FONT_OPTIONS[0].value;
 
@@ -154,6 +235,7 @@ export function SettingsModal({ open, onClose }: Props) {
               { key: "appearance", label: "Appearance" },
               { key: "reading", label: "Reading" },
               { key: "storage", label: "Storage" },
+              { key: "apps", label: "Mobile Apps" },
               { key: "data", label: "Data" },
             ].map((item) => (
               <button
@@ -332,6 +414,57 @@ export function SettingsModal({ open, onClose }: Props) {
             </div>
           )}
 
+          {section === "apps" && (
+            <div className="settings-section space-y-6">
+              <FormGroup
+                label="App Password"
+                hint={`Apps that speak the Fever or Google Reader API, such as Reeder and NetNewsWire, sign in with your email and this password. Use ${window.location.origin}/api/fever/ as the Fever server or ${window.location.origin}/api/greader as the Google Reader server.`}
+              >
+                <p className="mt-2 text-xs text-gray-500">
+                  {feverQuery.isLoading
+                    ? "Checking..."
+                    : feverQuery.data
+                      ? "App access is enabled. Setting a new password signs out apps using the old one."
+                      : "App access is disabled."}
+                </p>
+                <form
+                  className="mt-3 flex flex-col gap-2 sm:flex-row"
+                  onSubmit={(e) => {
+                    e.preventDefault();
+                    feverPasswordMutation.mutate(feverPassword);
+                  }}
+                >
+                  <Input
+                    type="password"
+                    autoComplete="new-password"
+                    placeholder="At least 8 characters"
+                    value={feverPassword}
+                    onChange={(e) => setFeverPasswordInput(e.target.value)}
+                    disabled={feverPasswordMutation.isPending}
+                  />
+                  <Button
+                    type="submit"
+                    variant="primary"
+                    disabled={feverPassword.length < 8 || feverPasswordMutation.isPending}
+                  >
+                    {feverPasswordMutation.isPending ? "Saving..." : feverQuery.data ? "Change Password" : "Set Password"}
+                  </Button>
+                </form>
+                {feverQuery.data && (
+                  <div className="mt-3">
+                    <Button
+                      variant="danger"
+                      onClick={() => disableFeverMutation.mutate()}
+                      disabled={disableFeverMutation.isPending}
+                    >
+                      {disableFeverMutation.isPending ? "Disabling..." : "Disable App Access"}
+                    </Button>
+                  </div>
+                )}
+              </FormGroup>
+            </div>
+          )}
+
           {section === "data" && (
             <div className="settings-section space-y-8">
               <div className="space-y-4">
@@ -379,6 +512,53 @@ export function SettingsModal({ open, onClose }: Props) {
                   </div>
                 </div>
               </div>
+
+              <div className="space-y-4">
+                <div>
+                  <h4 className="text-base font-semibold text-gray-900 dark:text-gray-100">Account Archive</h4>
+                  <p className="mt-1 text-sm text-gray-500 dark:text-gray-400">
+                    Move your whole account to another server: folders, feeds, read and bookmark state, tags, saved
+                    searches, filters and settings. Importing merges into this account and never removes anything.
+                  </p>
+                </div>
+
+                <div className="grid gap-6 sm:grid-cols-2">
+                  <div className="rounded-xl border border-gray-200 bg-gray-50 p-5 dark:border-gray-800 dark:bg-gray-900/50">
+                    <h5 className="font-medium text-gray-900 dark:text-gray-100">Import Archive</h5>
+                    <p className="mt-1 text-xs text-gray-500">
+                      Upload a .zip or .json account archive.
+                    </p>
+                    <div className="mt-4">
+                      <label className="inline-flex cursor-pointer items-center justify-center gap-2 rounded-lg bg-[var(--accent)] px-4 py-2 text-sm font-medium text-white shadow hover:bg-[var(--accent-hover)] focus:outline-none focus:ring-2 focus:ring-[var(--accent)]/40 focus:ring-offset-1 disabled:opacity-50 disabled:cursor-not-allowed transition-colors">
+                        {importingArchive ? "Importing..." : "Select File"}
+                        <input
+                          type="file"
+                          accept=".zip,.json"
+                          onChange={handleImportArchive}
+                          disabled={importingArchive}
+                          className="hidden"
+                        />
+                      </label>
+                    </div>
+                  </div>
+
+                  <div className="rounded-xl border border-gray-200 bg-gray-50 p-5 dark:border-gray-800 dark:bg-gray-900/50">
+                    <h5 className="font-medium text-gray-900 dark:text-gray-100">Export Archive</h5>
+                    <p className="mt-1 text-xs text-gray-500">
+                      Download everything in your account, including starred articles.
+                    </p>
+                    <div className="mt-4">
+                      <Button
+                        variant="primary"
+                        onClick={handleExportArchive}
+                        disabled={exportingArchive}
+                      >
+                        {exportingArchive ? "Exporting..." : "Download Archive"}
+                      </Button>
+                    </div>
+                  </div>
+                </div>
+              </div>
             </div>
           )}
         </div>